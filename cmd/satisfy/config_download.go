@@ -2,23 +2,30 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 
-	"github.com/smartystreets/gcs"
 	"github.com/smartystreets/satisfy/contracts"
 	"github.com/smartystreets/satisfy/core"
-	"github.com/smartystreets/satisfy/shell"
 )
 
 type DownloadConfig struct {
 	MaxRetry          int
 	QuickVerification bool
-	GoogleCredentials gcs.Credentials
+	Concurrency       int
+	ChunkSize         int64
+	RemoteStorage     RemoteStorageConfig
 	Dependencies      contracts.DependencyListing
+	TrustedKeys       core.TrustedKeys
+	LockfilePath      string
+	Frozen            bool
+	Lockfile          contracts.Lockfile
+	filtered          bool
 	jsonPath          string
 }
 
@@ -34,11 +41,35 @@ func parseDownloadConfig(args []string) (config DownloadConfig, err error) {
 		true,
 		"When set to false, perform full file content validation on installed packages.",
 	)
+	flags.IntVar(&config.Concurrency,
+		"concurrency",
+		4,
+		"How many byte ranges of a large archive to download at once (1 disables parallel downloads).",
+	)
+	chunkSize := flags.Int64("chunk-size",
+		32,
+		"The size (in MiB) of each byte range of a parallel download; smaller archives are downloaded as a single stream.",
+	)
+	flags.BoolVar(&config.RemoteStorage.Anonymous,
+		"anonymous",
+		false,
+		"When set, download from Google Cloud Storage using unsigned requests (public buckets only; no credentials required).",
+	)
 	flags.StringVar(&config.jsonPath,
 		"json",
 		"_STDIN_",
 		"Path to file with dependency listing or, if equal to _STDIN_, read from stdin.",
 	)
+	flags.StringVar(&config.LockfilePath,
+		"lockfile",
+		"",
+		"Path to the lockfile which records the version (and digests) to which each dependency was resolved (none when blank).",
+	)
+	flags.BoolVar(&config.Frozen,
+		"frozen",
+		false,
+		"When set, install exactly the versions in the lockfile, failing if the remote packages no longer match.",
+	)
 
 	flags.Usage = func() {
 		output := flags.Output()
@@ -48,9 +79,11 @@ func parseDownloadConfig(args []string) (config DownloadConfig, err error) {
 		_, _ = fmt.Fprintln(output, "  Package names may be passed as non-flag arguments and will serve as a filter "+
 			"against the provided dependency listing.")
 		_, _ = fmt.Fprintln(output)
-		_, _ = fmt.Fprintln(output, "  The satisfy tool also provides 2 additional subcommands:")
+		_, _ = fmt.Fprintln(output, "  The satisfy tool also provides 4 additional subcommands:")
 		_, _ = fmt.Fprintln(output, "	check	Has package@version already been uploaded according to json config?")
 		_, _ = fmt.Fprintln(output, "	upload	Upload package contents according to json config.")
+		_, _ = fmt.Fprintln(output, "	mirror	Copy packages from one remote address to another.")
+		_, _ = fmt.Fprintln(output, "	rollback	Point side-by-side packages back at their previously installed version.")
 		_, _ = fmt.Fprintln(output)
 	}
 
@@ -58,14 +91,31 @@ func parseDownloadConfig(args []string) (config DownloadConfig, err error) {
 	if err != nil {
 		return DownloadConfig{}, err
 	}
+	if config.Concurrency < 1 || *chunkSize < 1 {
+		return DownloadConfig{}, errors.New("concurrency and chunk size must be positive")
+	}
+	config.ChunkSize = *chunkSize << 20
+	if config.Frozen && config.LockfilePath == "" {
+		return DownloadConfig{}, errors.New("a lockfile is required when frozen")
+	}
 
-	parser := core.NewGoogleCredentialParser(shell.NewDiskFileSystem(""), shell.NewEnvironment())
-	config.GoogleCredentials, err = parser.Parse()
+	config.Dependencies, err = loadDependencyListing(config.jsonPath, flags.Args())
 	if err != nil {
 		return DownloadConfig{}, err
 	}
+	config.filtered = len(flags.Args()) > 0
 
-	config.Dependencies, err = loadDependencyListing(config.jsonPath, flags.Args())
+	config.Lockfile, err = loadLockfile(config)
+	if err != nil {
+		return DownloadConfig{}, err
+	}
+
+	config.TrustedKeys, err = core.NewTrustedKeys(config.Dependencies.TrustedKeys)
+	if err != nil {
+		return DownloadConfig{}, err
+	}
+
+	err = parseDownloadCredentials(&config)
 	if err != nil {
 		return DownloadConfig{}, err
 	}
@@ -73,6 +123,20 @@ func parseDownloadConfig(args []string) (config DownloadConfig, err error) {
 	return config, nil
 }
 
+func parseDownloadCredentials(config *DownloadConfig) error {
+	schemes := make(map[string]struct{})
+	for _, dependency := range config.Dependencies.Listing {
+		schemes[dependency.RemoteAddress.Scheme] = struct{}{}
+		for _, mirror := range dependency.Mirrors {
+			schemes[mirror.Scheme] = struct{}{}
+		}
+	}
+	for _, mirror := range config.Dependencies.Mirrors {
+		schemes[mirror.Scheme] = struct{}{}
+	}
+	return parseRemoteStorageCredentials(&config.RemoteStorage, schemes)
+}
+
 func loadDependencyListing(path string, filter []string) (contracts.DependencyListing, error) {
 	dependencies, err := readDependencyListing(path)
 	if err != nil {
@@ -84,6 +148,11 @@ func loadDependencyListing(path string, filter []string) (contracts.DependencyLi
 		return contracts.DependencyListing{}, err
 	}
 
+	err = validateVersionConstraints(dependencies.Listing)
+	if err != nil {
+		return contracts.DependencyListing{}, err
+	}
+
 	dependencies.Listing = core.Filter(dependencies.Listing, filter)
 
 	if len(dependencies.Listing) == 0 {
@@ -93,6 +162,38 @@ func loadDependencyListing(path string, filter []string) (contracts.DependencyLi
 	return dependencies, nil
 }
 
+func validateVersionConstraints(listing []contracts.Dependency) error {
+	for _, dependency := range listing {
+		if !core.IsVersionConstraint(dependency.PackageVersion) {
+			continue
+		}
+		if _, err := core.ParseVersionConstraint(dependency.PackageVersion); err != nil {
+			return fmt.Errorf("%s: %w", dependency.PackageName, err)
+		}
+	}
+	return nil
+}
+
+// loadLockfile reads the lockfile when it's required: to install the locked versions (when frozen) or
+// to retain the versions locked for dependencies excluded by a filter.
+func loadLockfile(config DownloadConfig) (lockfile contracts.Lockfile, err error) {
+	if !config.Frozen && (!config.filtered || config.LockfilePath == "") {
+		return lockfile, nil
+	}
+	raw, err := ioutil.ReadFile(config.LockfilePath)
+	if os.IsNotExist(err) && !config.Frozen {
+		return lockfile, nil
+	}
+	if err != nil {
+		return lockfile, fmt.Errorf("could not read lockfile (%q): %w", config.LockfilePath, err)
+	}
+	err = json.Unmarshal(raw, &lockfile)
+	if err != nil {
+		return lockfile, fmt.Errorf("malformed lockfile (%q): %w", config.LockfilePath, err)
+	}
+	return lockfile, nil
+}
+
 func readDependencyListing(path string) (contracts.DependencyListing, error) {
 	if path == "_STDIN_" {
 		return readFromReader(os.Stdin)
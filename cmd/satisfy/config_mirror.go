@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/smartystreets/satisfy/core"
+)
+
+type MirrorConfig struct {
+	MaxRetry      int
+	Source        url.URL
+	Target        url.URL
+	Versions      core.VersionSelector
+	Packages      []string
+	Anonymous     bool
+	RemoteStorage RemoteStorageConfig
+}
+
+func parseMirrorConfig(args []string) (config MirrorConfig, err error) {
+	var source, target, versions string
+	flags := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	flags.IntVar(&config.MaxRetry,
+		"max-retry",
+		5,
+		"How many times to retry attempts to download or upload packages.",
+	)
+	flags.StringVar(&source,
+		"source",
+		"",
+		"The remote address prefix from which packages are copied (e.g. gcs://bucket/path/prefix).",
+	)
+	flags.StringVar(&target,
+		"target",
+		"",
+		"The remote address prefix to which packages are copied (e.g. file:///srv/packages).",
+	)
+	flags.StringVar(&versions,
+		"versions",
+		"latest",
+		"The versions to copy: 'latest', 'all', a comma-separated list, or an inclusive range (e.g. 1.2.0..1.4.0).",
+	)
+	flags.BoolVar(&config.Anonymous,
+		"anonymous",
+		false,
+		"When set, download from a Google Cloud Storage source using unsigned requests (public buckets only; the target still requires credentials).",
+	)
+
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage of %s mirror:\n", os.Args[0])
+		flags.PrintDefaults()
+		_, _ = fmt.Fprintln(output)
+		_, _ = fmt.Fprintln(output, "  Package names must be passed as non-flag arguments.")
+		_, _ = fmt.Fprintln(output)
+	}
+
+	err = flags.Parse(args)
+	if err != nil {
+		return MirrorConfig{}, err
+	}
+
+	config.Source, err = parseMirrorAddress("source", source)
+	if err != nil {
+		return MirrorConfig{}, err
+	}
+	config.Target, err = parseMirrorAddress("target", target)
+	if err != nil {
+		return MirrorConfig{}, err
+	}
+	config.Versions, err = core.ParseVersionSelector(versions)
+	if err != nil {
+		return MirrorConfig{}, err
+	}
+	config.Packages = flags.Args()
+	if len(config.Packages) == 0 {
+		return MirrorConfig{}, errors.New("at least one package name is required")
+	}
+
+	schemes := map[string]struct{}{config.Target.Scheme: {}}
+	if !config.Anonymous || config.Source.Scheme != "gcs" {
+		schemes[config.Source.Scheme] = struct{}{}
+	}
+	err = parseRemoteStorageCredentials(&config.RemoteStorage, schemes)
+	if err != nil {
+		return MirrorConfig{}, err
+	}
+
+	return config, nil
+}
+
+func parseMirrorAddress(name, value string) (url.URL, error) {
+	if value == "" {
+		return url.URL{}, fmt.Errorf("%s remote address is required", name)
+	}
+	address, err := url.Parse(value)
+	if err != nil {
+		return url.URL{}, fmt.Errorf("malformed %s remote address: %w", name, err)
+	}
+	if address.Scheme == "" {
+		return url.URL{}, fmt.Errorf("%s remote address requires a scheme: %q", name, value)
+	}
+	return *address, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+type RollbackConfig struct {
+	Dependencies contracts.DependencyListing
+	jsonPath     string
+}
+
+func parseRollbackConfig(args []string) (config RollbackConfig, err error) {
+	flags := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	flags.StringVar(&config.jsonPath,
+		"json",
+		"_STDIN_",
+		"Path to file with dependency listing or, if equal to _STDIN_, read from stdin.",
+	)
+
+	flags.Usage = func() {
+		output := flags.Output()
+		_, _ = fmt.Fprintf(output, "Usage of %s rollback:\n", os.Args[0])
+		flags.PrintDefaults()
+		_, _ = fmt.Fprintln(output)
+		_, _ = fmt.Fprintln(output, "  Package names must be passed as non-flag arguments. Each package must be "+
+			"installed side-by-side (see 'side_by_side' in the dependency listing). The version rolled back to "+
+			"remains installed until the version in the dependency listing changes.")
+		_, _ = fmt.Fprintln(output)
+	}
+
+	err = flags.Parse(args)
+	if err != nil {
+		return RollbackConfig{}, err
+	}
+	if flags.NArg() == 0 {
+		return RollbackConfig{}, errors.New("at least one package name is required")
+	}
+
+	config.Dependencies, err = loadDependencyListing(config.jsonPath, flags.Args())
+	if err != nil {
+		return RollbackConfig{}, err
+	}
+	if len(config.Dependencies.Listing) == 0 {
+		return RollbackConfig{}, fmt.Errorf("no dependency in the listing matches %q, so nothing can be rolled back", flags.Args())
+	}
+	for _, dependency := range config.Dependencies.Listing {
+		if !dependency.SideBySide {
+			return RollbackConfig{}, fmt.Errorf("%s is not installed side-by-side and cannot be rolled back", dependency.Title())
+		}
+	}
+
+	return config, nil
+}
@@ -15,6 +15,10 @@ func main() {
 		uploadMain(os.Args[2:])
 	} else if isSubCommand("check") {
 		checkMain(os.Args[2:])
+	} else if isSubCommand("mirror") {
+		mirrorMain(os.Args[2:])
+	} else if isSubCommand("rollback") {
+		rollbackMain(os.Args[2:])
 	} else if isSubCommand("version") {
 		versionMain()
 	} else if isSubCommand("download") {
@@ -54,6 +58,22 @@ func downloadMain(args []string) {
 	NewDownloadApp(config).Run()
 }
 
+func mirrorMain(args []string) {
+	config, err := parseMirrorConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	NewMirrorApp(config).Run()
+}
+
+func rollbackMain(args []string) {
+	config, err := parseRollbackConfig(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	NewRollbackApp(config).Run()
+}
+
 func versionMain() {
 	log.Printf("satisfy [%s]\n", ldflagsSoftwareVersion)
 }
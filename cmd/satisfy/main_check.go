@@ -8,7 +8,6 @@ import (
 
 	"github.com/smartystreets/satisfy/contracts"
 	"github.com/smartystreets/satisfy/core"
-	"github.com/smartystreets/satisfy/shell"
 )
 
 type CheckApp struct {
@@ -43,7 +42,6 @@ func (this *CheckApp) Run() {
 }
 
 func (this *CheckApp) buildRemoteStorageClient() contracts.Downloader {
-	client := shell.NewHTTPClient()
-	gcsClient := shell.NewGoogleCloudStorageClient(client, this.config.GoogleCredentials, http.StatusNotFound)
-	return core.NewRetryClient(gcsClient, this.config.MaxRetry, time.Sleep)
+	registry := newRemoteStorageRegistry(uploadRemoteStorageConfig(this.config), http.StatusNotFound)
+	return core.NewRetryClient(registry, this.config.MaxRetry, time.Sleep)
 }
@@ -2,8 +2,12 @@ package main
 
 import (
 	"crypto/md5"
+	"encoding/json"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -13,34 +17,52 @@ import (
 )
 
 type DownloadApp struct {
-	listing   contracts.DependencyListing
+	config    DownloadConfig
 	installer *core.PackageInstaller
 	integrity contracts.IntegrityCheck
 	waiter    *sync.WaitGroup
 	results   chan error
+	lock      *sync.Mutex
+	locked    []contracts.LockedPackage
+
+	directories map[string]*sync.Mutex // key: absolute local directory
 }
 
 func NewDownloadApp(config DownloadConfig) *DownloadApp {
 	disk := shell.NewDiskFileSystem("")
-	client := shell.NewGoogleCloudStorageClient(shell.NewHTTPClient(), config.GoogleCredentials, http.StatusOK)
-	installer := core.NewPackageInstaller(core.NewRetryClient(client, config.MaxRetry, time.Sleep), disk)
-	integrity := core.NewCompoundIntegrityCheck(
+	registry := newRemoteStorageRegistry(config.RemoteStorage, http.StatusOK)
+	retry := core.NewRetryClient(registry, config.MaxRetry, time.Sleep)
+	mirrors := core.NewMirrorDownloader(retry, config.Dependencies)
+	downloader := core.NewParallelDownloader(mirrors, config.ChunkSize, config.Concurrency, NewDownloadSpool)
+	installer := core.NewPackageInstaller(downloader, disk, config.TrustedKeys, NewArchiveSpool)
+	var checks []contracts.IntegrityCheck
+	if config.Frozen {
+		checks = append(checks, core.NewLockfileIntegrityCheck(config.Lockfile))
+	}
+	checks = append(checks,
 		core.NewFileListingIntegrityChecker(disk),
 		core.NewFileContentIntegrityCheck(md5.New, disk, !config.QuickVerification),
 	)
+	if len(config.TrustedKeys) > 0 {
+		checks = append(checks, core.NewSignatureIntegrityCheck(disk, config.TrustedKeys))
+	}
+	integrity := core.NewCompoundIntegrityCheck(checks...)
 	waiter := new(sync.WaitGroup)
 	waiter.Add(len(config.Dependencies.Listing))
 	return &DownloadApp{
-		listing:   config.Dependencies,
+		config:    config,
 		installer: installer,
 		integrity: integrity,
 		waiter:    waiter,
 		results:   make(chan error),
+		lock:      new(sync.Mutex),
+
+		directories: make(map[string]*sync.Mutex),
 	}
 }
 
 func (this *DownloadApp) Run() {
-	for _, dependency := range this.listing.Listing {
+	for _, dependency := range this.config.Dependencies.Listing {
 		go this.install(dependency)
 	}
 	go this.awaitCompletion()
@@ -52,6 +74,7 @@ func (this *DownloadApp) Run() {
 	if failed > 0 {
 		log.Fatalf("[WARN] %d packages failed to install.", failed)
 	}
+	this.writeLockfile()
 }
 
 func (this *DownloadApp) awaitCompletion() {
@@ -62,9 +85,90 @@ func (this *DownloadApp) awaitCompletion() {
 func (this *DownloadApp) install(dependency contracts.Dependency) {
 	defer this.waiter.Done()
 
+	var err error
+	if this.config.Frozen {
+		dependency, err = core.FreezeDependency(this.config.Lockfile, dependency)
+		if err != nil {
+			this.results <- err
+			return
+		}
+	}
+
+	unlock := this.lockLocalDirectory(dependency.LocalDirectory)
 	resolver := core.NewDependencyResolver(shell.NewDiskFileSystem(""), this.integrity, this.installer, dependency)
-	err := resolver.Resolve()
+	manifest, err := resolver.Resolve()
+	unlock()
 	if err != nil {
 		this.results <- err
+		return
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.locked = append(this.locked, core.LockPackage(dependency, manifest))
+}
+
+// lockLocalDirectory serializes the installation of dependencies which share a local directory, as
+// each decides whether it owns the local directory (and may replace it wholesale) from its contents.
+func (this *DownloadApp) lockLocalDirectory(directory string) (unlock func()) {
+	if absolute, err := filepath.Abs(directory); err == nil {
+		directory = absolute
+	}
+	this.lock.Lock()
+	lock, found := this.directories[directory]
+	if !found {
+		lock = new(sync.Mutex)
+		this.directories[directory] = lock
+	}
+	this.lock.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// writeLockfile records the resolved versions of every dependency (retaining those of dependencies
+// excluded by a filter). A frozen installation leaves the lockfile exactly as it was.
+func (this *DownloadApp) writeLockfile() {
+	path := this.config.LockfilePath
+	if path == "" || this.config.Frozen {
+		return
+	}
+	lockfile := this.config.Lockfile
+	lockfile.Lock(this.locked...)
+	raw, err := json.MarshalIndent(lockfile, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	temporary, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".")
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = temporary.Write(append(raw, '\n'))
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temporary.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(temporary.Name())
+		log.Fatal(err)
+	}
+	log.Printf("Lockfile written: %s", path)
+}
+
+func NewArchiveSpool() (core.ArchiveSpool, error) {
+	file, err := ioutil.TempFile("", "satisfy-archive-")
+	if err != nil {
+		return nil, err
+	}
+	return &TemporaryFile{File: file}, nil
+}
+
+func NewDownloadSpool() (core.DownloadSpool, error) {
+	file, err := ioutil.TempFile("", "satisfy-download-")
+	if err != nil {
+		return nil, err
 	}
+	return &TemporaryFile{File: file}, nil
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/smartystreets/satisfy/contracts"
+	"github.com/smartystreets/satisfy/core"
+)
+
+type MirrorApp struct {
+	config   MirrorConfig
+	registry *core.RemoteStorageRegistry
+	mirror   *core.PackageMirror
+}
+
+// NewMirrorApp downloads anonymously (when so configured) only from the source; the target always
+// requires credentials.
+func NewMirrorApp(config MirrorConfig) *MirrorApp {
+	sourceConfig := config.RemoteStorage
+	sourceConfig.Anonymous = config.Anonymous
+	sourceRegistry := newRemoteStorageRegistry(sourceConfig, http.StatusOK)
+	targetRegistry := newRemoteStorageRegistry(config.RemoteStorage, http.StatusOK)
+	return &MirrorApp{
+		config:   config,
+		registry: sourceRegistry,
+		mirror: core.NewPackageMirror(
+			core.NewRetryClient(sourceRegistry, config.MaxRetry, time.Sleep),
+			core.NewRetryClient(targetRegistry, config.MaxRetry, time.Sleep),
+			config.Source, config.Target, NewTemporaryFile,
+		),
+	}
+}
+
+func (this *MirrorApp) Run() {
+	failed := 0
+	for _, packageName := range this.config.Packages {
+		err := this.mirrorPackage(packageName)
+		if err != nil {
+			failed++
+			log.Println("[WARN]", err)
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("[WARN] %d packages failed to mirror.", failed)
+	}
+}
+
+func (this *MirrorApp) mirrorPackage(packageName string) error {
+	versions, err := this.selectVersions(packageName)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		log.Printf("Mirroring [%s @ %s]", packageName, version)
+		_, err = this.mirror.MirrorVersion(packageName, version)
+		if isMissingListedVersion(this.config.Versions, err) {
+			log.Printf("[WARN] No manifest found, skipping [%s @ %s]", packageName, version)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return this.mirror.MirrorLatest(packageName)
+}
+
+func (this *MirrorApp) selectVersions(packageName string) ([]string, error) {
+	if !this.config.Versions.RequiresListing() {
+		return this.config.Versions.Select(nil), nil
+	}
+	address := this.config.Source
+	address.Path = path.Join("/", address.Path, packageName)
+	available, err := this.registry.List(address)
+	if err != nil {
+		return nil, err
+	}
+	return this.config.Versions.Select(available), nil
+}
+
+// isMissingListedVersion tolerates listed entries which aren't versions at all (such as the
+// directory of another package whose name shares this package's name as a prefix).
+func isMissingListedVersion(selector core.VersionSelector, err error) bool {
+	var statusErr *contracts.StatusCodeError
+	return selector.RequiresListing() && errors.As(err, &statusErr) && statusErr.StatusCode() == http.StatusNotFound
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+// TemporaryFile is removed from the disk when closed.
+type TemporaryFile struct {
+	*os.File
+}
+
+func NewTemporaryFile() (core.MirrorSpool, error) {
+	file, err := ioutil.TempFile("", "satisfy-mirror-")
+	if err != nil {
+		return nil, err
+	}
+	return &TemporaryFile{File: file}, nil
+}
+
+func (this *TemporaryFile) Close() error {
+	err := this.File.Close()
+	_ = os.Remove(this.Name())
+	return err
+}
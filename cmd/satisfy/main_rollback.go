@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+
+	"github.com/smartystreets/satisfy/core"
+	"github.com/smartystreets/satisfy/shell"
+)
+
+type RollbackApp struct {
+	config RollbackConfig
+}
+
+func NewRollbackApp(config RollbackConfig) *RollbackApp {
+	return &RollbackApp{config: config}
+}
+
+func (this *RollbackApp) Run() {
+	disk := shell.NewDiskFileSystem("")
+	failed, rolledBack := 0, 0
+	for _, dependency := range this.config.Dependencies.Listing {
+		installation := core.NewSideBySideInstallation(disk, dependency.LocalDirectory, dependency.RetainedVersionCount())
+		version, err := installation.Rollback(dependency.PackageVersion)
+		if err != nil {
+			failed++
+			log.Printf("[WARN] Failed to roll back [%s]: %s", dependency.PackageName, err)
+			continue
+		}
+		log.Printf("Rolled back [%s] to version %s", dependency.PackageName, version)
+		rolledBack++
+	}
+	if failed > 0 {
+		log.Fatalf("[WARN] %d packages failed to roll back.", failed)
+	}
+	if rolledBack == 0 {
+		log.Fatal("[WARN] No packages were rolled back.")
+	}
+}
@@ -2,9 +2,10 @@ package main
 
 import (
 	"bytes"
-	"compress/gzip"
+	"crypto/ed25519"
 	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"hash"
 	"io"
 	"io/ioutil"
@@ -14,7 +15,6 @@ import (
 	"os"
 	"time"
 
-	"github.com/klauspost/compress/zstd"
 	"github.com/smartystreets/satisfy/contracts"
 	"github.com/smartystreets/satisfy/core"
 	"github.com/smartystreets/satisfy/shell"
@@ -25,10 +25,12 @@ type UploadApp struct {
 	packageConfig contracts.PackageConfig
 	file          *os.File
 	hasher        hash.Hash
+	digest        hash.Hash
 	compressor    io.WriteCloser
+	dictionary    []byte
 	builder       *core.PackageBuilder
 	manifest      contracts.Manifest
-	client        contracts.RemoteStorage
+	client        *core.ChunkedUploader
 }
 
 func NewUploadApp(config contracts.UploadConfig) *UploadApp {
@@ -39,93 +41,212 @@ func NewUploadApp(config contracts.UploadConfig) *UploadApp {
 func (this *UploadApp) Run() {
 	this.buildRemoteStorageClient()
 
+	this.loadDictionary()
+
 	log.Println("Building the archive...")
-	this.buildArchiveAndManifestContents()
-	this.completeManifest()
+	if this.config.Stream {
+		this.streamArchive()
+	} else {
+		this.uploadArchive()
+	}
 
-	log.Println("Manifest:", this.dumpManifest())
+	if dictionary := this.manifest.Archive.Dictionary; dictionary != nil {
+		this.uploadDictionary(*dictionary)
+	}
+
+	log.Println("Uploading the manifest...")
+	this.uploadManifest(this.packageConfig.ComposeRemoteAddress(contracts.RemoteManifestFilename))
+	this.uploadManifest(this.packageConfig.ComposeLatestManifestRemoteAddress())
+
+	this.updateVersionIndex()
+}
+
+// updateVersionIndex adds the version to the index of published versions (against which version
+// constraints are resolved) once its manifest has been uploaded. Note that simultaneous uploads of
+// different versions of the same package may each overwrite the index updated by the other.
+func (this *UploadApp) updateVersionIndex() {
+	address := this.packageConfig.ComposeVersionIndexRemoteAddress()
+	var index contracts.VersionIndex
+	body, err := this.client.Download(address)
+	if err == nil {
+		err = json.NewDecoder(body).Decode(&index)
+		_ = body.Close()
+	}
+	var statusErr *contracts.StatusCodeError
+	if err != nil && !(errors.As(err, &statusErr) && statusErr.StatusCode() == http.StatusNotFound) {
+		log.Fatal(err)
+	}
+	if !index.Add(this.packageConfig.PackageVersion) {
+		log.Println("Version already in the versions index.")
+		return
+	}
+	log.Println("Updating the versions index...")
+	raw, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	checksum := md5.Sum(raw)
+	this.upload(contracts.UploadRequest{
+		RemoteAddress: address,
+		Body:          bytes.NewReader(raw),
+		Size:          int64(len(raw)),
+		ContentType:   "application/json",
+		Checksum:      checksum[:],
+	})
+}
 
-	log.Println("Uploading the archive...")
-	this.upload(this.buildArchiveUploadRequest())
+// uploadManifest uploads the signature of the manifest (when there is a signing key) ahead of the
+// manifest itself, so that a signed package never appears to be unsigned.
+func (this *UploadApp) uploadManifest(address url.URL) {
+	request := this.buildManifestUploadRequest(address)
+	if key := this.config.SigningKey; key != nil {
+		public := key.Public().(ed25519.PublicKey)
+		log.Printf("Signing the manifest with key %s (%s)", core.KeyID(public), core.EncodePublicKey(public))
+		this.upload(this.buildSignatureUploadRequest(address))
+	}
+	this.upload(request)
+}
+
+func (this *UploadApp) buildSignatureUploadRequest(manifestAddress url.URL) contracts.UploadRequest {
+	signature := core.SignManifest(this.config.SigningKey, this.writeManifestToBuffer().Bytes())
+	checksum := md5.Sum(signature)
+	return contracts.UploadRequest{
+		RemoteAddress: contracts.ComposeSignatureAddress(manifestAddress),
+		Body:          bytes.NewReader(signature),
+		Size:          int64(len(signature)),
+		ContentType:   "application/json",
+		Checksum:      checksum[:],
+	}
+}
+
+func (this *UploadApp) uploadArchive() {
+	this.createArchiveFile()
+	err := this.buildArchiveAndManifestContents(this.file)
+	if err != nil {
+		log.Fatal(err)
+	}
 	this.closeArchiveFile()
+	this.completeManifest(this.localArchiveSize())
+
+	log.Println("Manifest:", this.dumpManifest())
+
+	if this.archiveExistsInPool() {
+		log.Println("Archive already exists in the pool:", this.manifest.Archive.Location)
+	} else {
+		log.Println("Uploading the archive...")
+		this.upload(this.buildArchiveUploadRequest())
+		this.closeArchiveFile()
+	}
 	this.deleteLocalArchiveFile()
+}
 
-	log.Println("Uploading the manifest...")
-	this.upload(this.buildManifestUploadRequest(this.packageConfig.ComposeRemoteAddress(contracts.RemoteManifestFilename)))
-	this.upload(this.buildManifestUploadRequest(this.packageConfig.ComposeLatestManifestRemoteAddress()))
+// streamArchive uploads the archive (in chunks) as it is built, without a temporary file. The upload
+// is only finalized, and its checksum verified, once the manifest is complete.
+func (this *UploadApp) streamArchive() {
+	stream, err := this.client.BeginStream(contracts.UploadRequest{
+		RemoteAddress: this.packageConfig.ComposeArchiveAddress(contracts.Archive{Filename: contracts.RemoteArchiveFilename}),
+		ContentType:   this.archiveContentType(),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = this.buildArchiveAndManifestContents(stream)
+	if err != nil {
+		_ = stream.Abort()
+		log.Fatal(err)
+	}
+	this.completeManifest(stream.Size())
+
+	log.Println("Manifest:", this.dumpManifest())
+
+	log.Println("Completing the archive upload...")
+	err = stream.Complete(this.manifest.Archive.MD5Checksum)
+	if err != nil {
+		_ = stream.Abort()
+		log.Fatal(err)
+	}
 }
 
 func (this *UploadApp) buildArchiveUploadRequest() contracts.UploadRequest {
 	this.openArchiveFile()
 	return contracts.UploadRequest{
-		RemoteAddress: this.packageConfig.ComposeRemoteAddress(contracts.RemoteArchiveFilename),
-		Body:          NewFileWrapper(this.file),
+		RemoteAddress: this.packageConfig.ComposeArchiveAddress(this.manifest.Archive),
+		Body:          core.RewindOnClose{ReadSeeker: this.file},
 		Size:          int64(this.manifest.Archive.Size),
-		ContentType:   contentType[this.manifest.Archive.CompressionAlgorithm],
+		ContentType:   this.archiveContentType(),
 		Checksum:      this.manifest.Archive.MD5Checksum,
 	}
 }
 
-func (this *UploadApp) buildArchiveAndManifestContents() {
+func (this *UploadApp) createArchiveFile() {
 	var err error
 	this.file, err = ioutil.TempFile("", "")
 	if err != nil {
 		log.Fatal(err)
 	}
+}
+
+func (this *UploadApp) buildArchiveAndManifestContents(archive io.Writer) error {
+	newDigest := this.hashAlgorithm()
 	this.hasher = md5.New()
-	writer := io.MultiWriter(this.hasher, this.file)
+	this.digest = newDigest()
+	writer := io.MultiWriter(this.hasher, this.digest, archive)
 	this.InitializeCompressor(writer)
 
 	this.builder = core.NewPackageBuilder(
 		shell.NewDiskFileSystem(this.packageConfig.SourceDirectory),
-		shell.NewSwitchArchiveWriter(this.compressor),
+		core.NewSwitchArchiveWriter(this.compressor),
 		md5.New(),
+		newDigest(),
 	)
 
-	err = this.builder.Build()
+	err := this.builder.Build()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	err = this.compressor.Close()
-	if err != nil {
-		log.Fatal(err)
-	}
+	return this.compressor.Close()
+}
 
-	this.closeArchiveFile()
+func (this *UploadApp) hashAlgorithm() func() hash.Hash {
+	algorithm, found := core.LookupHashAlgorithm(this.config.HashAlgorithm)
+	if !found {
+		log.Fatalln("Unsupported hash algorithm:", this.config.HashAlgorithm)
+	}
+	return algorithm
 }
 
 func (this *UploadApp) InitializeCompressor(writer io.Writer) {
-	factory, found := compression[this.packageConfig.CompressionAlgorithm]
+	codec, found := core.LookupCodec(this.packageConfig.CompressionAlgorithm)
 	if !found {
 		log.Fatalln("Unsupported compression algorithm:", this.packageConfig.CompressionAlgorithm)
 	}
-	this.compressor = factory(writer, this.packageConfig.CompressionLevel)
+	var err error
+	this.compressor, err = codec.Compress(writer, core.NewCodecOptions(this.packageConfig, this.dictionary))
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
-var compression = map[string]func(_ io.Writer, level int) io.WriteCloser{
-	"zstd": func(writer io.Writer, level int) io.WriteCloser {
-		compressor, err := zstd.NewWriter(writer, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
-		if err != nil {
-			log.Fatal(err)
-		}
-		return compressor
-	},
-	"gzip": func(writer io.Writer, level int) io.WriteCloser {
-		compressor, err := gzip.NewWriterLevel(writer, level)
-		if err != nil {
-			log.Panicln(err)
-		}
-		return compressor
-	},
-	"zip": func(writer io.Writer, level int) io.WriteCloser {
-		return shell.NewZipArchiveWriter(writer, level)
-	},
+func (this *UploadApp) loadDictionary() {
+	path := this.packageConfig.Zstd.Dictionary
+	if path == "" {
+		return
+	}
+	var err error
+	this.dictionary, err = ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = core.ZstdDictionaryID(this.dictionary)
+	if err != nil {
+		log.Fatalf("%s: %s", path, err)
+	}
 }
-var contentType = map[string]string{
-	"zstd": "application/zstd",
-	"gzip": "application/gzip",
-	"zip":  "application/zip",
+
+func (this *UploadApp) archiveContentType() string {
+	codec, _ := core.LookupCodec(this.packageConfig.CompressionAlgorithm)
+	return codec.ContentType
 }
 
 func (this *UploadApp) buildManifestUploadRequest(remoteAddress url.URL) contracts.UploadRequest {
@@ -140,27 +261,88 @@ func (this *UploadApp) buildManifestUploadRequest(remoteAddress url.URL) contrac
 }
 
 func (this *UploadApp) buildRemoteStorageClient() {
-	client := shell.NewHTTPClient()
-	gcsClient := shell.NewGoogleCloudStorageClient(client, this.config.GoogleCredentials, http.StatusOK)
-	this.client = core.NewRetryClient(gcsClient, this.config.MaxRetry, time.Sleep)
+	registry := newRemoteStorageRegistry(uploadRemoteStorageConfig(this.config), http.StatusOK)
+	client := core.NewRetryClient(registry, this.config.MaxRetry, time.Sleep)
+	this.client = core.NewChunkedUploader(client, this.config.ChunkSize, this.config.MaxRetry, time.Sleep)
 }
 
-func (this *UploadApp) completeManifest() {
+func (this *UploadApp) localArchiveSize() int64 {
 	fileInfo, err := os.Stat(this.file.Name())
 	if err != nil {
 		log.Fatal(err)
 	}
+	return fileInfo.Size()
+}
+
+func (this *UploadApp) completeManifest(size int64) {
 	this.manifest = contracts.Manifest{
 		Name:    this.packageConfig.PackageName,
 		Version: this.packageConfig.PackageVersion,
 		Archive: contracts.Archive{
 			Filename:             contracts.RemoteArchiveFilename,
-			Size:                 uint64(fileInfo.Size()),
+			Size:                 uint64(size),
 			MD5Checksum:          this.hasher.Sum(nil),
+			HashAlgorithm:        this.config.HashAlgorithm,
+			Checksum:             this.digest.Sum(nil),
 			Contents:             this.builder.Contents(),
 			CompressionAlgorithm: this.packageConfig.CompressionAlgorithm,
 		},
 	}
+	if this.config.ArchivePool {
+		this.manifest.Archive.Location = contracts.ComposeArchivePoolLocation(this.manifest.Archive.Checksum)
+	}
+	if this.dictionary != nil {
+		id, _ := core.ZstdDictionaryID(this.dictionary)
+		checksum := md5.Sum(this.dictionary)
+		digest := this.hashAlgorithm()()
+		_, _ = digest.Write(this.dictionary)
+		this.manifest.Archive.Dictionary = &contracts.Dictionary{
+			ID:          id,
+			Location:    contracts.ComposeDictionaryLocation(digest.Sum(nil)),
+			Size:        uint64(len(this.dictionary)),
+			MD5Checksum: checksum[:],
+			Checksum:    digest.Sum(nil),
+		}
+	}
+}
+
+// archiveExistsInPool allows identical archives (by content) to be uploaded only once.
+func (this *UploadApp) archiveExistsInPool() bool {
+	if this.manifest.Archive.Location == "" {
+		return false
+	}
+	return this.existsRemotely(this.packageConfig.ComposeArchiveAddress(this.manifest.Archive))
+}
+
+// uploadDictionary shares a compression dictionary between all packages (and versions) which use it.
+func (this *UploadApp) uploadDictionary(dictionary contracts.Dictionary) {
+	address := this.packageConfig.ComposeDictionaryAddress(dictionary)
+	if this.existsRemotely(address) {
+		log.Println("Compression dictionary already exists:", dictionary.Location)
+		return
+	}
+	log.Println("Uploading the compression dictionary...")
+	this.upload(contracts.UploadRequest{
+		RemoteAddress: address,
+		Body:          bytes.NewReader(this.dictionary),
+		Size:          int64(len(this.dictionary)),
+		ContentType:   "application/octet-stream",
+		Checksum:      dictionary.MD5Checksum,
+	})
+}
+
+func (this *UploadApp) existsRemotely(address url.URL) bool {
+	body, err := this.client.Download(address)
+	if err == nil {
+		_ = body.Close()
+		return true
+	}
+	var statusErr *contracts.StatusCodeError
+	if errors.As(err, &statusErr) && statusErr.StatusCode() == http.StatusNotFound {
+		return false
+	}
+	log.Fatal(err)
+	return false
 }
 
 func (this *UploadApp) closeArchiveFile() {
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/smartystreets/gcs"
+	"github.com/smartystreets/satisfy/contracts"
+	"github.com/smartystreets/satisfy/core"
+	"github.com/smartystreets/satisfy/shell"
+)
+
+type RemoteStorageConfig struct {
+	GoogleCredentials gcs.Credentials
+	AWSCredentials    contracts.AWSCredentials
+	AzureCredentials  contracts.AzureCredentials
+	HTTPCredentials   contracts.HTTPCredentials
+	OCICredentials    contracts.OCICredentials
+	Anonymous         bool
+}
+
+func newRemoteStorageRegistry(config RemoteStorageConfig, expectedStatus int) *core.RemoteStorageRegistry {
+	client := shell.NewHTTPClient()
+	registry := core.NewRemoteStorageRegistry()
+	registry.Register("gcs", func(url.URL) (contracts.RemoteStorage, error) {
+		if config.Anonymous {
+			return shell.NewAnonymousGoogleCloudStorageClient(client, expectedStatus), nil
+		}
+		return shell.NewGoogleCloudStorageClient(client, config.GoogleCredentials, expectedStatus), nil
+	})
+	registry.Register("s3", func(url.URL) (contracts.RemoteStorage, error) {
+		return shell.NewS3Client(client, config.AWSCredentials, expectedStatus), nil
+	})
+	registry.Register("azblob", func(url.URL) (contracts.RemoteStorage, error) {
+		return shell.NewAzureBlobStorageClient(client, config.AzureCredentials, expectedStatus), nil
+	})
+	registry.Register("oci", func(url.URL) (contracts.RemoteStorage, error) {
+		return shell.NewOCIRegistryClient(client, config.OCICredentials, expectedStatus), nil
+	})
+	registry.Register("file", func(url.URL) (contracts.RemoteStorage, error) {
+		return shell.NewFileSystemStorage(expectedStatus), nil
+	})
+	registry.Register("https", func(url.URL) (contracts.RemoteStorage, error) {
+		return shell.NewHTTPDownloader(client, config.HTTPCredentials, expectedStatus), nil
+	})
+	registry.Register("http", func(url.URL) (contracts.RemoteStorage, error) {
+		return shell.NewHTTPDownloader(client, config.HTTPCredentials, expectedStatus), nil
+	})
+	return registry
+}
+
+func uploadRemoteStorageConfig(config contracts.UploadConfig) RemoteStorageConfig {
+	return RemoteStorageConfig{
+		GoogleCredentials: config.GoogleCredentials,
+		AWSCredentials:    config.AWSCredentials,
+		AzureCredentials:  config.AzureCredentials,
+		OCICredentials:    config.OCICredentials,
+	}
+}
+
+// parseRemoteStorageCredentials loads credentials for only those schemes in use, so that
+// (for example) Google credentials aren't required to download from S3.
+func parseRemoteStorageCredentials(config *RemoteStorageConfig, schemes map[string]struct{}) (err error) {
+	disk := shell.NewDiskFileSystem("")
+	environment := shell.NewEnvironment()
+
+	config.HTTPCredentials, err = core.NewHTTPCredentialParser(environment).Parse()
+	if err != nil {
+		return err
+	}
+	config.OCICredentials = core.NewOCICredentialParser(environment).Parse()
+	if _, found := schemes["s3"]; found {
+		config.AWSCredentials, err = core.NewAWSCredentialParser(disk, environment).Parse()
+		if err != nil {
+			return err
+		}
+	}
+	if _, found := schemes["azblob"]; found {
+		config.AzureCredentials, err = core.NewAzureCredentialParser(environment).Parse()
+		if err != nil {
+			return err
+		}
+	}
+	if _, found := schemes["gcs"]; found && !config.Anonymous {
+		config.GoogleCredentials, err = core.NewGoogleCredentialParser(disk, environment).Parse()
+		if err != nil {
+			return fmt.Errorf("%w (for public buckets, use -anonymous instead)", err)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package contracts
 
 import (
+	"crypto/ed25519"
 	"net/url"
 	"path"
 
@@ -9,27 +10,63 @@ import (
 
 type UploadConfig struct {
 	MaxRetry          int
+	ChunkSize         int64
+	HashAlgorithm     string
+	SigningKey        ed25519.PrivateKey
 	GoogleCredentials gcs.Credentials
+	AWSCredentials    AWSCredentials
+	AzureCredentials  AzureCredentials
+	OCICredentials    OCICredentials
 	JSONPath          string
 	Overwrite         bool
+	ArchivePool       bool
+	Stream            bool
 	PackageConfig     PackageConfig
 }
 
 type PackageConfig struct {
-	CompressionAlgorithm string `json:"compression_algorithm"`
-	CompressionLevel     int    `json:"compression_level"`
-	SourceDirectory      string `json:"source_directory"`
-	PackageName          string `json:"package_name"`
-	PackageVersion       string `json:"package_version"`
-	RemoteAddressPrefix  *URL   `json:"remote_address"`
+	CompressionAlgorithm string      `json:"compression_algorithm"`
+	CompressionLevel     int         `json:"compression_level"`
+	SourceDirectory      string      `json:"source_directory"`
+	PackageName          string      `json:"package_name"`
+	PackageVersion       string      `json:"package_version"`
+	RemoteAddressPrefix  *URL        `json:"remote_address"`
+	Zstd                 ZstdOptions `json:"zstd"`
+}
+
+// ZstdOptions tune the zstd compression algorithm (and are only valid in combination with it).
+type ZstdOptions struct {
+	WindowSize  int    `json:"window_size"` // a power of two, in bytes
+	Long        bool   `json:"long"`        // matches over a 128 MiB window (like 'zstd --long')
+	Concurrency int    `json:"concurrency"`
+	Dictionary  string `json:"dictionary"` // path to a dictionary trained with 'zstd --train'
 }
 
 func (this PackageConfig) ComposeRemoteAddress(filename string) url.URL {
 	return AppendRemotePath(url.URL(*this.RemoteAddressPrefix), this.PackageName, this.PackageVersion, filename)
 }
 
+func (this PackageConfig) ComposeArchiveAddress(archive Archive) url.URL {
+	dependency := Dependency{
+		PackageName:    this.PackageName,
+		PackageVersion: this.PackageVersion,
+		RemoteAddress:  *this.RemoteAddressPrefix,
+	}
+	return dependency.ComposeArchiveAddress(archive)
+}
+
+func (this PackageConfig) ComposeDictionaryAddress(dictionary Dictionary) url.URL {
+	return Dependency{RemoteAddress: *this.RemoteAddressPrefix}.ComposeDictionaryAddress(dictionary)
+}
+
 func (this PackageConfig) ComposeLatestManifestRemoteAddress() url.URL {
 	address := url.URL(*this.RemoteAddressPrefix)
 	address.Path = path.Join(address.Path, this.PackageName, RemoteManifestFilename)
 	return address
 }
+
+func (this PackageConfig) ComposeVersionIndexRemoteAddress() url.URL {
+	address := url.URL(*this.RemoteAddressPrefix)
+	address.Path = path.Join(address.Path, this.PackageName, RemoteVersionIndexFilename)
+	return address
+}
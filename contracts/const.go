@@ -1,6 +1,9 @@
 package contracts
 
 const (
-	RemoteManifestFilename = "manifest.json"
-	RemoteArchiveFilename  = "archive"
+	RemoteManifestFilename     = "manifest.json"
+	RemoteVersionIndexFilename = "versions.json" // beside the 'latest' manifest
+	RemoteArchiveFilename      = "archive"
+	RemoteArchivePool          = "pool"
+	RemoteDictionaryPool       = "dictionaries"
 )
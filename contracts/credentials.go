@@ -0,0 +1,30 @@
+package contracts
+
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Region          string
+	Endpoint        string
+}
+
+// HTTPCredentials are sent only to the host (e.g. "packages.example.com:8443") over https.
+type HTTPCredentials struct {
+	Host        string
+	BearerToken string
+	Username    string
+	Password    string
+}
+
+type AzureCredentials struct {
+	AccountName string
+	AccountKey  string
+	SASToken    string
+	Endpoint    string
+}
+
+type OCICredentials struct {
+	Username  string
+	Password  string
+	PlainHTTP bool
+}
@@ -12,10 +12,20 @@ import (
 
 type DependencyListing struct {
 	Listing []Dependency `json:"dependencies"`
+	Mirrors []URL        `json:"mirrors,omitempty"` // consulted for every dependency, after its own mirrors
+
+	// TrustedKeys (base64-encoded ed25519 public keys) require every package to be signed by one of them.
+	TrustedKeys []string `json:"trusted_keys,omitempty"`
 }
 
 func (this *DependencyListing) Validate() error {
 	inventory := make(map[string]struct{}) // map[PackageName+LocalDirectory]struct
+	directories := make(map[string]bool)   // map[LocalDirectory]SideBySide
+
+	err := validateMirrors(this.Mirrors)
+	if err != nil {
+		return err
+	}
 
 	for i, dependency := range this.Listing {
 		if dependency.LocalDirectory == "" {
@@ -30,7 +40,16 @@ func (this *DependencyListing) Validate() error {
 		if dependency.RemoteAddress.Value().String() == "" {
 			return errors.New("remote address is required")
 		}
+		if err = validateMirrors(dependency.Mirrors); err != nil {
+			return err
+		}
+		if dependency.RetainedVersions < 0 {
+			return errors.New("retained versions must not be negative")
+		}
 
+		if dependency.ListedLocalDirectory == "" {
+			dependency.ListedLocalDirectory = dependency.LocalDirectory
+		}
 		dependency.LocalDirectory = resolveLocalDirectory(dependency.LocalDirectory)
 		this.Listing[i] = dependency
 		key := fmt.Sprintf("%s %s", dependency.PackageName, dependency.LocalDirectory)
@@ -39,6 +58,20 @@ func (this *DependencyListing) Validate() error {
 		}
 
 		inventory[key] = struct{}{}
+
+		sideBySide, found := directories[dependency.LocalDirectory]
+		if found && (sideBySide || dependency.SideBySide) {
+			return errors.New("side-by-side local directory conflict (the local directory must be dedicated to a single package)")
+		}
+		directories[dependency.LocalDirectory] = dependency.SideBySide
+	}
+	return nil
+}
+func validateMirrors(mirrors []URL) error {
+	for _, mirror := range mirrors {
+		if mirror.Value().String() == "" {
+			return errors.New("mirror address is required")
+		}
 	}
 	return nil
 }
@@ -65,7 +98,35 @@ type Dependency struct {
 	PackageName    string `json:"package_name"`
 	PackageVersion string `json:"package_version"`
 	RemoteAddress  URL    `json:"remote_address"`
+	Mirrors        []URL  `json:"mirrors,omitempty"`
 	LocalDirectory string `json:"local_directory"`
+
+	// ListedLocalDirectory is the local directory as written in the listing (i.e. before '~/' and
+	// $HOME are expanded) so that a lockfile remains valid for everyone sharing the listing.
+	ListedLocalDirectory string `json:"-"`
+
+	// SideBySide installs each version into <local_directory>/.versions/<version>/ and points the
+	// <local_directory>/current symlink at the active version, keeping RetainedVersions previous
+	// versions (DefaultRetainedVersions when unspecified) available for 'satisfy rollback'.
+	SideBySide       bool `json:"side_by_side,omitempty"`
+	RetainedVersions int  `json:"retained_versions,omitempty"`
+}
+
+const DefaultRetainedVersions = 1
+
+// LockedLocalDirectory identifies the dependency within a lockfile (along with its package name).
+func (this Dependency) LockedLocalDirectory() string {
+	if this.ListedLocalDirectory != "" {
+		return this.ListedLocalDirectory
+	}
+	return this.LocalDirectory
+}
+
+func (this Dependency) RetainedVersionCount() int {
+	if this.RetainedVersions > 0 {
+		return this.RetainedVersions
+	}
+	return DefaultRetainedVersions
 }
 
 func (this Dependency) ComposeRemoteAddress(fileName string) url.URL {
@@ -76,9 +137,37 @@ func (this Dependency) ComposeRemoteAddress(fileName string) url.URL {
 		fileName,
 	)
 }
+
+// ComposeArchiveAddress honors the archive location recorded in the manifest, falling back
+// to the archive filename within the directory of the package version.
+func (this Dependency) ComposeArchiveAddress(archive Archive) url.URL {
+	if archive.Location != "" {
+		address := url.URL(this.RemoteAddress)
+		address.Path = path.Join("/", address.Path, archive.Location)
+		return address
+	}
+	if archive.Filename != "" {
+		return this.ComposeRemoteAddress(archive.Filename)
+	}
+	return this.ComposeRemoteAddress(RemoteArchiveFilename)
+}
+
+func (this Dependency) ComposeDictionaryAddress(dictionary Dictionary) url.URL {
+	address := url.URL(this.RemoteAddress)
+	address.Path = path.Join("/", address.Path, dictionary.Location)
+	return address
+}
 func (this Dependency) ComposeLatestManifestRemoteAddress() url.URL {
 	address := url.URL(this.RemoteAddress)
-	address.Path = "/" + path.Join(address.Path, this.PackageName, RemoteManifestFilename)
+	address.Path = path.Join("/", address.Path, this.PackageName, RemoteManifestFilename)
+	return address
+}
+
+// ComposeVersionIndexRemoteAddress locates the index of the published versions of the package,
+// against which a version constraint is resolved.
+func (this Dependency) ComposeVersionIndexRemoteAddress() url.URL {
+	address := url.URL(this.RemoteAddress)
+	address.Path = path.Join("/", address.Path, this.PackageName, RemoteVersionIndexFilename)
 	return address
 }
 func (this Dependency) Title() string {
@@ -63,6 +63,24 @@ func (this *DependencyListingFixture) TestValidateEachDependencyMustHaveARemoteA
 	this.So(err, should.NotBeNil)
 }
 
+func (this *DependencyListingFixture) TestValidateEachMirrorMustHaveAnAddress() {
+	this.appendDependency("name", "1.2.3", "host", "local")
+	this.listing.Listing[0].Mirrors = []URL{{Host: "mirror"}, {}}
+
+	err := this.listing.Validate()
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *DependencyListingFixture) TestValidateEachGlobalMirrorMustHaveAnAddress() {
+	this.appendDependency("name", "1.2.3", "host", "local")
+	this.listing.Mirrors = []URL{{}}
+
+	err := this.listing.Validate()
+
+	this.So(err, should.NotBeNil)
+}
+
 func (this *DependencyListingFixture) TestValidateResolvesLocalDirectory() {
 	this.appendDependency("name", "1.2.3", "address", "~/")
 	this.appendDependency("name", "1.2.3", "address", "~/path1")
@@ -74,10 +92,10 @@ func (this *DependencyListingFixture) TestValidateResolvesLocalDirectory() {
 
 	this.So(err, should.BeNil)
 	this.So(this.listing.Listing, should.Resemble, []Dependency{
-		{PackageName: "name", PackageVersion: "1.2.3", RemoteAddress: URL{Host: "address"}, LocalDirectory: home},
-		{PackageName: "name", PackageVersion: "1.2.3", RemoteAddress: URL{Host: "address"}, LocalDirectory: home + "/path1"},
-		{PackageName: "name", PackageVersion: "1.2.3", RemoteAddress: URL{Host: "address"}, LocalDirectory: home + "/path2"},
-		{PackageName: "name", PackageVersion: "1.2.3", RemoteAddress: URL{Host: "address"}, LocalDirectory: home + "/path3"},
+		{PackageName: "name", PackageVersion: "1.2.3", RemoteAddress: URL{Host: "address"}, LocalDirectory: home, ListedLocalDirectory: "~/"},
+		{PackageName: "name", PackageVersion: "1.2.3", RemoteAddress: URL{Host: "address"}, LocalDirectory: home + "/path1", ListedLocalDirectory: "~/path1"},
+		{PackageName: "name", PackageVersion: "1.2.3", RemoteAddress: URL{Host: "address"}, LocalDirectory: home + "/path2", ListedLocalDirectory: "$HOME/path2"},
+		{PackageName: "name", PackageVersion: "1.2.3", RemoteAddress: URL{Host: "address"}, LocalDirectory: home + "/path3", ListedLocalDirectory: "${HOME}/path3"},
 	})
 }
 
@@ -90,6 +108,31 @@ func (this *DependencyListingFixture) TestMultiplePackagesWithSameNameCannotBeIn
 	this.So(err, should.NotBeNil)
 }
 
+func (this *DependencyListingFixture) TestSideBySidePackageRequiresDedicatedLocalDirectory() {
+	this.appendDependency("name1", "1.2.3", "host", "local")
+	this.appendDependency("name2", "1.2.3", "host", "local")
+	this.listing.Listing[1].SideBySide = true
+
+	err := this.listing.Validate()
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *DependencyListingFixture) TestRetainedVersionsMustNotBeNegative() {
+	this.appendDependency("name", "1.2.3", "host", "local")
+	this.listing.Listing[0].SideBySide = true
+	this.listing.Listing[0].RetainedVersions = -1
+
+	err := this.listing.Validate()
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *DependencyListingFixture) TestRetainedVersionCount() {
+	this.So(Dependency{}.RetainedVersionCount(), should.Equal, DefaultRetainedVersions)
+	this.So(Dependency{RetainedVersions: 3}.RetainedVersionCount(), should.Equal, 3)
+}
+
 func (this *DependencyListingFixture) TestAppendRemoteAddress() {
 	address, err := url.Parse("https://www.google.com/folder")
 	this.So(err, should.BeNil)
@@ -116,6 +159,19 @@ func (this *DependencyListingFixture) TestAppendRemoteAddressLatest() {
 	this.So(actual.String(), should.Equal, "https://www.google.com/folder/package-name/manifest")
 }
 
+func (this *DependencyListingFixture) TestComposeLatestManifestRemoteAddress() {
+	address, err := url.Parse("file:///srv/packages")
+	this.So(err, should.BeNil)
+	dependency := Dependency{
+		PackageName:    "package-name",
+		PackageVersion: "latest",
+		RemoteAddress:  URL(*address),
+	}
+	actual := dependency.ComposeRemoteManifestAddress()
+
+	this.So(actual.String(), should.Equal, "file:///srv/packages/package-name/manifest.json")
+}
+
 func (this *DependencyListingFixture) TestTitleString() {
 	dependency := Dependency{
 		PackageName:    "package-name",
@@ -134,3 +190,31 @@ func (this *DependencyListingFixture) appendDependency(name, version, address, d
 		LocalDirectory: directory,
 	})
 }
+
+func (this *DependencyListingFixture) TestComposeArchiveAddress() {
+	dependency := Dependency{
+		PackageName:    "package-name",
+		PackageVersion: "1.2.3",
+		RemoteAddress:  URL{Scheme: "gcs", Host: "bucket", Path: "/prefix"},
+	}
+
+	pooled := dependency.ComposeArchiveAddress(Archive{Filename: "archive", Location: "pool/0123abcd"})
+	named := dependency.ComposeArchiveAddress(Archive{Filename: "archive.tar.gz"})
+	legacy := dependency.ComposeArchiveAddress(Archive{})
+
+	this.So(pooled.String(), should.Equal, "gcs://bucket/prefix/pool/0123abcd")
+	this.So(named.String(), should.Equal, "gcs://bucket/prefix/package-name/1.2.3/archive.tar.gz")
+	this.So(legacy.String(), should.Equal, "gcs://bucket/prefix/package-name/1.2.3/archive")
+}
+
+func (this *DependencyListingFixture) TestComposeDictionaryAddress() {
+	dependency := Dependency{
+		PackageName:    "package-name",
+		PackageVersion: "1.2.3",
+		RemoteAddress:  URL{Scheme: "gcs", Host: "bucket", Path: "/prefix"},
+	}
+
+	address := dependency.ComposeDictionaryAddress(Dictionary{Location: "dictionaries/0123abcd"})
+
+	this.So(address.String(), should.Equal, "gcs://bucket/prefix/dictionaries/0123abcd")
+}
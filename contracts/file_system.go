@@ -36,6 +36,18 @@ type Deleter interface {
 	Delete(path string)
 }
 
+type TreeDeleter interface {
+	DeleteAll(path string)
+}
+
+type Renamer interface {
+	Rename(source, target string) error
+}
+
+type DirectoryReader interface {
+	ReadDirectory(path string) (names []string, err error)
+}
+
 type FileChecker interface {
 	Stat(path string) (FileInfo, error)
 }
@@ -3,8 +3,9 @@ package contracts
 import "net/url"
 
 type InstallationRequest struct {
-	RemoteAddress url.URL
-	LocalPath     string
+	RemoteAddress     url.URL
+	DictionaryAddress url.URL
+	LocalPath         string
 }
 
 type IntegrityCheck interface {
@@ -13,6 +14,7 @@ type IntegrityCheck interface {
 
 type PackageInstaller interface {
 	DownloadManifest(remoteAddress url.URL) (manifest Manifest, err error)
+	DownloadVersionIndex(remoteAddress url.URL) (index VersionIndex, err error)
 	InstallManifest(request InstallationRequest) (manifest Manifest, err error)
 	InstallPackage(manifest Manifest, request InstallationRequest) error
 }
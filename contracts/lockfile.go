@@ -0,0 +1,54 @@
+package contracts
+
+import "sort"
+
+// Lockfile records the version to which each dependency was resolved (along with digests of its
+// manifest and archive) so that dependencies on the 'latest' version can be installed reproducibly.
+type Lockfile struct {
+	Packages []LockedPackage `json:"packages"`
+}
+
+type LockedPackage struct {
+	PackageName          string `json:"package_name"`
+	PackageVersion       string `json:"package_version"`
+	LocalDirectory       string `json:"local_directory"`
+	ManifestSHA256       []byte `json:"manifest_sha256"`
+	ArchiveHashAlgorithm string `json:"archive_hash_algorithm"`
+	ArchiveChecksum      []byte `json:"archive_checksum"`
+}
+
+// Lookup finds the locked package of the dependency (by name and local directory as listed, which
+// together identify a dependency within a listing).
+func (this Lockfile) Lookup(dependency Dependency) (LockedPackage, bool) {
+	for _, locked := range this.Packages {
+		if locked.PackageName == dependency.PackageName && locked.LocalDirectory == dependency.LockedLocalDirectory() {
+			return locked, true
+		}
+	}
+	return LockedPackage{}, false
+}
+
+// Lock adds (or replaces) the locked packages, keeping the packages sorted so that changes to the
+// lockfile are easy to review.
+func (this *Lockfile) Lock(packages ...LockedPackage) {
+	for _, locked := range packages {
+		this.remove(locked)
+		this.Packages = append(this.Packages, locked)
+	}
+	sort.Slice(this.Packages, func(i, j int) bool {
+		if this.Packages[i].PackageName != this.Packages[j].PackageName {
+			return this.Packages[i].PackageName < this.Packages[j].PackageName
+		}
+		return this.Packages[i].LocalDirectory < this.Packages[j].LocalDirectory
+	})
+}
+
+func (this *Lockfile) remove(locked LockedPackage) {
+	kept := this.Packages[:0]
+	for _, existing := range this.Packages {
+		if existing.PackageName != locked.PackageName || existing.LocalDirectory != locked.LocalDirectory {
+			kept = append(kept, existing)
+		}
+	}
+	this.Packages = kept
+}
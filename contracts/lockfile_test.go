@@ -0,0 +1,63 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+)
+
+func TestLockfileFixture(t *testing.T) {
+	gunit.Run(new(LockfileFixture), t)
+}
+
+type LockfileFixture struct {
+	*gunit.Fixture
+
+	lockfile Lockfile
+}
+
+func (this *LockfileFixture) Setup() {
+	this.lockfile.Lock(
+		LockedPackage{PackageName: "b", PackageVersion: "1", LocalDirectory: "x"},
+		LockedPackage{PackageName: "a", PackageVersion: "1", LocalDirectory: "y"},
+		LockedPackage{PackageName: "a", PackageVersion: "2", LocalDirectory: "x"},
+	)
+}
+
+func (this *LockfileFixture) TestPackagesSortedByNameAndLocalDirectory() {
+	this.So(this.lockfile.Packages, should.Resemble, []LockedPackage{
+		{PackageName: "a", PackageVersion: "2", LocalDirectory: "x"},
+		{PackageName: "a", PackageVersion: "1", LocalDirectory: "y"},
+		{PackageName: "b", PackageVersion: "1", LocalDirectory: "x"},
+	})
+}
+
+func (this *LockfileFixture) TestLockedPackageReplaced() {
+	this.lockfile.Lock(LockedPackage{PackageName: "a", PackageVersion: "3", LocalDirectory: "y"})
+
+	this.So(this.lockfile.Packages, should.HaveLength, 3)
+	this.So(this.lockfile.Packages[1].PackageVersion, should.Equal, "3")
+}
+
+func (this *LockfileFixture) TestLookupByNameAndLocalDirectory() {
+	locked, found := this.lockfile.Lookup(Dependency{PackageName: "a", PackageVersion: "latest", LocalDirectory: "y"})
+
+	this.So(found, should.BeTrue)
+	this.So(locked.PackageVersion, should.Equal, "1")
+}
+
+func (this *LockfileFixture) TestLookupByLocalDirectoryAsListed() {
+	this.lockfile.Lock(LockedPackage{PackageName: "c", PackageVersion: "1", LocalDirectory: "~/z"})
+
+	locked, found := this.lockfile.Lookup(Dependency{PackageName: "c", LocalDirectory: "/home/someone/z", ListedLocalDirectory: "~/z"})
+
+	this.So(found, should.BeTrue)
+	this.So(locked.PackageVersion, should.Equal, "1")
+}
+
+func (this *LockfileFixture) TestLookupNotFound() {
+	_, found := this.lockfile.Lookup(Dependency{PackageName: "b", LocalDirectory: "y"})
+
+	this.So(found, should.BeFalse)
+}
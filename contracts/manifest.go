@@ -1,21 +1,56 @@
 package contracts
 
+import (
+	"encoding/hex"
+	"path"
+)
+
 type Manifest struct {
 	Name    string  `json:"name"` //a-z 0-9 _-/
 	Version string  `json:"version"`
 	Archive Archive `json:"archive"`
 }
 
+// Archive records an MD5 checksum of the archive (and of each item and dictionary) for compatibility
+// with older versions of satisfy. Newer manifests also record a digest calculated with a stronger
+// hash algorithm, which is verified in preference to the MD5 checksum.
 type Archive struct {
 	Filename             string        `json:"filename"`
+	Location             string        `json:"location,omitempty"` // relative to the remote address prefix
 	Size                 uint64        `json:"size"`
 	MD5Checksum          []byte        `json:"md5"`
+	HashAlgorithm        string        `json:"hash_algorithm,omitempty"` // of each Checksum (e.g. sha256)
+	Checksum             []byte        `json:"checksum,omitempty"`
 	Contents             []ArchiveItem `json:"contents"`
 	CompressionAlgorithm string        `json:"compression"`
+	Dictionary           *Dictionary   `json:"dictionary,omitempty"`
+}
+
+// Dictionary describes the (zstd) compression dictionary required to decompress an archive.
+type Dictionary struct {
+	ID          uint32 `json:"id"`
+	Location    string `json:"location"` // relative to the remote address prefix
+	Size        uint64 `json:"size"`
+	MD5Checksum []byte `json:"md5"`
+	Checksum    []byte `json:"checksum,omitempty"`
 }
 
 type ArchiveItem struct {
 	Path        string `json:"path"`
 	Size        int64  `json:"size"`
 	MD5Checksum []byte `json:"md5"`
+	Checksum    []byte `json:"checksum,omitempty"`
+}
+
+// ComposeArchivePoolLocation names an archive by the digest of its contents (calculated with the
+// stronger hash algorithm rather than MD5, which admits collisions) within the pool shared by all
+// packages beneath a remote address prefix.
+func ComposeArchivePoolLocation(checksum []byte) string {
+	return path.Join(RemoteArchivePool, hex.EncodeToString(checksum))
+}
+
+// ComposeDictionaryLocation names a compression dictionary by the (strong) digest of its contents so
+// that every version of every package beneath a remote address prefix may share it.
+func ComposeDictionaryLocation(checksum []byte) string {
+	return path.Join(RemoteDictionaryPool, hex.EncodeToString(checksum))
 }
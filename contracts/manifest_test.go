@@ -22,18 +22,28 @@ func (this *ManifestFixture) TestMarshalManifest() {
 		Version: "1.2.3",
 		Archive: Archive{
 			Filename:    "filename",
+			Location:    "pool/636865636b73756d",
 			Size:        1,
 			MD5Checksum: []byte("checksum"),
 			Contents: []ArchiveItem{
 				{Path: "item1", Size: 1, MD5Checksum: []byte("item1")},
 				{Path: "item2", Size: 2, MD5Checksum: []byte("item2")},
 			},
+			Dictionary: &Dictionary{ID: 42, Location: "dictionaries/64696374", Size: 4, MD5Checksum: []byte("dict")},
 		},
 	}
 	clone := this.unmarshal(this.marshal(original))
 	this.So(clone, should.Resemble, original)
 }
 
+func (this *ManifestFixture) TestComposeArchivePoolLocation() {
+	this.So(ComposeArchivePoolLocation([]byte("checksum")), should.Equal, "pool/636865636b73756d")
+}
+
+func (this *ManifestFixture) TestComposeDictionaryLocation() {
+	this.So(ComposeDictionaryLocation([]byte("checksum")), should.Equal, "dictionaries/636865636b73756d")
+}
+
 func (this *ManifestFixture) unmarshal(raw []byte) Manifest {
 	var clone Manifest
 	err := json.Unmarshal(raw, &clone)
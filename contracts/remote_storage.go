@@ -26,10 +26,75 @@ type UploadRequest struct {
 	Checksum      []byte
 }
 
+// SessionUploader is implemented by remote storage which can upload a large object in parts through
+// an upload session. Each part is committed by the remote storage as it arrives, so that a failed
+// upload continues from the last byte committed rather than from the beginning.
+type SessionUploader interface {
+	BeginUpload(UploadRequest) (UploadSession, error)
+}
+
+type UploadSession interface {
+	// Committed reports how many bytes of the object have been committed by the remote storage.
+	Committed() (int64, error)
+
+	// UploadPart sends the bytes of the object which follow the offset. The final part (which may
+	// be empty) establishes the size of the object.
+	UploadPart(part []byte, offset int64, final bool) error
+
+	// Complete finalizes the object (once the final part has been sent) and verifies it against
+	// the checksum (if any).
+	Complete(checksum []byte) error
+
+	// Abort discards the parts of an object which will never be completed.
+	Abort() error
+}
+
 type Downloader interface {
 	Download(url.URL) (io.ReadCloser, error)
 }
 
+// RangeDownloader is implemented by remote storage that can serve part of a remote object: the
+// bytes from the offset to the end or, when the length is positive, the length bytes which follow.
+type RangeDownloader interface {
+	DownloadRange(address url.URL, offset, length int64) (io.ReadCloser, error)
+}
+
+// Validator identifies the version of a remote object (by its ETag and/or Last-Modified date), so
+// that a download resumed part way through isn't spliced together from two versions of the object.
+type Validator struct {
+	ETag         string
+	LastModified string
+}
+
+// Differs reports whether the validators identify different versions of an object (comparing
+// their ETags when both have them, otherwise their Last-Modified dates when both have them).
+func (this Validator) Differs(that Validator) bool {
+	if this.ETag != "" && that.ETag != "" {
+		return this.ETag != that.ETag
+	}
+	return this.LastModified != "" && that.LastModified != "" && this.LastModified != that.LastModified
+}
+
+// ValidatedBody is implemented by the bodies of downloads which identify the version of the remote object.
+type ValidatedBody interface {
+	Validator() Validator
+}
+
+// ConditionalRangeDownloader is implemented by remote storage that can serve part of a remote object
+// provided that it's still the version identified by the validator (failing with ObjectChangedErr
+// otherwise).
+type ConditionalRangeDownloader interface {
+	DownloadRangeIfUnchanged(address url.URL, offset, length int64, validator Validator) (io.ReadCloser, error)
+}
+
+// Lister is implemented by remote storage that can enumerate the entries (e.g. package versions)
+// immediately beneath an address.
+type Lister interface {
+	List(url.URL) ([]string, error)
+}
+
+type RemoteStorageFactory func(address url.URL) (RemoteStorage, error)
+
 func AppendRemotePath(prefix url.URL, packageName, version, fileName string) url.URL {
 	if version == "latest" {
 		prefix.Path = path.Join(prefix.Path, packageName, fileName)
@@ -44,6 +109,12 @@ func AppendRemotePath(prefix url.URL, packageName, version, fileName string) url
 
 var RetryErr = errors.New("retry")
 
+var RangeUnsupportedErr = errors.New("ranged downloads are not supported")
+
+var SessionUnsupportedErr = errors.New("upload sessions are not supported")
+
+var ObjectChangedErr = errors.New("the remote object changed during the download")
+
 type StatusCodeError struct {
 	actualStatusCode   int
 	expectedStatusCode int
@@ -0,0 +1,18 @@
+package contracts
+
+import "net/url"
+
+// ManifestSignature is a detached signature over the exact bytes of a manifest, stored next to the
+// manifest with the SignatureExtension appended to its name.
+type ManifestSignature struct {
+	Algorithm string `json:"algorithm"` // ed25519
+	KeyID     string `json:"key_id"`
+	Signature []byte `json:"signature"`
+}
+
+const SignatureExtension = ".sig"
+
+func ComposeSignatureAddress(manifestAddress url.URL) url.URL {
+	manifestAddress.Path += SignatureExtension
+	return manifestAddress
+}
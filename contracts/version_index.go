@@ -0,0 +1,17 @@
+package contracts
+
+// VersionIndex lists the published versions of a package (in the order of publication).
+type VersionIndex struct {
+	Versions []string `json:"versions"`
+}
+
+// Add appends the version to the index, reporting whether the index didn't already include it.
+func (this *VersionIndex) Add(version string) bool {
+	for _, existing := range this.Versions {
+		if existing == version {
+			return false
+		}
+	}
+	this.Versions = append(this.Versions, version)
+	return true
+}
@@ -0,0 +1,33 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+)
+
+func TestVersionIndexFixture(t *testing.T) {
+	gunit.Run(new(VersionIndexFixture), t)
+}
+
+type VersionIndexFixture struct {
+	*gunit.Fixture
+}
+
+func (this *VersionIndexFixture) TestVersionsAddedInOrderOfPublication() {
+	var index VersionIndex
+
+	this.So(index.Add("1.2.4"), should.BeTrue)
+	this.So(index.Add("1.2.3"), should.BeTrue)
+	this.So(index.Add("1.2.4"), should.BeFalse)
+	this.So(index.Versions, should.Resemble, []string{"1.2.4", "1.2.3"})
+}
+
+func (this *VersionIndexFixture) TestVersionIndexAddress() {
+	dependency := Dependency{PackageName: "a/b", PackageVersion: "^1.2", RemoteAddress: URL{Scheme: "gcs", Host: "bucket", Path: "/prefix"}}
+
+	address := dependency.ComposeVersionIndexRemoteAddress()
+
+	this.So(address.String(), should.Equal, "gcs://bucket/prefix/a/b/versions.json")
+}
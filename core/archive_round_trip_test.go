@@ -0,0 +1,131 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"io/ioutil"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestArchiveRoundTripFixture(t *testing.T) {
+	gunit.Run(new(ArchiveRoundTripFixture), t)
+}
+
+type ArchiveRoundTripFixture struct {
+	*gunit.Fixture
+	source     *inMemoryFileSystem
+	target     *inMemoryFileSystem
+	downloader *FakeDownloader
+	installer  *PackageInstaller
+}
+
+func (this *ArchiveRoundTripFixture) Setup() {
+	this.source = newInMemoryFileSystem()
+	this.source.WriteFile("/in/bin/tool", []byte("#!/bin/sh"))
+	_ = this.source.Chmod("/in/bin/tool", 0755)
+	this.source.WriteFile("/in/data.txt", []byte("data"))
+	this.source.CreateSymlink("/in/bin/tool", "/in/links/tool")
+	this.source.Root = "/in"
+
+	this.target = newInMemoryFileSystem()
+	this.downloader = &FakeDownloader{}
+	this.installer = NewPackageInstaller(this.downloader, this.target, nil, newMemoryArchiveSpool)
+}
+
+func (this *ArchiveRoundTripFixture) TestZipPreservesSymlinksAndExecutableBits() {
+	buffer := bytes.NewBuffer(nil)
+
+	manifest := this.build(NewZipArchiveWriter(buffer, 6), zipAlgorithm)
+	this.install(buffer, manifest)
+
+	this.assertInstalled()
+}
+
+func (this *ArchiveRoundTripFixture) TestTarPreservesSymlinksAndExecutableBits() {
+	buffer := bytes.NewBuffer(nil)
+	compressor := gzip.NewWriter(buffer)
+
+	manifest := this.build(NewTarArchiveWriter(compressor), gzipAlgorithm)
+	_ = compressor.Close()
+	this.install(buffer, manifest)
+
+	this.assertInstalled()
+}
+
+func (this *ArchiveRoundTripFixture) TestSHA256ChecksumsVerifiedInPreferenceToMD5() {
+	buffer := bytes.NewBuffer(nil)
+	builder := NewPackageBuilder(this.source, NewZipArchiveWriter(buffer, 6), md5.New(), sha256.New())
+	this.So(builder.Build(), should.BeNil)
+	checksum := sha256.Sum256(buffer.Bytes())
+	manifest := contracts.Manifest{
+		Archive: contracts.Archive{
+			MD5Checksum:          []byte("not verified"),
+			HashAlgorithm:        "sha256",
+			Checksum:             checksum[:],
+			Contents:             builder.Contents(),
+			CompressionAlgorithm: zipAlgorithm,
+		},
+	}
+	this.downloader.Body = ioutil.NopCloser(bytes.NewReader(buffer.Bytes()))
+
+	err := this.installer.InstallPackage(manifest, contracts.InstallationRequest{LocalPath: "local"})
+
+	this.So(err, should.BeNil)
+	this.assertInstalled()
+	data := sha256.Sum256([]byte("data"))
+	this.So(manifest.Archive.Contents[1].Path, should.Equal, "data.txt")
+	this.So(manifest.Archive.Contents[1].Checksum, should.Resemble, data[:])
+	integrity := NewFileContentIntegrityCheck(md5.New, this.target, true)
+	this.So(integrity.Verify(manifest, "local"), should.BeNil)
+}
+
+func (this *ArchiveRoundTripFixture) TestSHA256ChecksumMismatch() {
+	buffer := bytes.NewBuffer(nil)
+	manifest := this.build(NewZipArchiveWriter(buffer, 6), zipAlgorithm)
+	checksum := md5.Sum(buffer.Bytes())
+	manifest.Archive.MD5Checksum = checksum[:]
+	manifest.Archive.HashAlgorithm = "sha256"
+	manifest.Archive.Checksum = make([]byte, sha256.Size)
+	this.downloader.Body = ioutil.NopCloser(bytes.NewReader(buffer.Bytes()))
+
+	err := this.installer.InstallPackage(manifest, contracts.InstallationRequest{LocalPath: "local"})
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "(sha256)")
+}
+
+func (this *ArchiveRoundTripFixture) build(writer contracts.ArchiveWriter, algorithm string) contracts.Manifest {
+	builder := NewPackageBuilder(this.source, writer, md5.New(), nil)
+	this.So(builder.Build(), should.BeNil)
+	return contracts.Manifest{
+		Archive: contracts.Archive{
+			Contents:             builder.Contents(),
+			CompressionAlgorithm: algorithm,
+		},
+	}
+}
+
+func (this *ArchiveRoundTripFixture) install(buffer *bytes.Buffer, manifest contracts.Manifest) {
+	checksum := md5.Sum(buffer.Bytes())
+	manifest.Archive.MD5Checksum = checksum[:]
+	this.downloader.Body = ioutil.NopCloser(bytes.NewReader(buffer.Bytes()))
+
+	err := this.installer.InstallPackage(manifest, contracts.InstallationRequest{LocalPath: "local"})
+
+	this.So(err, should.BeNil)
+}
+
+func (this *ArchiveRoundTripFixture) assertInstalled() {
+	this.So(this.target.readFile("local/bin/tool"), should.Resemble, []byte("#!/bin/sh"))
+	this.So(this.target.fileSystem["local/bin/tool"].Mode(), should.Equal, 0755)
+	this.So(this.target.readFile("local/data.txt"), should.Resemble, []byte("data"))
+	this.So(this.target.fileSystem["local/data.txt"].Mode(), should.NotEqual, 0755)
+	this.So(this.target.fileSystem["local/links/tool"].Symlink(), should.Equal, "../bin/tool")
+	this.So(this.target.readFile("local/links/tool"), should.Resemble, []byte("#!/bin/sh"))
+}
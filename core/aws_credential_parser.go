@@ -0,0 +1,97 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+type AWSCredentialParser struct {
+	storage     contracts.FileReader
+	environment contracts.Environment
+}
+
+func NewAWSCredentialParser(storage contracts.FileReader, environment contracts.Environment) AWSCredentialParser {
+	return AWSCredentialParser{storage: storage, environment: environment}
+}
+
+func (this AWSCredentialParser) Parse() (credentials contracts.AWSCredentials, err error) {
+	profile := this.lookup("AWS_PROFILE", "default")
+	shared := this.readProfile(this.lookup("AWS_SHARED_CREDENTIALS_FILE", this.homePath(".aws", "credentials")), profile)
+	configSection := "profile " + profile
+	if profile == "default" {
+		configSection = profile
+	}
+	config := this.readProfile(this.lookup("AWS_CONFIG_FILE", this.homePath(".aws", "config")), configSection)
+
+	credentials.AccessKeyID = this.lookup("AWS_ACCESS_KEY_ID", shared["aws_access_key_id"])
+	credentials.SecretAccessKey = this.lookup("AWS_SECRET_ACCESS_KEY", shared["aws_secret_access_key"])
+	credentials.SessionToken = this.lookup("AWS_SESSION_TOKEN", shared["aws_session_token"])
+	credentials.Region = this.lookup("AWS_REGION", this.lookup("AWS_DEFAULT_REGION", config["region"]))
+	credentials.Endpoint = this.lookup("AWS_ENDPOINT_URL_S3", this.lookup("AWS_ENDPOINT_URL", config["endpoint_url"]))
+
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return contracts.AWSCredentials{}, errors.New("the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (or a shared credentials file) are required")
+	}
+	if credentials.Region == "" {
+		credentials.Region = defaultAWSRegion
+	}
+	return credentials, nil
+}
+
+func (this AWSCredentialParser) lookup(key, fallback string) string {
+	value, found := this.environment.LookupEnv(key)
+	value = strings.TrimSpace(value)
+	if !found || value == "" {
+		return fallback
+	}
+	return value
+}
+
+func (this AWSCredentialParser) homePath(elements ...string) string {
+	home := this.lookup("HOME", "")
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(append([]string{home}, elements...)...)
+}
+
+func (this AWSCredentialParser) readProfile(path, section string) map[string]string {
+	if path == "" {
+		return nil
+	}
+	raw, err := this.storage.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return parseINISection(raw, section)
+}
+
+func parseINISection(raw []byte, section string) map[string]string {
+	values := make(map[string]string)
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if current != section {
+			continue
+		}
+		if index := strings.Index(line, "="); index > 0 {
+			values[strings.TrimSpace(line[:index])] = strings.TrimSpace(line[index+1:])
+		}
+	}
+	return values
+}
+
+const defaultAWSRegion = "us-east-1"
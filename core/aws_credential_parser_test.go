@@ -0,0 +1,135 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestAWSCredentialParserFixture(t *testing.T) {
+	gunit.Run(new(AWSCredentialParserFixture), t)
+}
+
+type AWSCredentialParserFixture struct {
+	*gunit.Fixture
+
+	parser      AWSCredentialParser
+	storage     *inMemoryFileSystem
+	environment FakeEnvironment
+}
+
+func (this *AWSCredentialParserFixture) Setup() {
+	this.storage = newInMemoryFileSystem()
+	this.environment = make(FakeEnvironment)
+	this.environment["HOME"] = "/home/user"
+	this.parser = NewAWSCredentialParser(this.storage, this.environment)
+}
+
+func (this *AWSCredentialParserFixture) TestCredentialsFromEnvironment() {
+	this.environment["AWS_ACCESS_KEY_ID"] = "access"
+	this.environment["AWS_SECRET_ACCESS_KEY"] = "secret"
+	this.environment["AWS_SESSION_TOKEN"] = "token"
+	this.environment["AWS_REGION"] = "us-west-2"
+	this.environment["AWS_ENDPOINT_URL_S3"] = "http://localhost:9000"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, contracts.AWSCredentials{
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+		Region:          "us-west-2",
+		Endpoint:        "http://localhost:9000",
+	})
+}
+
+func (this *AWSCredentialParserFixture) TestCredentialsFromDefaultProfileFiles() {
+	this.storage.WriteFile("/home/user/.aws/credentials", []byte(awsCredentialsFile))
+	this.storage.WriteFile("/home/user/.aws/config", []byte(awsConfigFile))
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, contracts.AWSCredentials{
+		AccessKeyID:     "default-access",
+		SecretAccessKey: "default-secret",
+		Region:          "eu-west-1",
+	})
+}
+
+func (this *AWSCredentialParserFixture) TestCredentialsFromNamedProfile() {
+	this.environment["AWS_PROFILE"] = "minio"
+	this.environment["AWS_SHARED_CREDENTIALS_FILE"] = "/etc/aws/credentials"
+	this.environment["AWS_CONFIG_FILE"] = "/etc/aws/config"
+	this.storage.WriteFile("/etc/aws/credentials", []byte(awsCredentialsFile))
+	this.storage.WriteFile("/etc/aws/config", []byte(awsConfigFile))
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, contracts.AWSCredentials{
+		AccessKeyID:     "minio-access",
+		SecretAccessKey: "minio-secret",
+		SessionToken:    "minio-token",
+		Region:          "local",
+		Endpoint:        "http://localhost:9000",
+	})
+}
+
+func (this *AWSCredentialParserFixture) TestEnvironmentTakesPrecedenceOverFiles() {
+	this.storage.WriteFile("/home/user/.aws/credentials", []byte(awsCredentialsFile))
+	this.environment["AWS_ACCESS_KEY_ID"] = "access"
+	this.environment["AWS_SECRET_ACCESS_KEY"] = "secret"
+	this.environment["AWS_DEFAULT_REGION"] = "ap-south-1"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials.AccessKeyID, should.Equal, "access")
+	this.So(credentials.SecretAccessKey, should.Equal, "secret")
+	this.So(credentials.Region, should.Equal, "ap-south-1")
+}
+
+func (this *AWSCredentialParserFixture) TestRegionDefaultsWhenUnspecified() {
+	this.environment["AWS_ACCESS_KEY_ID"] = "access"
+	this.environment["AWS_SECRET_ACCESS_KEY"] = "secret"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials.Region, should.Equal, "us-east-1")
+}
+
+func (this *AWSCredentialParserFixture) TestMissingCredentials() {
+	this.environment["AWS_ACCESS_KEY_ID"] = "access"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.NotBeNil)
+	this.So(credentials, should.BeZeroValue)
+}
+
+const (
+	awsCredentialsFile = `
+[default]
+aws_access_key_id = default-access
+aws_secret_access_key = default-secret
+
+# MinIO running locally
+[minio]
+aws_access_key_id=minio-access
+aws_secret_access_key=minio-secret
+aws_session_token=minio-token
+`
+	awsConfigFile = `
+[default]
+region = eu-west-1
+
+[profile minio]
+region = local
+endpoint_url = http://localhost:9000
+`
+)
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+type AzureCredentialParser struct {
+	environment contracts.Environment
+}
+
+func NewAzureCredentialParser(environment contracts.Environment) AzureCredentialParser {
+	return AzureCredentialParser{environment: environment}
+}
+
+func (this AzureCredentialParser) Parse() (credentials contracts.AzureCredentials, err error) {
+	connection := parseAzureConnectionString(this.lookup("AZURE_STORAGE_CONNECTION_STRING"))
+
+	credentials.AccountName = this.lookupOrDefault("AZURE_STORAGE_ACCOUNT", connection["AccountName"])
+	credentials.AccountKey = this.lookupOrDefault("AZURE_STORAGE_KEY", connection["AccountKey"])
+	credentials.SASToken = strings.TrimPrefix(this.lookupOrDefault("AZURE_STORAGE_SAS_TOKEN", connection["SharedAccessSignature"]), "?")
+	credentials.Endpoint = strings.TrimSuffix(this.lookupOrDefault("AZURE_STORAGE_BLOB_ENDPOINT", connection["BlobEndpoint"]), "/")
+
+	if credentials.AccountKey == "" && credentials.SASToken == "" {
+		return contracts.AzureCredentials{}, errors.New("the AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN (or AZURE_STORAGE_CONNECTION_STRING) is required")
+	}
+	return credentials, nil
+}
+
+func (this AzureCredentialParser) lookup(key string) string {
+	value, _ := this.environment.LookupEnv(key)
+	return strings.TrimSpace(value)
+}
+
+func (this AzureCredentialParser) lookupOrDefault(key, fallback string) string {
+	if value := this.lookup(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func parseAzureConnectionString(value string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(value, ";") {
+		if index := strings.Index(pair, "="); index > 0 {
+			values[strings.TrimSpace(pair[:index])] = strings.TrimSpace(pair[index+1:])
+		}
+	}
+	return values
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestAzureCredentialParserFixture(t *testing.T) {
+	gunit.Run(new(AzureCredentialParserFixture), t)
+}
+
+type AzureCredentialParserFixture struct {
+	*gunit.Fixture
+
+	parser      AzureCredentialParser
+	environment FakeEnvironment
+}
+
+func (this *AzureCredentialParserFixture) Setup() {
+	this.environment = make(FakeEnvironment)
+	this.parser = NewAzureCredentialParser(this.environment)
+}
+
+func (this *AzureCredentialParserFixture) TestSharedKeyFromEnvironment() {
+	this.environment["AZURE_STORAGE_ACCOUNT"] = "account"
+	this.environment["AZURE_STORAGE_KEY"] = "a2V5"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, contracts.AzureCredentials{AccountName: "account", AccountKey: "a2V5"})
+}
+
+func (this *AzureCredentialParserFixture) TestSASTokenFromEnvironment() {
+	this.environment["AZURE_STORAGE_SAS_TOKEN"] = "?sv=2020-04-08&sig=abc"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, contracts.AzureCredentials{SASToken: "sv=2020-04-08&sig=abc"})
+}
+
+func (this *AzureCredentialParserFixture) TestConnectionString() {
+	this.environment["AZURE_STORAGE_CONNECTION_STRING"] = "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;" +
+		"AccountKey=a2V5==;BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1/;"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, contracts.AzureCredentials{
+		AccountName: "devstoreaccount1",
+		AccountKey:  "a2V5==",
+		Endpoint:    "http://127.0.0.1:10000/devstoreaccount1",
+	})
+}
+
+func (this *AzureCredentialParserFixture) TestEnvironmentTakesPrecedenceOverConnectionString() {
+	this.environment["AZURE_STORAGE_CONNECTION_STRING"] = "AccountName=connection;AccountKey=connection-key"
+	this.environment["AZURE_STORAGE_KEY"] = "environment-key"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials.AccountName, should.Equal, "connection")
+	this.So(credentials.AccountKey, should.Equal, "environment-key")
+}
+
+func (this *AzureCredentialParserFixture) TestMissingCredentials() {
+	this.environment["AZURE_STORAGE_ACCOUNT"] = "account"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.NotBeNil)
+	this.So(credentials, should.BeZeroValue)
+}
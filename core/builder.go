@@ -21,14 +21,18 @@ type PackageBuilder struct {
 	storage  PackageBuilderFileSystem
 	archive  contracts.ArchiveWriter
 	hasher   hash.Hash
+	digest   hash.Hash
 	contents []contracts.ArchiveItem
 }
 
-func NewPackageBuilder(storage PackageBuilderFileSystem, archive contracts.ArchiveWriter, hasher hash.Hash) *PackageBuilder {
+// NewPackageBuilder calculates the MD5 checksum of each item with the hasher and, unless it is nil,
+// the checksum of the manifest's (stronger) hash algorithm with the digest.
+func NewPackageBuilder(storage PackageBuilderFileSystem, archive contracts.ArchiveWriter, hasher, digest hash.Hash) *PackageBuilder {
 	return &PackageBuilder{
 		storage: storage,
 		archive: archive,
 		hasher:  hasher,
+		digest:  digest,
 	}
 }
 
@@ -59,10 +63,10 @@ func (this *PackageBuilder) add(file contracts.FileInfo) error {
 
 func (this *PackageBuilder) archiveContents(file contracts.FileInfo, symlinkSourcePath string) error {
 	if symlinkSourcePath != "" {
-		_, _ = io.WriteString(this.hasher, symlinkSourcePath)
+		_, _ = io.WriteString(this.hashers(), symlinkSourcePath)
 		return nil
 	}
-	writer := io.MultiWriter(this.hasher, this.archive)
+	writer := io.MultiWriter(this.hashers(), this.archive)
 	reader := this.storage.Open(file.Path())
 	defer closeResource(reader)
 	_, err := io.Copy(writer, reader)
@@ -70,6 +74,13 @@ func (this *PackageBuilder) archiveContents(file contracts.FileInfo, symlinkSour
 	return err
 }
 
+func (this *PackageBuilder) hashers() io.Writer {
+	if this.digest == nil {
+		return this.hasher
+	}
+	return io.MultiWriter(this.hasher, this.digest)
+}
+
 func (this *PackageBuilder) buildHeader(file contracts.FileInfo) (header contracts.ArchiveHeader, err error) {
 	header.Name = strings.TrimPrefix(file.Path(), this.storage.RootPath()+"/")
 	header.Size = file.Size()
@@ -106,11 +117,16 @@ func (this *PackageBuilder) symlinkOutOfBoundError(file contracts.FileInfo) erro
 
 func (this *PackageBuilder) buildManifestEntry(file contracts.FileInfo, symlinkSourcePath string) contracts.ArchiveItem {
 	defer this.hasher.Reset()
-	return contracts.ArchiveItem{
+	item := contracts.ArchiveItem{
 		Path:        strings.TrimPrefix(file.Path(), this.storage.RootPath()+"/"),
 		Size:        this.determineFileSize(file, symlinkSourcePath),
 		MD5Checksum: this.hasher.Sum(nil),
 	}
+	if this.digest != nil {
+		item.Checksum = this.digest.Sum(nil)
+		this.digest.Reset()
+	}
+	return item
 }
 
 func (this *PackageBuilder) determineFileSize(file contracts.FileInfo, symlinkSourcePath string) int64 {
@@ -25,7 +25,7 @@ func (this *PackageBuilderFixture) Setup() {
 	this.fileSystem = newInMemoryFileSystem()
 	this.archive = NewFakeArchiveWriter()
 	this.hasher = NewFakeHasher()
-	this.builder = NewPackageBuilder(this.fileSystem, this.archive, this.hasher)
+	this.builder = NewPackageBuilder(this.fileSystem, this.archive, this.hasher, nil)
 	this.fileSystem.WriteFile("/in/file0.txt", []byte("a"))
 	_ = this.fileSystem.Chmod("/in/file0.txt", 0755)
 	this.fileSystem.WriteFile("/in/file1.txt", []byte("bb"))
@@ -46,6 +46,19 @@ func (this *PackageBuilderFixture) TestContentsAreInventoried() {
 	})
 }
 
+func (this *PackageBuilderFixture) TestContentsDigested() {
+	digest := NewFakeHasher()
+	this.builder = NewPackageBuilder(this.fileSystem, this.archive, this.hasher, digest)
+
+	err := this.builder.Build()
+
+	this.So(err, should.BeNil)
+	this.So(this.builder.Contents()[1], should.Resemble,
+		contracts.ArchiveItem{Path: "file1.txt", Size: 2, MD5Checksum: []byte("bb [HASHED]"), Checksum: []byte("bb [HASHED]")})
+	this.So(this.builder.Contents()[2].Checksum, should.Resemble, []byte("../file0.txt [HASHED]"))
+	this.So(digest.sum, should.BeNil)
+}
+
 func (this *PackageBuilderFixture) TestContentsAreArchived() {
 	err := this.builder.Build()
 
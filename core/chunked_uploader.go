@@ -0,0 +1,195 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// ChunkedUploader sends objects larger than a single chunk through an upload session of the remote
+// storage, one chunk at a time. A chunk which fails to upload is retried from the last byte
+// committed by the remote storage rather than restarting the upload from the beginning. Smaller
+// objects, and objects on remote storage without upload sessions, are uploaded in a single request.
+type ChunkedUploader struct {
+	inner     contracts.RemoteStorage
+	chunkSize int64
+	maxRetry  int
+	sleep     func(duration time.Duration)
+}
+
+func NewChunkedUploader(inner contracts.RemoteStorage, chunkSize int64, maxRetry int, sleep func(duration time.Duration)) *ChunkedUploader {
+	return &ChunkedUploader{inner: inner, chunkSize: chunkSize, maxRetry: maxRetry, sleep: sleep}
+}
+
+func (this *ChunkedUploader) Download(request url.URL) (io.ReadCloser, error) {
+	return this.inner.Download(request)
+}
+
+func (this *ChunkedUploader) Upload(request contracts.UploadRequest) error {
+	sessions, ok := this.inner.(contracts.SessionUploader)
+	if !ok || this.chunkSize <= 0 || request.Size <= this.chunkSize {
+		return this.inner.Upload(request)
+	}
+	session, err := sessions.BeginUpload(request)
+	if errors.Is(err, contracts.SessionUnsupportedErr) {
+		return this.inner.Upload(request)
+	}
+	if err != nil {
+		return err
+	}
+	err = this.uploadChunks(session, request)
+	if err != nil {
+		_ = session.Abort()
+		return err
+	}
+	return nil
+}
+
+// BeginStream starts an upload session for an object whose size and checksum are not known until
+// all of its content has been written (such as an archive compressed on the fly). Unlike Upload,
+// there is no fallback to a single request when the remote storage lacks upload sessions.
+func (this *ChunkedUploader) BeginStream(request contracts.UploadRequest) (*UploadStream, error) {
+	sessions, ok := this.inner.(contracts.SessionUploader)
+	if !ok {
+		return nil, fmt.Errorf("%w (%s)", contracts.SessionUnsupportedErr, request.RemoteAddress.String())
+	}
+	if this.chunkSize <= 0 {
+		return nil, fmt.Errorf("a positive chunk size is required to stream %s", request.RemoteAddress.String())
+	}
+	session, err := sessions.BeginUpload(request)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadStream{uploader: this, session: session, request: request}, nil
+}
+
+func (this *ChunkedUploader) uploadChunks(session contracts.UploadSession, request contracts.UploadRequest) error {
+	current, err := this.readChunk(request.Body)
+	if err != nil {
+		return err
+	}
+	for offset := int64(0); ; {
+		next, err := this.readChunk(request.Body)
+		if err != nil {
+			return err
+		}
+		final := len(next) == 0
+		err = this.uploadChunk(session, current, offset, final)
+		if err != nil {
+			return err
+		}
+		offset += int64(len(current))
+		this.logProgress(request, offset)
+		if final {
+			break
+		}
+		current = next
+	}
+	return session.Complete(request.Checksum)
+}
+
+func (this *ChunkedUploader) readChunk(body io.Reader) ([]byte, error) {
+	chunk := make([]byte, this.chunkSize)
+	count, err := io.ReadFull(body, chunk)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return chunk[:count], err
+}
+
+func (this *ChunkedUploader) uploadChunk(session contracts.UploadSession, chunk []byte, offset int64, final bool) (err error) {
+	for x := 0; x <= this.maxRetry; x++ {
+		if x > 0 {
+			log.Printf("[WARN] upload of chunk at offset %d failed (%s), resuming from the last byte committed.", offset, err)
+			this.sleep(time.Second * 3)
+			chunk, offset, err = this.resume(session, chunk, offset)
+			if err != nil {
+				if errors.Is(err, contracts.RetryErr) {
+					continue
+				}
+				return err
+			}
+		}
+		err = session.UploadPart(chunk, offset, final)
+		if err == nil || !errors.Is(err, contracts.RetryErr) {
+			return err
+		}
+	}
+	return err
+}
+
+// resume skips whatever part of the chunk the remote storage has already committed.
+func (this *ChunkedUploader) resume(session contracts.UploadSession, chunk []byte, offset int64) ([]byte, int64, error) {
+	committed, err := session.Committed()
+	if err != nil {
+		return chunk, offset, err
+	}
+	if committed < offset {
+		return chunk, offset, fmt.Errorf("upload session lost committed bytes: [%d] committed, [%d] expected", committed, offset)
+	}
+	skipped := minimum64(committed-offset, int64(len(chunk)))
+	return chunk[skipped:], offset + skipped, nil
+}
+
+func (this *ChunkedUploader) logProgress(request contracts.UploadRequest, uploaded int64) {
+	if request.Size > 0 {
+		log.Printf("Uploaded %d of %d bytes (%d%%) to %s", uploaded, request.Size, uploaded*100/request.Size, request.RemoteAddress.String())
+	} else {
+		log.Printf("Uploaded %d bytes to %s", uploaded, request.RemoteAddress.String())
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// UploadStream uploads each chunk written to it as soon as the next byte arrives (at which point
+// the chunk is known not to be the final chunk). The final chunk is held back until Complete so
+// that the object isn't finalized by the remote storage before its checksum is known.
+type UploadStream struct {
+	uploader *ChunkedUploader
+	session  contracts.UploadSession
+	request  contracts.UploadRequest
+	pending  []byte
+	offset   int64
+}
+
+func (this *UploadStream) Write(buffer []byte) (int, error) {
+	this.pending = append(this.pending, buffer...)
+	for int64(len(this.pending)) > this.uploader.chunkSize {
+		chunk := this.pending[:this.uploader.chunkSize]
+		err := this.uploader.uploadChunk(this.session, chunk, this.offset, false)
+		if err != nil {
+			return 0, err
+		}
+		this.offset += int64(len(chunk))
+		this.uploader.logProgress(this.request, this.offset)
+		this.pending = append(this.pending[:0], this.pending[len(chunk):]...)
+	}
+	return len(buffer), nil
+}
+
+// Size is the number of bytes written thus far.
+func (this *UploadStream) Size() int64 {
+	return this.offset + int64(len(this.pending))
+}
+
+// Complete uploads the final chunk and verifies the checksum of the object.
+func (this *UploadStream) Complete(checksum []byte) error {
+	err := this.uploader.uploadChunk(this.session, this.pending, this.offset, true)
+	if err != nil {
+		return err
+	}
+	this.offset, this.pending = this.Size(), nil
+	this.request.Size = this.offset
+	this.uploader.logProgress(this.request, this.offset)
+	return this.session.Complete(checksum)
+}
+
+// Abort discards whatever has been uploaded.
+func (this *UploadStream) Abort() error {
+	return this.session.Abort()
+}
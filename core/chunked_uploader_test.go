@@ -0,0 +1,304 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestChunkedUploaderFixture(t *testing.T) {
+	gunit.Run(new(ChunkedUploaderFixture), t)
+}
+
+type ChunkedUploaderFixture struct {
+	*gunit.Fixture
+
+	inner    *FakeSessionClient
+	uploader *ChunkedUploader
+	naps     []time.Duration
+	content  string
+	request  contracts.UploadRequest
+}
+
+func (this *ChunkedUploaderFixture) Setup() {
+	this.inner = &FakeSessionClient{FakeClient: &FakeClient{}, session: &FakeUploadSession{}}
+	this.uploader = NewChunkedUploader(this.inner, 4, 2, func(duration time.Duration) {
+		this.naps = append(this.naps, duration)
+	})
+	this.content = "0123456789"
+	this.request = contracts.UploadRequest{
+		RemoteAddress: url.URL{Scheme: "gcs", Host: "bucket", Path: "/archive"},
+		Body:          strings.NewReader(this.content),
+		Size:          int64(len(this.content)),
+		Checksum:      []byte("checksum"),
+	}
+}
+
+func (this *ChunkedUploaderFixture) TestSmallObjectUploadedInSingleRequest() {
+	this.request.Body = strings.NewReader("0123")
+	this.request.Size = 4
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.BeNil)
+	this.So(this.inner.uploadAttempts, should.Equal, 1)
+	this.So(this.inner.begun, should.Equal, 0)
+}
+
+func (this *ChunkedUploaderFixture) TestSingleRequestWithoutChunkSize() {
+	this.uploader = NewChunkedUploader(this.inner, 0, 2, nil)
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.BeNil)
+	this.So(this.inner.uploadAttempts, should.Equal, 1)
+	this.So(this.inner.begun, should.Equal, 0)
+}
+
+func (this *ChunkedUploaderFixture) TestSingleRequestWithoutSessionSupport() {
+	this.uploader = NewChunkedUploader(this.inner.FakeClient, 4, 2, nil)
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.BeNil)
+	this.So(this.inner.uploadAttempts, should.Equal, 1)
+}
+
+func (this *ChunkedUploaderFixture) TestSingleRequestWhenSessionsUnsupportedForAddress() {
+	this.inner.beginErr = fmt.Errorf("%w (file)", contracts.SessionUnsupportedErr)
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.BeNil)
+	this.So(this.inner.begun, should.Equal, 1)
+	this.So(this.inner.uploadAttempts, should.Equal, 1)
+}
+
+func (this *ChunkedUploaderFixture) TestBeginUploadFailure() {
+	this.inner.beginErr = aRegularError
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.Equal, aRegularError)
+	this.So(this.inner.uploadAttempts, should.Equal, 0)
+}
+
+func (this *ChunkedUploaderFixture) TestUploadedInChunks() {
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.BeNil)
+	this.So(this.inner.uploadAttempts, should.Equal, 0)
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123", "4:4567", "8:89 (final)"})
+	this.So(this.inner.session.uploaded.String(), should.Equal, this.content)
+	this.So(string(this.inner.session.checksum), should.Equal, "checksum")
+	this.So(this.inner.session.aborted, should.BeFalse)
+}
+
+func (this *ChunkedUploaderFixture) TestFinalChunkFull() {
+	this.request.Body = strings.NewReader("01234567")
+	this.request.Size = 8
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.BeNil)
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123", "4:4567 (final)"})
+}
+
+func (this *ChunkedUploaderFixture) TestFailedChunkResumedFromLastByteCommitted() {
+	this.inner.session.interruptions = []int64{6}
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.BeNil)
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123", "4:4567", "6:67", "8:89 (final)"})
+	this.So(this.inner.session.uploaded.String(), should.Equal, this.content)
+	this.So(this.naps, should.Resemble, []time.Duration{time.Second * 3})
+}
+
+func (this *ChunkedUploaderFixture) TestFailedChunkRetriedAtMostMaxRetryTimes() {
+	this.inner.session.interruptions = []int64{5, 5, 5}
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.Equal, aRetryError)
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123", "4:4567", "5:567", "5:567"})
+	this.So(this.inner.session.aborted, should.BeTrue)
+	this.So(this.inner.session.checksum, should.BeNil)
+}
+
+func (this *ChunkedUploaderFixture) TestRegularErrorAbortsSession() {
+	this.inner.session.partErr = aRegularError
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.Equal, aRegularError)
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123"})
+	this.So(this.inner.session.aborted, should.BeTrue)
+	this.So(this.naps, should.BeEmpty)
+}
+
+func (this *ChunkedUploaderFixture) TestLostCommittedBytesAbortsSession() {
+	this.inner.session.interruptions = []int64{6}
+	this.inner.session.lost = true
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.NotBeNil)
+	this.So(this.inner.session.aborted, should.BeTrue)
+}
+
+func (this *ChunkedUploaderFixture) TestFailedChecksumVerificationAbortsSession() {
+	this.inner.session.completeErr = aRegularError
+
+	err := this.uploader.Upload(this.request)
+
+	this.So(err, should.Equal, aRegularError)
+	this.So(this.inner.session.aborted, should.BeTrue)
+}
+
+func (this *ChunkedUploaderFixture) TestStreamedInChunks() {
+	stream, err := this.uploader.BeginStream(contracts.UploadRequest{RemoteAddress: this.request.RemoteAddress})
+	this.So(err, should.BeNil)
+
+	_, _ = stream.Write([]byte("0123"))
+	this.So(this.inner.session.parts, should.BeEmpty)
+	_, _ = stream.Write([]byte("456"))
+	_, _ = stream.Write([]byte("789"))
+	this.So(stream.Size(), should.Equal, 10)
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123", "4:4567"})
+	this.So(this.inner.session.checksum, should.BeNil)
+
+	err = stream.Complete([]byte("checksum"))
+
+	this.So(err, should.BeNil)
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123", "4:4567", "8:89 (final)"})
+	this.So(this.inner.session.uploaded.String(), should.Equal, this.content)
+	this.So(string(this.inner.session.checksum), should.Equal, "checksum")
+}
+
+func (this *ChunkedUploaderFixture) TestStreamHoldsBackFinalChunk() {
+	stream, _ := this.uploader.BeginStream(contracts.UploadRequest{RemoteAddress: this.request.RemoteAddress})
+
+	_, _ = stream.Write([]byte("01234567"))
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123"})
+
+	err := stream.Complete([]byte("checksum"))
+
+	this.So(err, should.BeNil)
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123", "4:4567 (final)"})
+}
+
+func (this *ChunkedUploaderFixture) TestStreamedChunkResumedFromLastByteCommitted() {
+	this.inner.session.interruptions = []int64{2}
+	stream, _ := this.uploader.BeginStream(contracts.UploadRequest{RemoteAddress: this.request.RemoteAddress})
+
+	count, err := stream.Write([]byte(this.content))
+
+	this.So(err, should.BeNil)
+	this.So(count, should.Equal, 10)
+	this.So(this.inner.session.parts, should.Resemble, []string{"0:0123", "2:23", "4:4567"})
+}
+
+func (this *ChunkedUploaderFixture) TestStreamedChunkFailure() {
+	this.inner.session.partErr = aRegularError
+	stream, _ := this.uploader.BeginStream(contracts.UploadRequest{RemoteAddress: this.request.RemoteAddress})
+
+	count, err := stream.Write([]byte(this.content))
+
+	this.So(err, should.Equal, aRegularError)
+	this.So(count, should.Equal, 0)
+	this.So(stream.Abort(), should.BeNil)
+	this.So(this.inner.session.aborted, should.BeTrue)
+}
+
+func (this *ChunkedUploaderFixture) TestStreamRequiresSessionSupport() {
+	this.uploader = NewChunkedUploader(this.inner.FakeClient, 4, 2, nil)
+
+	stream, err := this.uploader.BeginStream(this.request)
+
+	this.So(stream, should.BeNil)
+	this.So(errors.Is(err, contracts.SessionUnsupportedErr), should.BeTrue)
+}
+
+func (this *ChunkedUploaderFixture) TestStreamRequiresChunkSize() {
+	this.uploader = NewChunkedUploader(this.inner, 0, 2, nil)
+
+	stream, err := this.uploader.BeginStream(this.request)
+
+	this.So(stream, should.BeNil)
+	this.So(err, should.NotBeNil)
+	this.So(this.inner.begun, should.Equal, 0)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+type FakeSessionClient struct {
+	*FakeClient
+
+	session  *FakeUploadSession
+	begun    int
+	beginErr error
+}
+
+func (this *FakeSessionClient) BeginUpload(request contracts.UploadRequest) (contracts.UploadSession, error) {
+	this.begun++
+	if this.beginErr != nil {
+		return nil, this.beginErr
+	}
+	return this.session, nil
+}
+
+// FakeUploadSession commits each part up to (but not including) the next of its interruptions.
+type FakeUploadSession struct {
+	parts         []string
+	uploaded      bytes.Buffer
+	interruptions []int64
+	lost          bool
+	partErr       error
+	completeErr   error
+	checksum      []byte
+	aborted       bool
+}
+
+func (this *FakeUploadSession) Committed() (int64, error) {
+	if this.lost {
+		return 0, nil
+	}
+	return int64(this.uploaded.Len()), nil
+}
+
+func (this *FakeUploadSession) UploadPart(part []byte, offset int64, final bool) error {
+	description := fmt.Sprintf("%d:%s", offset, part)
+	if final {
+		description += " (final)"
+	}
+	this.parts = append(this.parts, description)
+	if this.partErr != nil {
+		return this.partErr
+	}
+	if len(this.interruptions) > 0 && this.interruptions[0] < offset+int64(len(part)) {
+		this.uploaded.Write(part[:this.interruptions[0]-offset])
+		this.interruptions = this.interruptions[1:]
+		return aRetryError
+	}
+	this.uploaded.Write(part)
+	return nil
+}
+
+func (this *FakeUploadSession) Complete(checksum []byte) error {
+	this.checksum = checksum
+	return this.completeErr
+}
+
+func (this *FakeUploadSession) Abort() error {
+	this.aborted = true
+	return nil
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/smartystreets/satisfy/contracts"
+	"github.com/ulikunitz/xz"
+)
+
+// Codec compresses an archive when a package is uploaded and decompresses it when the package
+// is installed. Codecs are registered under the compression algorithm recorded in the manifest.
+// A compressor which is also a contracts.ArchiveWriter (like zip) replaces the tar format
+// entirely, in which case its decompressor must provide an ArchiveReader.
+type Codec struct {
+	ContentType string
+	Compress    func(writer io.Writer, options CodecOptions) (io.WriteCloser, error)
+	Decompress  func(reader io.Reader, options CodecOptions) (io.ReadCloser, error)
+}
+
+// CodecOptions are honored by the codecs that support them (currently only zstd, beyond the
+// compression level) and ignored by the others. The dictionary, when present, must be supplied
+// both when compressing and when decompressing. The spool is required to decompress zip archives.
+type CodecOptions struct {
+	Level       int
+	WindowSize  int
+	Concurrency int
+	Dictionary  []byte
+	Spool       func() (ArchiveSpool, error)
+}
+
+// zstdLongWindowSize matches the window of 'zstd --long' (2^27 bytes).
+const zstdLongWindowSize = 1 << 27
+
+func NewCodecOptions(config contracts.PackageConfig, dictionary []byte) CodecOptions {
+	options := CodecOptions{
+		Level:       config.CompressionLevel,
+		WindowSize:  config.Zstd.WindowSize,
+		Concurrency: config.Zstd.Concurrency,
+		Dictionary:  dictionary,
+	}
+	if config.Zstd.Long && options.WindowSize < zstdLongWindowSize {
+		options.WindowSize = zstdLongWindowSize
+	}
+	return options
+}
+
+// ZstdDictionaryID reads the ID from the header of a dictionary trained with 'zstd --train'.
+func ZstdDictionaryID(dictionary []byte) (uint32, error) {
+	if len(dictionary) < 8 || binary.LittleEndian.Uint32(dictionary) != zstdDictionaryMagic {
+		return 0, errors.New("not a zstd dictionary (expected one trained with 'zstd --train')")
+	}
+	return binary.LittleEndian.Uint32(dictionary[4:]), nil
+}
+
+const zstdDictionaryMagic = 0xEC30A437
+
+var codecs = map[string]Codec{
+	"zstd":   {ContentType: "application/zstd", Compress: newZStdWriter, Decompress: newZStdReader},
+	"gzip":   {ContentType: "application/gzip", Compress: newGZipWriter, Decompress: newGZipReader},
+	"zip":    {ContentType: "application/zip", Compress: newZipWriter, Decompress: newZipReader},
+	"xz":     {ContentType: "application/x-xz", Compress: newXZWriter, Decompress: newXZReader},
+	"lz4":    {ContentType: "application/x-lz4", Compress: newLZ4Writer, Decompress: newLZ4Reader},
+	"brotli": {ContentType: "application/x-brotli", Compress: newBrotliWriter, Decompress: newBrotliReader},
+	"none":   {ContentType: "application/x-tar", Compress: newUncompressedWriter, Decompress: newUncompressedReader},
+}
+
+// RegisterCodec makes an additional compression algorithm available to both upload and install.
+func RegisterCodec(algorithm string, codec Codec) {
+	codecs[algorithm] = codec
+}
+
+func LookupCodec(algorithm string) (Codec, bool) {
+	codec, found := codecs[algorithm]
+	return codec, found
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func newZStdWriter(writer io.Writer, options CodecOptions) (io.WriteCloser, error) {
+	encoderOptions := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(options.Level))}
+	if options.WindowSize > 0 {
+		encoderOptions = append(encoderOptions, zstd.WithWindowSize(options.WindowSize))
+	}
+	if options.Concurrency > 0 {
+		encoderOptions = append(encoderOptions, zstd.WithEncoderConcurrency(options.Concurrency))
+	}
+	if len(options.Dictionary) > 0 {
+		encoderOptions = append(encoderOptions, zstd.WithEncoderDict(options.Dictionary))
+	}
+	return zstd.NewWriter(writer, encoderOptions...)
+}
+func newZStdReader(source io.Reader, options CodecOptions) (io.ReadCloser, error) {
+	var decoderOptions []zstd.DOption
+	if len(options.Dictionary) > 0 {
+		decoderOptions = append(decoderOptions, zstd.WithDecoderDicts(options.Dictionary))
+	}
+	if reader, err := zstd.NewReader(source, decoderOptions...); err != nil {
+		return nil, err
+	} else {
+		return reader.IOReadCloser(), nil
+	}
+}
+
+func newGZipWriter(writer io.Writer, options CodecOptions) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(writer, options.Level)
+}
+func newGZipReader(source io.Reader, _ CodecOptions) (io.ReadCloser, error) {
+	return gzip.NewReader(source)
+}
+
+func newZipWriter(writer io.Writer, options CodecOptions) (io.WriteCloser, error) {
+	return NewZipArchiveWriter(writer, options.Level), nil
+}
+
+// xzDictionaryCapacities mirrors the dictionary sizes of the xz command line presets (-0 through -9).
+var xzDictionaryCapacities = []int{256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20, 8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20}
+
+func newXZWriter(writer io.Writer, options CodecOptions) (io.WriteCloser, error) {
+	level := options.Level
+	if level < 0 || level >= len(xzDictionaryCapacities) {
+		level = 6
+	}
+	return xz.WriterConfig{DictCap: xzDictionaryCapacities[level]}.NewWriter(writer)
+}
+func newXZReader(source io.Reader, _ CodecOptions) (io.ReadCloser, error) {
+	reader, err := xz.NewReader(source)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(reader), nil
+}
+
+func newLZ4Writer(writer io.Writer, options CodecOptions) (io.WriteCloser, error) {
+	compressor := lz4.NewWriter(writer)
+	compressionLevel := lz4.Fast
+	if options.Level > 0 {
+		compressionLevel = lz4.Level1 << uint(minimum(options.Level, 9)-1)
+	}
+	return compressor, compressor.Apply(lz4.CompressionLevelOption(compressionLevel))
+}
+func newLZ4Reader(source io.Reader, _ CodecOptions) (io.ReadCloser, error) {
+	return ioutil.NopCloser(lz4.NewReader(source)), nil
+}
+
+func newBrotliWriter(writer io.Writer, options CodecOptions) (io.WriteCloser, error) {
+	return brotli.NewWriterLevel(writer, options.Level), nil
+}
+func newBrotliReader(source io.Reader, _ CodecOptions) (io.ReadCloser, error) {
+	return ioutil.NopCloser(brotli.NewReader(source)), nil
+}
+
+func newUncompressedWriter(writer io.Writer, _ CodecOptions) (io.WriteCloser, error) {
+	return nopWriteCloser{Writer: writer}, nil
+}
+func newUncompressedReader(source io.Reader, _ CodecOptions) (io.ReadCloser, error) {
+	return ioutil.NopCloser(source), nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func minimum(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestCodecFixture(t *testing.T) {
+	gunit.Run(new(CodecFixture), t)
+}
+
+type CodecFixture struct {
+	*gunit.Fixture
+}
+
+func (this *CodecFixture) TestCodecOptionsFromPackageConfig() {
+	options := NewCodecOptions(contracts.PackageConfig{
+		CompressionLevel: 3,
+		Zstd:             contracts.ZstdOptions{WindowSize: 1 << 20, Concurrency: 2},
+	}, []byte("dictionary"))
+
+	this.So(options, should.Resemble, CodecOptions{
+		Level:       3,
+		WindowSize:  1 << 20,
+		Concurrency: 2,
+		Dictionary:  []byte("dictionary"),
+	})
+}
+
+func (this *CodecFixture) TestLongModeWidensWindow() {
+	options := NewCodecOptions(contracts.PackageConfig{
+		Zstd: contracts.ZstdOptions{WindowSize: 1 << 20, Long: true},
+	}, nil)
+
+	this.So(options.WindowSize, should.Equal, 1<<27)
+}
+
+func (this *CodecFixture) TestZstdRoundTripWithWindowAndConcurrency() {
+	codec, _ := LookupCodec("zstd")
+	options := CodecOptions{Level: 3, WindowSize: 1 << 20, Concurrency: 2}
+	buffer := new(bytes.Buffer)
+
+	compressor, err := codec.Compress(buffer, options)
+	this.So(err, should.BeNil)
+	_, _ = compressor.Write(bytes.Repeat([]byte("satisfy "), 1024))
+	this.So(compressor.Close(), should.BeNil)
+
+	decompressor, err := codec.Decompress(buffer, options)
+	this.So(err, should.BeNil)
+	raw, err := ioutil.ReadAll(decompressor)
+	this.So(err, should.BeNil)
+	this.So(raw, should.Resemble, bytes.Repeat([]byte("satisfy "), 1024))
+}
+
+func (this *CodecFixture) TestInvalidZstdWindowSize() {
+	codec, _ := LookupCodec("zstd")
+
+	_, err := codec.Compress(new(bytes.Buffer), CodecOptions{WindowSize: 1000})
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *CodecFixture) TestZstdDictionaryID() {
+	id, err := ZstdDictionaryID([]byte{0x37, 0xa4, 0x30, 0xec, 0x2a, 0x00, 0x00, 0x00, 0xff})
+
+	this.So(err, should.BeNil)
+	this.So(id, should.Equal, 42)
+}
+
+func (this *CodecFixture) TestZstdDictionaryWithoutMagicNumber() {
+	_, err := ZstdDictionaryID([]byte("raw content dictionary"))
+
+	this.So(err, should.NotBeNil)
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/smartystreets/satisfy/contracts"
 )
@@ -14,6 +15,12 @@ type DependencyResolverFileSystem interface {
 	contracts.FileChecker
 	contracts.FileReader
 	contracts.Deleter
+	contracts.TreeDeleter
+	contracts.Renamer
+	contracts.DirectoryReader
+	contracts.FileWriter
+	contracts.SymlinkCreator
+	contracts.Chmod
 }
 
 type DependencyResolver struct {
@@ -37,25 +44,60 @@ func NewDependencyResolver(
 	}
 }
 
-func (this *DependencyResolver) Resolve() error {
+// Resolve installs the dependency (unless it's already installed correctly) and returns the
+// manifest of the version to which it was resolved.
+func (this *DependencyResolver) Resolve() (contracts.Manifest, error) {
+	if this.dependency.SideBySide {
+		if pinned := this.sideBySide().PinnedVersion(this.dependency.PackageVersion); pinned != "" {
+			log.Printf("%s was rolled back to version %s, which is kept until the listed version changes",
+				this.dependency.Title(), pinned)
+			this.dependency.PackageVersion = pinned
+		}
+	}
+	if IsVersionConstraint(this.dependency.PackageVersion) {
+		err := this.resolveVersionConstraint()
+		if err != nil {
+			return contracts.Manifest{}, err
+		}
+	}
+
 	log.Printf("Installing dependency: %s", this.dependency.Title())
 
-	manifestPath := ComposeManifestPath(this.dependency.LocalDirectory, this.dependency.PackageName)
+	manifestPath := ComposeManifestPath(this.installedDirectory(), this.dependency.PackageName)
 	if !this.localManifestExists(manifestPath) {
-		return this.installPackage()
+		return this.installPackage(nil)
 	}
 
 	localManifest, err := this.loadLocalManifest(manifestPath)
 	if err != nil {
-		return err
+		return contracts.Manifest{}, err
 	}
 
 	if this.isInstalledCorrectly(localManifest) {
-		return nil
+		return localManifest, nil
 	}
 
-	this.uninstallPackage(localManifest)
-	return this.installPackage()
+	return this.installPackage(&localManifest)
+}
+
+// resolveVersionConstraint settles on the highest published version which satisfies the constraint
+// (according to the versions index of the package), which is then installed like any other version.
+func (this *DependencyResolver) resolveVersionConstraint() error {
+	constraint, err := ParseVersionConstraint(this.dependency.PackageVersion)
+	if err != nil {
+		return fmt.Errorf("%s: %w", this.dependency.Title(), err)
+	}
+	index, err := this.packageInstaller.DownloadVersionIndex(this.dependency.ComposeVersionIndexRemoteAddress())
+	if err != nil {
+		return fmt.Errorf("failed to download the versions index for %s: %w", this.dependency.Title(), err)
+	}
+	version, found := constraint.Highest(index.Versions)
+	if !found {
+		return fmt.Errorf("no published version satisfies %s", this.dependency.Title())
+	}
+	log.Printf("Resolved %s to version %s", this.dependency.Title(), version)
+	this.dependency.PackageVersion = version
+	return nil
 }
 
 func (this *DependencyResolver) loadLocalManifest(manifestPath string) (localManifest contracts.Manifest, err error) {
@@ -95,7 +137,7 @@ func (this *DependencyResolver) isInstalledCorrectly(localManifest contracts.Man
 		return false
 	}
 
-	verifyErr := this.integrityChecker.Verify(localManifest, this.dependency.LocalDirectory)
+	verifyErr := this.integrityChecker.Verify(localManifest, this.installedDirectory())
 	if verifyErr != nil {
 		log.Printf("%s in %s", verifyErr.Error(), this.dependency.Title())
 		return false
@@ -105,14 +147,25 @@ func (this *DependencyResolver) isInstalledCorrectly(localManifest contracts.Man
 	return true
 }
 
-func (this *DependencyResolver) installPackage() error {
+// installPackage extracts the package into a staging directory next to the local directory and,
+// only once the staged files pass the integrity checks, swaps them into place. Until then the
+// previously installed version (if any) is left untouched.
+func (this *DependencyResolver) installPackage(previous *contracts.Manifest) (contracts.Manifest, error) {
+	staging := ComposeStagingPath(this.dependency.LocalDirectory, this.dependency.PackageName)
+	this.fileSystem.DeleteAll(staging) // left behind by an interrupted installation
+	defer this.fileSystem.DeleteAll(staging)
+
 	log.Printf("Downloading manifest for %s", this.dependency.Title())
 	manifest, err := this.packageInstaller.InstallManifest(contracts.InstallationRequest{
 		RemoteAddress: this.dependency.ComposeRemoteManifestAddress(),
-		LocalPath:     this.dependency.LocalDirectory,
+		LocalPath:     staging,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to install manifest for %s: %w", this.dependency.Title(), err)
+		return contracts.Manifest{}, fmt.Errorf("failed to install manifest for %s: %w", this.dependency.Title(), err)
+	}
+	err = this.verifyIdentity(manifest)
+	if err != nil {
+		return contracts.Manifest{}, err
 	}
 	log.Printf("Downloading and extracting package contents for %s", this.dependency.Title())
 
@@ -120,28 +173,243 @@ func (this *DependencyResolver) installPackage() error {
 		this.dependency.PackageVersion = manifest.Version
 	}
 
-	// TODO:
-	//  Manifest archive should always be used during download/install instead of contracts.RemoteArchiveFilename.
-	//    The contracts.RemoteArchiveFilename is to be used during the creation of a manifest, but once the manifest
-	//    exists, we can change the archive filename in the contracts and all previously uploaded manifests using the
-	//    older name are still recognized and understood.
+	if this.dependency.SideBySide && this.isRetained(manifest) {
+		log.Printf("Activating retained version of %s", this.dependency.Title())
+		return manifest, this.sideBySide().Activate(manifest.Version)
+	}
 
-	err = this.packageInstaller.InstallPackage(manifest, contracts.InstallationRequest{
-		RemoteAddress: this.dependency.ComposeRemoteAddress(contracts.RemoteArchiveFilename),
-		LocalPath:     this.dependency.LocalDirectory,
-	})
+	request := contracts.InstallationRequest{
+		RemoteAddress: this.dependency.ComposeArchiveAddress(manifest.Archive),
+		LocalPath:     staging,
+	}
+	if manifest.Archive.Dictionary != nil {
+		request.DictionaryAddress = this.dependency.ComposeDictionaryAddress(*manifest.Archive.Dictionary)
+	}
+	err = this.packageInstaller.InstallPackage(manifest, request)
+	if err != nil {
+		return contracts.Manifest{}, fmt.Errorf("failed to install package contents for %s: %w", this.dependency.Title(), err)
+	}
+
+	err = this.integrityChecker.Verify(manifest, staging)
 	if err != nil {
-		return fmt.Errorf("failed to install package contents for %s: %w", this.dependency.Title(), err)
+		return contracts.Manifest{}, fmt.Errorf("failed to verify staged package contents for %s: %w", this.dependency.Title(), err)
+	}
+
+	if this.dependency.SideBySide {
+		err = this.activate(staging, manifest)
+	} else {
+		err = this.swap(staging, manifest, previous)
+	}
+	if err != nil {
+		return contracts.Manifest{}, fmt.Errorf("failed to move staged package contents into place for %s: %w", this.dependency.Title(), err)
 	}
 
 	log.Printf("Dependency installed: %s", this.dependency.Title())
+	return manifest, nil
+}
+
+// verifyIdentity refuses a manifest (however validly signed) published for some other package or
+// version, such as a signed manifest of an older version copied over that of the dependency.
+func (this *DependencyResolver) verifyIdentity(manifest contracts.Manifest) error {
+	if manifest.Name != this.dependency.PackageName {
+		return fmt.Errorf("manifest for %s names another package: [%s]", this.dependency.Title(), manifest.Name)
+	}
+	if this.dependency.PackageVersion != "latest" && manifest.Version != this.dependency.PackageVersion {
+		return fmt.Errorf("manifest for %s names another version: [%s]", this.dependency.Title(), manifest.Version)
+	}
 	return nil
 }
 
-func (this *DependencyResolver) uninstallPackage(manifest contracts.Manifest) {
+func (this *DependencyResolver) sideBySide() *SideBySideInstallation {
+	return NewSideBySideInstallation(this.fileSystem, this.dependency.LocalDirectory, this.dependency.RetainedVersionCount())
+}
+
+// installedDirectory is where the installed package (and its manifest) can be found, which, for
+// side-by-side installations, is the directory of the version targeted by the 'current' symlink.
+func (this *DependencyResolver) installedDirectory() string {
+	if !this.dependency.SideBySide {
+		return this.dependency.LocalDirectory
+	}
+	installation := this.sideBySide()
+	if version := installation.ActiveVersion(); version != "" {
+		return installation.VersionPath(version)
+	}
+	return installation.CurrentPath()
+}
+
+func (this *DependencyResolver) isRetained(manifest contracts.Manifest) bool {
+	if validateSideBySideVersion(manifest.Version) != nil {
+		return false
+	}
+	directory := this.sideBySide().VersionPath(manifest.Version)
+	if !this.localManifestExists(ComposeManifestPath(directory, manifest.Name)) {
+		return false
+	}
+	return this.integrityChecker.Verify(manifest, directory) == nil
+}
+
+func (this *DependencyResolver) activate(staging string, manifest contracts.Manifest) error {
+	installation := this.sideBySide()
+	err := installation.Store(staging, manifest.Version)
+	if err != nil {
+		return err
+	}
+	return installation.Activate(manifest.Version)
+}
+
+// swap exchanges the entire local directory for the staging directory when the local directory
+// holds nothing but this package. A local directory shared with other packages (or files) can't be
+// exchanged wholesale, so each item is renamed into place individually, with the manifest last.
+func (this *DependencyResolver) swap(staging string, manifest contracts.Manifest, previous *contracts.Manifest) error {
+	if this.ownsLocalDirectory(previous) {
+		return this.swapDirectory(staging, manifest, previous)
+	}
+	log.Printf("[WARN] %s is shared with other files, swapping the contents of %s one item at a time",
+		this.dependency.LocalDirectory, this.dependency.Title())
+	return this.swapContents(staging, manifest, previous)
+}
+
+func (this *DependencyResolver) ownsLocalDirectory(previous *contracts.Manifest) bool {
+	if containsWorkingDirectory(this.dependency.LocalDirectory) {
+		return false // renaming the working directory would strand every relative path
+	}
+	if info, err := this.fileSystem.Stat(this.dependency.LocalDirectory); err == nil && info.Symlink() != "" {
+		return false // renaming a symlinked local directory would replace the symlink itself
+	}
+	names, err := this.fileSystem.ReadDirectory(this.dependency.LocalDirectory)
+	if os.IsNotExist(err) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	manifestName := filepath.Base(ComposeManifestPath("", this.dependency.PackageName))
+	owned := map[string]bool{manifestName: true, manifestName + contracts.SignatureExtension: true}
+	if previous != nil {
+		for _, item := range previous.Archive.Contents {
+			owned[strings.Split(filepath.ToSlash(filepath.Clean(item.Path)), "/")[0]] = true
+		}
+	}
+	for _, name := range names {
+		if !owned[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsWorkingDirectory(directory string) bool {
+	working, err := os.Getwd()
+	if err != nil {
+		return true
+	}
+	directory, err = filepath.Abs(directory)
+	if err != nil {
+		return true
+	}
+	relative, err := filepath.Rel(directory, working)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// swapDirectory renames the staging directory into place (with the permissions of the local directory
+// it replaces). Should the local directory not be renamed (as with a mount point or a parent directory
+// which isn't writable) the contents are swapped one item at a time instead.
+func (this *DependencyResolver) swapDirectory(staging string, manifest contracts.Manifest, previous *contracts.Manifest) error {
+	local := this.dependency.LocalDirectory
+	aside := staging + ".previous"
+	this.fileSystem.DeleteAll(aside)
+
+	if info, err := this.fileSystem.Stat(local); err == nil && info.Mode().IsDir() {
+		_ = this.fileSystem.Chmod(staging, info.Mode().Perm())
+	}
+	err := this.fileSystem.Rename(local, aside)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("[WARN] %s could not be renamed (%s), swapping the contents of %s one item at a time",
+			local, err, this.dependency.Title())
+		return this.swapContents(staging, manifest, previous)
+	}
+	err = this.fileSystem.Rename(staging, local)
+	if err != nil {
+		if restoreErr := this.fileSystem.Rename(aside, local); restoreErr != nil && !os.IsNotExist(restoreErr) {
+			return err
+		}
+		log.Printf("[WARN] %s could not be renamed into place (%s), swapping the contents of %s one item at a time",
+			staging, err, this.dependency.Title())
+		return this.swapContents(staging, manifest, previous)
+	}
+	this.fileSystem.DeleteAll(aside)
+	return nil
+}
+
+// swapContents moves each item it replaces aside before renaming the staged item into place, so
+// that, should a rename fail, the items already swapped can be put back the way they were.
+func (this *DependencyResolver) swapContents(staging string, manifest contracts.Manifest, previous *contracts.Manifest) error {
+	local := this.dependency.LocalDirectory
+	aside := staging + ".previous"
+	this.fileSystem.DeleteAll(aside)
+	defer this.fileSystem.DeleteAll(aside)
+
+	var swapped []swappedItem
+	for _, item := range this.stagedItems(staging, manifest) {
+		swap := swappedItem{path: item}
+		if _, err := this.fileSystem.Stat(filepath.Join(local, item)); err == nil {
+			if err = this.fileSystem.Rename(filepath.Join(local, item), filepath.Join(aside, item)); err != nil {
+				this.restoreContents(local, aside, swapped)
+				return err
+			}
+			swap.replaced = true
+		}
+		swapped = append(swapped, swap)
+		if err := this.fileSystem.Rename(filepath.Join(staging, item), filepath.Join(local, item)); err != nil {
+			this.restoreContents(local, aside, swapped)
+			return err
+		}
+	}
+
+	if previous != nil {
+		current := make(map[string]bool)
+		for _, item := range manifest.Archive.Contents {
+			current[item.Path] = true
+		}
+		for _, item := range previous.Archive.Contents {
+			if !current[item.Path] {
+				this.fileSystem.Delete(filepath.Join(local, item.Path))
+			}
+		}
+	}
+	return nil
+}
+
+// stagedItems lists the paths (relative to the staging directory) in the order in which they are
+// swapped into place: the contents, then the signature (if any) and, last of all, the manifest.
+func (this *DependencyResolver) stagedItems(staging string, manifest contracts.Manifest) (items []string) {
 	for _, item := range manifest.Archive.Contents {
-		this.fileSystem.Delete(filepath.Join(this.dependency.LocalDirectory, item.Path))
+		items = append(items, item.Path)
+	}
+	manifestName := filepath.Base(ComposeManifestPath("", manifest.Name))
+	if _, err := this.fileSystem.Stat(filepath.Join(staging, manifestName+contracts.SignatureExtension)); err == nil {
+		items = append(items, manifestName+contracts.SignatureExtension)
 	}
+	return append(items, manifestName)
+}
+
+// restoreContents reverses the swapped items (in reverse order), removing each item which was moved
+// into place and restoring the item it replaced (if any).
+func (this *DependencyResolver) restoreContents(local, aside string, swapped []swappedItem) {
+	for x := len(swapped) - 1; x >= 0; x-- {
+		item := swapped[x]
+		this.fileSystem.Delete(filepath.Join(local, item.path))
+		if item.replaced {
+			if err := this.fileSystem.Rename(filepath.Join(aside, item.path), filepath.Join(local, item.path)); err != nil {
+				log.Printf("[WARN] failed to restore %s: %s", filepath.Join(local, item.path), err)
+			}
+		}
+	}
+}
+
+type swappedItem struct {
+	path     string
+	replaced bool
 }
 
 func (this *DependencyResolver) localManifestIsLatest(manifest contracts.Manifest) bool {
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/smartystreets/assertions/should"
@@ -23,12 +25,13 @@ type DependencyResolverFixture struct {
 	integrityChecker *FakeIntegrityCheck
 	packageInstaller *FakePackageInstaller
 	dependency       contracts.Dependency
+	resolved         contracts.Manifest
 }
 
 func (this *DependencyResolverFixture) Setup() {
-	this.integrityChecker = &FakeIntegrityCheck{}
+	this.integrityChecker = &FakeIntegrityCheck{pathErrors: make(map[string]error)}
 	this.fileSystem = newInMemoryFileSystem()
-	this.packageInstaller = &FakePackageInstaller{}
+	this.packageInstaller = &FakePackageInstaller{fileSystem: this.fileSystem, remote: contracts.Manifest{Name: "B/C", Version: "D"}}
 	this.dependency = contracts.Dependency{
 		PackageName:    "B/C",
 		PackageVersion: "D",
@@ -38,25 +41,66 @@ func (this *DependencyResolverFixture) Setup() {
 	this.fileSystem.WriteFile("local/manifest_B|C.json", []byte("{}"))
 }
 
-func (this *DependencyResolverFixture) Resolve() error {
+func (this *DependencyResolverFixture) Resolve() (err error) {
 	this.resolver = NewDependencyResolver(this.fileSystem, this.integrityChecker, this.packageInstaller, this.dependency)
-	return this.resolver.Resolve()
+	this.resolved, err = this.resolver.Resolve()
+	return err
 }
 
 func (this *DependencyResolverFixture) TestFreshInstallation() {
 	manifest := contracts.Manifest{
 		Name:    "B/C",
 		Version: "D",
-		Archive: contracts.Archive{Filename: "archive-name"},
+		Archive: contracts.Archive{Filename: "archive"},
 	}
 	this.packageInstaller.remote = manifest
 
 	err := this.Resolve()
 
 	this.So(err, should.BeNil)
+	this.So(this.resolved, should.Resemble, manifest)
 	this.assertNewPackageInstalled(this.dependency.PackageVersion)
 }
 
+func (this *DependencyResolverFixture) TestArchiveInstalledFromFilenameInManifest() {
+	this.packageInstaller.remote = contracts.Manifest{
+		Name:    "B/C",
+		Version: "D",
+		Archive: contracts.Archive{Filename: "archive-name"},
+	}
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.packageRequest.RemoteAddress, should.Resemble, this.URL("gcs://A/B/C/D/archive-name"))
+}
+
+func (this *DependencyResolverFixture) TestArchiveInstalledFromPoolLocationInManifest() {
+	this.packageInstaller.remote = contracts.Manifest{
+		Name:    "B/C",
+		Version: "D",
+		Archive: contracts.Archive{Filename: "archive", Location: "pool/0123abcd"},
+	}
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.packageRequest.RemoteAddress, should.Resemble, this.URL("gcs://A/pool/0123abcd"))
+}
+
+func (this *DependencyResolverFixture) TestDictionaryAddressComposedFromManifest() {
+	this.packageInstaller.remote = contracts.Manifest{
+		Name:    "B/C",
+		Version: "D",
+		Archive: contracts.Archive{Filename: "archive", Dictionary: &contracts.Dictionary{Location: "dictionaries/4567cdef"}},
+	}
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.packageRequest.DictionaryAddress, should.Resemble, this.URL("gcs://A/dictionaries/4567cdef"))
+}
+
 func (this *DependencyResolverFixture) TestManifestInstallationFailure() {
 	manifestErr := errors.New("manifest failure")
 	this.packageInstaller.installManifestErr = manifestErr
@@ -67,6 +111,32 @@ func (this *DependencyResolverFixture) TestManifestInstallationFailure() {
 	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
 }
 
+func (this *DependencyResolverFixture) TestRemoteManifestOfAnotherPackageRefused() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = this.remoteManifest("contents4")
+	this.packageInstaller.remote.Name = "B/other"
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+	this.assertPreviouslyInstalledPackageRetained()
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestRemoteManifestOfAnotherVersionRefused() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = this.remoteManifest("contents4")
+	this.packageInstaller.remote.Version = "older"
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+	this.assertPreviouslyInstalledPackageRetained()
+	this.assertStagingRemoved()
+}
+
 func (this *DependencyResolverFixture) TestManifestFileCannotBeRead() {
 	readFileErr := errors.New("manifest file cannot be read")
 	this.fileSystem.WriteFile("local/manifest_B___C.json", []byte("malformed json"))
@@ -110,16 +180,273 @@ func (this *DependencyResolverFixture) TestLocalManifestHasWrongVersion() {
 }
 
 func (this *DependencyResolverFixture) TestIntegrityCheckFailure() {
-	localManifest := this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
-	this.integrityChecker.err = errors.New("integrity check failure")
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, this.dependency.PackageVersion)
+	this.integrityChecker.pathErrors["local"] = errors.New("integrity check failure")
 
 	err := this.Resolve()
 
 	this.So(err, should.BeNil)
 	this.assertPreviouslyInstalledPackageUninstalled()
 	this.assertNewPackageInstalled(this.dependency.PackageVersion)
-	this.So(this.integrityChecker.localPath, should.Equal, this.dependency.LocalDirectory)
-	this.So(this.integrityChecker.manifest, should.Resemble, localManifest)
+	this.So(this.integrityChecker.verified, should.Resemble, []string{"local", ".local.staging.B___C"})
+	this.So(this.integrityChecker.manifest, should.Resemble, this.packageInstaller.remote)
+}
+
+func (this *DependencyResolverFixture) TestStagedPackageFailsVerification() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = this.remoteManifest("contents4")
+	this.integrityChecker.pathErrors[".local.staging.B___C"] = errors.New("integrity check failure")
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.assertPreviouslyInstalledPackageRetained()
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestFailedInstallationLeavesPreviousVersionInPlace() {
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = this.remoteManifest("contents4")
+	this.packageInstaller.installPackageErr = errors.New("install package error")
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.assertPreviouslyInstalledPackageRetained()
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestLeftoverStagingDirectoryDiscarded() {
+	this.fileSystem.WriteFile(".local.staging.B___C/leftover", []byte("leftover"))
+	this.packageInstaller.remote = this.remoteManifest("contents4")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/leftover")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents4")
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestDedicatedLocalDirectorySwappedWholesale() {
+	this.fileSystem.Delete("local/manifest_B|C.json")
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = this.remoteManifest("contents1", "nested/contents4")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.Listing(), should.HaveLength, 3)
+	this.So(this.fileSystem.readFile("local/contents1"), should.Resemble, []byte("D"))
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/nested/contents4")
+	this.So(this.installedManifest().Version, should.Equal, "D")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents2")
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestDedicatedLocalDirectoryRestoredWhenSwapFails() {
+	this.fileSystem.Delete("local/manifest_B|C.json")
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = this.remoteManifest("contents4")
+	this.fileSystem.errRename[".local.staging.B___C"] = errors.New("rename failure")
+	this.fileSystem.errRename[".local.staging.B___C/contents4"] = errors.New("rename failure")
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.assertPreviouslyInstalledPackageRetained()
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/manifest_B___C.json")
+}
+
+func (this *DependencyResolverFixture) TestDedicatedLocalDirectorySwappedItemByItemWhenRenameFails() {
+	this.fileSystem.Delete("local/manifest_B|C.json")
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = this.remoteManifest("contents1", "contents4")
+	this.fileSystem.errRename["local"] = errors.New("rename failure (e.g. a mount point)")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.readFile("local/contents1"), should.Resemble, []byte("D"))
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents4")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents2")
+	this.So(this.installedManifest().Version, should.Equal, "D")
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestSymlinkedLocalDirectoryKept() {
+	this.fileSystem.Delete("local/manifest_B|C.json")
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.fileSystem.CreateSymlink("elsewhere", "local")
+	this.packageInstaller.remote = this.remoteManifest("contents1", "contents4")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem["local"].symlink, should.Equal, "elsewhere")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents4")
+	this.So(this.installedManifest().Version, should.Equal, "D")
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestSharedLocalDirectorySwappedItemByItem() {
+	this.fileSystem.WriteFile("local/manifest_other.json", []byte("{}"))
+	this.fileSystem.WriteFile("local/other", []byte("other"))
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = this.remoteManifest("contents1", "contents4")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.readFile("local/other"), should.Resemble, []byte("other"))
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/manifest_other.json")
+	this.So(this.fileSystem.readFile("local/contents1"), should.Resemble, []byte("D"))
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents4")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents2")
+	this.So(this.installedManifest().Version, should.Equal, "D")
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestSharedLocalDirectoryRestoredWhenSwapFails() {
+	this.fileSystem.WriteFile("local/other", []byte("other"))
+	previous := this.prepareLocalPackageAndManifest(this.dependency.PackageName, "not"+this.dependency.PackageVersion)
+	this.packageInstaller.remote = this.remoteManifest("contents1", "contents2", "contents4")
+	this.fileSystem.errRename[".local.staging.B___C/contents4"] = errors.New("rename failure")
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.assertPreviouslyInstalledPackageRetained()
+	this.So(this.fileSystem.readFile("local/other"), should.Resemble, []byte("other"))
+	this.So(this.installedManifest(), should.Resemble, previous)
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestStagingPathOfWorkingDirectoryIsBesideIt() {
+	working, _ := os.Getwd()
+
+	staging := ComposeStagingPath(".", "B/C")
+
+	this.So(staging, should.Equal, filepath.Join(filepath.Dir(working), "."+filepath.Base(working)+".staging.B___C"))
+}
+
+func (this *DependencyResolverFixture) TestWorkingDirectoryNeverSwappedWholesale() {
+	this.So(containsWorkingDirectory("."), should.BeTrue)
+	this.So(containsWorkingDirectory(".."), should.BeTrue)
+	this.So(containsWorkingDirectory("local"), should.BeFalse)
+	this.So(containsWorkingDirectory("../sibling"), should.BeFalse)
+}
+
+func (this *DependencyResolverFixture) TestSideBySideVersionInstalledBehindCurrentSymlink() {
+	this.dependency.SideBySide = true
+	this.packageInstaller.remote = this.remoteManifest("contents1")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem["local/current"].Symlink(), should.Equal, ".versions/D")
+	this.So(this.fileSystem.readFile("local/.versions/D/contents1"), should.Resemble, []byte("D"))
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/.versions/D/manifest_B___C.json")
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestSideBySidePreviousVersionRetained() {
+	this.dependency.SideBySide = true
+	this.fileSystem.Delete("local/manifest_B|C.json")
+	this.prepareSideBySideVersion("E")
+	this.packageInstaller.remote = this.remoteManifest("contents1")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.fileSystem.fileSystem["local/current"].Symlink(), should.Equal, ".versions/D")
+	this.So(this.fileSystem.readFile("local/.versions/E/contents1"), should.Resemble, []byte("E"))
+	this.So(this.fileSystem.readFile("local/.versions/D/contents1"), should.Resemble, []byte("D"))
+}
+
+func (this *DependencyResolverFixture) TestSideBySideActiveVersionAlreadyInstalled() {
+	this.dependency.SideBySide = true
+	this.prepareSideBySideVersion("D")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+	this.So(this.integrityChecker.verified, should.Resemble, []string{"local/.versions/D"})
+}
+
+func (this *DependencyResolverFixture) TestSideBySideRetainedVersionActivatedWithoutDownload() {
+	this.dependency.SideBySide = true
+	this.prepareSideBySideVersion("D")
+	this.prepareSideBySideVersion("E")
+	this.packageInstaller.remote = this.remoteManifest("contents1")
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
+	this.So(this.fileSystem.fileSystem["local/current"].Symlink(), should.Equal, ".versions/D")
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) TestSideBySideRollbackKeptUntilListedVersionChanges() {
+	this.dependency.SideBySide = true
+	this.dependency.PackageVersion = "latest"
+	this.prepareSideBySideVersion("D")
+	this.prepareSideBySideVersion("E")
+	_, err := NewSideBySideInstallation(this.fileSystem, "local", 1).Rollback("latest")
+	this.So(err, should.BeNil)
+	this.packageInstaller.remoteLatest = this.remoteManifest("contents1")
+	this.packageInstaller.remoteLatest.Version = "E"
+	this.packageInstaller.remote = this.packageInstaller.remoteLatest
+
+	this.So(this.Resolve(), should.BeNil)
+	this.So(this.resolved.Version, should.Equal, "D")
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+	this.So(this.fileSystem.fileSystem["local/current"].Symlink(), should.Equal, ".versions/D")
+
+	this.dependency.PackageVersion = "E"
+	this.So(this.Resolve(), should.BeNil)
+	this.So(this.fileSystem.fileSystem["local/current"].Symlink(), should.Equal, ".versions/E")
+	this.So(NewSideBySideInstallation(this.fileSystem, "local", 1).PinnedVersion("latest"), should.BeEmpty)
+}
+
+func (this *DependencyResolverFixture) TestSideBySideFailedInstallationLeavesActiveVersion() {
+	this.dependency.SideBySide = true
+	this.prepareSideBySideVersion("E")
+	this.packageInstaller.remote = this.remoteManifest("contents1")
+	this.integrityChecker.pathErrors[".local.staging.B___C"] = errors.New("integrity check failure")
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.So(this.fileSystem.fileSystem["local/current"].Symlink(), should.Equal, ".versions/E")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/.versions/D/contents1")
+	this.assertStagingRemoved()
+}
+
+func (this *DependencyResolverFixture) prepareSideBySideVersion(version string) {
+	manifest := this.remoteManifest("contents1")
+	manifest.Version = version
+	raw, _ := json.Marshal(manifest)
+	this.fileSystem.WriteFile("local/.versions/"+version+"/manifest_B___C.json", raw)
+	this.fileSystem.WriteFile("local/.versions/"+version+"/contents1", []byte(version))
+	installation := NewSideBySideInstallation(this.fileSystem, "local", 1)
+	this.So(installation.Activate(version), should.BeNil)
+}
+
+func (this *DependencyResolverFixture) remoteManifest(paths ...string) contracts.Manifest {
+	manifest := contracts.Manifest{Name: "B/C", Version: "D", Archive: contracts.Archive{Filename: "archive"}}
+	for _, path := range paths {
+		manifest.Archive.Contents = append(manifest.Archive.Contents, contracts.ArchiveItem{Path: path})
+	}
+	return manifest
+}
+
+func (this *DependencyResolverFixture) installedManifest() (manifest contracts.Manifest) {
+	raw, _ := this.fileSystem.ReadFile("local/manifest_B___C.json")
+	_ = json.Unmarshal(raw, &manifest)
+	return manifest
 }
 
 func (this *DependencyResolverFixture) TestAlreadyInstalledCorrectly() {
@@ -133,6 +460,8 @@ func (this *DependencyResolverFixture) TestAlreadyInstalledCorrectly() {
 	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/contents3")
 	this.So(this.packageInstaller.installPackageCounter, should.Equal, 0)
 	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+	this.So(this.resolved.Name, should.Equal, this.dependency.PackageName)
+	this.So(this.resolved.Version, should.Equal, this.dependency.PackageVersion)
 }
 
 func (this *DependencyResolverFixture) TestFinalInstallationFailed() {
@@ -142,6 +471,7 @@ func (this *DependencyResolverFixture) TestFinalInstallationFailed() {
 	err := this.Resolve()
 
 	this.So(errors.Is(err, installError), should.BeTrue)
+	this.So(this.resolved, should.BeZeroValue)
 }
 
 func (this *DependencyResolverFixture) TestLatestIsAlreadyInstalled() {
@@ -162,7 +492,7 @@ func (this *DependencyResolverFixture) TestLatestIsAlreadyInstalled() {
 func (this *DependencyResolverFixture) TestLocalPackageIsBehindLatest() {
 	this.packageInstaller.remote = contracts.Manifest{
 		Name:    "B/C",
-		Version: "D",
+		Version: "E",
 	}
 
 	this.packageInstaller.remoteLatest = contracts.Manifest{
@@ -184,7 +514,7 @@ func (this *DependencyResolverFixture) TestLatestFreshInstallation() {
 	manifest := contracts.Manifest{
 		Name:    "B/C",
 		Version: "D",
-		Archive: contracts.Archive{Filename: "archive-name"},
+		Archive: contracts.Archive{Filename: "archive"},
 	}
 	this.packageInstaller.remote = manifest
 	this.dependency.PackageVersion = "latest"
@@ -193,6 +523,52 @@ func (this *DependencyResolverFixture) TestLatestFreshInstallation() {
 	err := this.Resolve()
 
 	this.assertLatestPackageInstalled(err, version)
+	this.So(this.resolved.Version, should.Equal, version)
+}
+
+func (this *DependencyResolverFixture) TestVersionConstraintResolvedToHighestMatchingVersion() {
+	this.packageInstaller.versionIndex = contracts.VersionIndex{Versions: []string{"1.4.0", "1.10.2", "2.0.0", "1.9.0"}}
+	this.packageInstaller.remote = contracts.Manifest{Name: "B/C", Version: "1.10.2", Archive: contracts.Archive{Filename: "archive"}}
+	this.dependency.PackageVersion = "^1.4"
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.versionIndexRequest, should.Resemble, this.URL("gcs://A/B/C/versions.json"))
+	this.assertNewPackageInstalled("1.10.2")
+	this.So(this.resolved.Version, should.Equal, "1.10.2")
+}
+
+func (this *DependencyResolverFixture) TestVersionConstraintSatisfiedByInstalledVersion() {
+	this.packageInstaller.versionIndex = contracts.VersionIndex{Versions: []string{"1.4.0", "1.4.7", "1.5.0"}}
+	this.prepareLocalPackageAndManifest(this.dependency.PackageName, "1.4.7")
+	this.dependency.PackageVersion = "~1.4"
+
+	err := this.Resolve()
+
+	this.So(err, should.BeNil)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+	this.So(this.resolved.Version, should.Equal, "1.4.7")
+}
+
+func (this *DependencyResolverFixture) TestNoVersionSatisfiesConstraint() {
+	this.packageInstaller.versionIndex = contracts.VersionIndex{Versions: []string{"1.4.0"}}
+	this.dependency.PackageVersion = "^2"
+
+	err := this.Resolve()
+
+	this.So(err, should.NotBeNil)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
+}
+
+func (this *DependencyResolverFixture) TestVersionIndexFailsToDownload() {
+	this.packageInstaller.downloadError = errors.New("download error")
+	this.dependency.PackageVersion = "^2"
+
+	err := this.Resolve()
+
+	this.So(errors.Is(err, this.packageInstaller.downloadError), should.BeTrue)
+	this.So(this.packageInstaller.installManifestCounter, should.Equal, 0)
 }
 
 func (this *DependencyResolverFixture) assertLatestPackageInstalled(err error, version string) {
@@ -200,11 +576,11 @@ func (this *DependencyResolverFixture) assertLatestPackageInstalled(err error, v
 	this.So(this.packageInstaller.installed, should.Resemble, this.packageInstaller.remote)
 	this.So(this.packageInstaller.manifestRequest, should.Resemble, contracts.InstallationRequest{
 		RemoteAddress: this.URL("gcs://A/B/C/manifest.json"),
-		LocalPath:     "local",
+		LocalPath:     ".local.staging.B___C",
 	})
 	this.So(this.packageInstaller.packageRequest, should.Resemble, contracts.InstallationRequest{
 		RemoteAddress: this.URL(fmt.Sprintf("gcs://A/B/C/%s/archive", version)),
-		LocalPath:     "local",
+		LocalPath:     ".local.staging.B___C",
 	})
 }
 func (this *DependencyResolverFixture) TestLatestManifestFailsToDownload() {
@@ -217,21 +593,18 @@ func (this *DependencyResolverFixture) TestLatestManifestFailsToDownload() {
 	err := this.Resolve()
 
 	this.So(err, should.NotBeNil)
-	this.assertPreviouslyInstalledPackageUninstalled()
-	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents1")
-	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents2")
-	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents3")
+	this.assertPreviouslyInstalledPackageRetained()
 }
 
 func (this *DependencyResolverFixture) assertNewPackageInstalled(version string) {
 	this.So(this.packageInstaller.installed, should.Resemble, this.packageInstaller.remote)
 	this.So(this.packageInstaller.manifestRequest, should.Resemble, contracts.InstallationRequest{
 		RemoteAddress: this.URL(fmt.Sprintf("gcs://A/B/C/%s/manifest.json", version)),
-		LocalPath:     "local",
+		LocalPath:     ".local.staging.B___C",
 	})
 	this.So(this.packageInstaller.packageRequest, should.Resemble, contracts.InstallationRequest{
 		RemoteAddress: this.URL(fmt.Sprintf("gcs://A/B/C/%s/archive", version)),
-		LocalPath:     "local",
+		LocalPath:     ".local.staging.B___C",
 	})
 }
 
@@ -258,6 +631,19 @@ func (this *DependencyResolverFixture) prepareLocalPackageAndManifest(
 	return manifest
 }
 
+func (this *DependencyResolverFixture) assertPreviouslyInstalledPackageRetained() {
+	this.So(this.fileSystem.readFile("local/contents1"), should.Resemble, []byte("contents1"))
+	this.So(this.fileSystem.readFile("local/contents2"), should.Resemble, []byte("contents2"))
+	this.So(this.fileSystem.readFile("local/contents3"), should.Resemble, []byte("contents3"))
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents4")
+}
+
+func (this *DependencyResolverFixture) assertStagingRemoved() {
+	for path := range this.fileSystem.fileSystem {
+		this.So(path, should.NotStartWith, ".local.staging")
+	}
+}
+
 func (this *DependencyResolverFixture) assertPreviouslyInstalledPackageUninstalled() {
 	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents1")
 	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/contents2")
@@ -272,7 +658,9 @@ func (this *DependencyResolverFixture) URL(address string) url.URL {
 
 ///////////////////////////////////////////////////////////////////////////////////////////////
 
+// FakePackageInstaller writes the manifest and (placeholder) contents of the remote package into the file system.
 type FakePackageInstaller struct {
+	fileSystem             *inMemoryFileSystem
 	remote                 contracts.Manifest
 	remoteLatest           contracts.Manifest
 	installed              contracts.Manifest
@@ -283,15 +671,26 @@ type FakePackageInstaller struct {
 	installManifestCounter int
 	installPackageCounter  int
 	downloadError          error
+	versionIndex           contracts.VersionIndex
+	versionIndexRequest    url.URL
 }
 
 func (this *FakePackageInstaller) DownloadManifest(remoteAddress url.URL) (manifest contracts.Manifest, err error) {
 	return this.remoteLatest, this.downloadError
 }
 
+func (this *FakePackageInstaller) DownloadVersionIndex(remoteAddress url.URL) (contracts.VersionIndex, error) {
+	this.versionIndexRequest = remoteAddress
+	return this.versionIndex, this.downloadError
+}
+
 func (this *FakePackageInstaller) InstallManifest(request contracts.InstallationRequest) (manifest contracts.Manifest, err error) {
 	this.installManifestCounter++
 	this.manifestRequest = request
+	if this.installManifestErr == nil {
+		raw, _ := json.Marshal(this.remote)
+		this.fileSystem.WriteFile(ComposeManifestPath(request.LocalPath, this.remote.Name), raw)
+	}
 	return this.remote, this.installManifestErr
 }
 
@@ -299,5 +698,8 @@ func (this *FakePackageInstaller) InstallPackage(manifest contracts.Manifest, re
 	this.installPackageCounter++
 	this.installed = manifest
 	this.packageRequest = request
+	for _, item := range manifest.Archive.Contents {
+		this.fileSystem.WriteFile(filepath.Join(request.LocalPath, item.Path), []byte(manifest.Version))
+	}
 	return this.installPackageErr
 }
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+)
+
+// hashAlgorithms calculate the digests recorded in a manifest alongside the MD5 checksums, which
+// remain both for compatibility with older versions of satisfy and for the Content-MD5 of uploads.
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// RegisterHashAlgorithm makes an additional hash algorithm (such as BLAKE3) available to both
+// upload and install.
+func RegisterHashAlgorithm(name string, algorithm func() hash.Hash) {
+	hashAlgorithms[name] = algorithm
+}
+
+func LookupHashAlgorithm(name string) (func() hash.Hash, bool) {
+	algorithm, found := hashAlgorithms[name]
+	return algorithm, found
+}
+
+// Checksum pairs the expected checksum of an archive, archive item or dictionary with the hash
+// algorithm which calculates it.
+type Checksum struct {
+	Algorithm string
+	New       func() hash.Hash
+	Expected  []byte
+}
+
+// StrongestChecksum selects the digest calculated with the hash algorithm of the manifest, falling
+// back to the MD5 checksum for older manifests (or for an algorithm which isn't registered).
+func StrongestChecksum(algorithm string, md5Checksum, checksum []byte) Checksum {
+	if newHash, found := LookupHashAlgorithm(algorithm); found && len(checksum) > 0 {
+		return Checksum{Algorithm: algorithm, New: newHash, Expected: checksum}
+	}
+	return Checksum{Algorithm: "md5", New: md5.New, Expected: md5Checksum}
+}
+
+func (this Checksum) Verify(actual []byte) error {
+	if !bytes.Equal(actual, this.Expected) {
+		return fmt.Errorf("checksum mismatch: actual [%x] != expected [%x] (%s)", actual, this.Expected, this.Algorithm)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"hash"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+)
+
+func TestHashAlgorithmFixture(t *testing.T) {
+	gunit.Run(new(HashAlgorithmFixture), t)
+}
+
+type HashAlgorithmFixture struct {
+	*gunit.Fixture
+}
+
+func (this *HashAlgorithmFixture) TestStrongestChecksumOfManifestAlgorithm() {
+	checksum := StrongestChecksum("sha256", []byte("md5"), []byte("sha256"))
+
+	this.So(checksum.Algorithm, should.Equal, "sha256")
+	this.So(checksum.Expected, should.Resemble, []byte("sha256"))
+	this.So(checksum.New().Size(), should.Equal, sha256.Size)
+}
+
+func (this *HashAlgorithmFixture) TestMD5ChecksumOfOlderManifests() {
+	checksum := StrongestChecksum("", []byte("md5"), nil)
+
+	this.So(checksum.Algorithm, should.Equal, "md5")
+	this.So(checksum.Expected, should.Resemble, []byte("md5"))
+	this.So(checksum.New().Size(), should.Equal, md5.Size)
+}
+
+func (this *HashAlgorithmFixture) TestMD5ChecksumWhenAlgorithmUnregistered() {
+	checksum := StrongestChecksum("blake3", []byte("md5"), []byte("blake3"))
+
+	this.So(checksum.Algorithm, should.Equal, "md5")
+	this.So(checksum.Expected, should.Resemble, []byte("md5"))
+}
+
+func (this *HashAlgorithmFixture) TestMD5ChecksumWhenDigestMissing() {
+	checksum := StrongestChecksum("sha256", []byte("md5"), nil)
+
+	this.So(checksum.Algorithm, should.Equal, "md5")
+}
+
+func (this *HashAlgorithmFixture) TestRegisteredAlgorithm() {
+	RegisterHashAlgorithm("fake", func() hash.Hash { return NewFakeHasher() })
+	defer delete(hashAlgorithms, "fake")
+
+	checksum := StrongestChecksum("fake", []byte("md5"), []byte("fake"))
+
+	this.So(checksum.Algorithm, should.Equal, "fake")
+}
+
+func (this *HashAlgorithmFixture) TestVerify() {
+	checksum := Checksum{Algorithm: "sha256", Expected: []byte{1, 2}}
+
+	this.So(checksum.Verify([]byte{1, 2}), should.BeNil)
+	this.So(checksum.Verify([]byte{2, 1}).Error(), should.Equal, "checksum mismatch: actual [0201] != expected [0102] (sha256)")
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+type HTTPCredentialParser struct {
+	environment contracts.Environment
+}
+
+func NewHTTPCredentialParser(environment contracts.Environment) HTTPCredentialParser {
+	return HTTPCredentialParser{environment: environment}
+}
+
+// Parse reads the optional credentials for plain HTTP(S) downloads. A bearer token, when
+// present, takes precedence over basic authentication. Absent credentials are not an error,
+// but credentials without the host to which they belong are.
+func (this HTTPCredentialParser) Parse() (credentials contracts.HTTPCredentials, err error) {
+	credentials.BearerToken = this.lookup("SATISFY_HTTP_BEARER_TOKEN")
+	if credentials.BearerToken == "" {
+		credentials.Username = this.lookup("SATISFY_HTTP_USERNAME")
+		credentials.Password = this.lookup("SATISFY_HTTP_PASSWORD")
+	}
+	if credentials == (contracts.HTTPCredentials{}) {
+		return credentials, nil
+	}
+	credentials.Host = strings.ToLower(this.lookup("SATISFY_HTTP_HOST"))
+	if credentials.Host == "" {
+		return contracts.HTTPCredentials{}, errors.New("the SATISFY_HTTP_HOST (to which the http credentials are sent) is required")
+	}
+	return credentials, nil
+}
+
+func (this HTTPCredentialParser) lookup(key string) string {
+	value, _ := this.environment.LookupEnv(key)
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestHTTPCredentialParserFixture(t *testing.T) {
+	gunit.Run(new(HTTPCredentialParserFixture), t)
+}
+
+type HTTPCredentialParserFixture struct {
+	*gunit.Fixture
+
+	parser      HTTPCredentialParser
+	environment FakeEnvironment
+}
+
+func (this *HTTPCredentialParserFixture) Setup() {
+	this.environment = make(FakeEnvironment)
+	this.parser = NewHTTPCredentialParser(this.environment)
+}
+
+func (this *HTTPCredentialParserFixture) TestNoCredentials() {
+	this.environment["SATISFY_HTTP_HOST"] = "packages.example.com"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.BeZeroValue)
+}
+
+func (this *HTTPCredentialParserFixture) TestBasicCredentials() {
+	this.environment["SATISFY_HTTP_HOST"] = " Packages.Example.com:8443 "
+	this.environment["SATISFY_HTTP_USERNAME"] = "username"
+	this.environment["SATISFY_HTTP_PASSWORD"] = " password "
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, contracts.HTTPCredentials{
+		Host:     "packages.example.com:8443",
+		Username: "username",
+		Password: "password",
+	})
+}
+
+func (this *HTTPCredentialParserFixture) TestBearerTokenTakesPrecedence() {
+	this.environment["SATISFY_HTTP_HOST"] = "packages.example.com"
+	this.environment["SATISFY_HTTP_BEARER_TOKEN"] = "token"
+	this.environment["SATISFY_HTTP_USERNAME"] = "username"
+	this.environment["SATISFY_HTTP_PASSWORD"] = "password"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.BeNil)
+	this.So(credentials, should.Resemble, contracts.HTTPCredentials{Host: "packages.example.com", BearerToken: "token"})
+}
+
+func (this *HTTPCredentialParserFixture) TestCredentialsWithoutHostRejected() {
+	this.environment["SATISFY_HTTP_BEARER_TOKEN"] = "token"
+
+	credentials, err := this.parser.Parse()
+
+	this.So(err, should.NotBeNil)
+	this.So(credentials, should.BeZeroValue)
+}
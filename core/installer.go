@@ -2,9 +2,6 @@ package core
 
 import (
 	"archive/tar"
-	"bytes"
-	"compress/gzip"
-	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,7 +13,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/klauspost/compress/zstd"
 	"github.com/smartystreets/satisfy/contracts"
 )
 
@@ -31,70 +27,169 @@ type PackageInstallerFileSystem interface {
 type PackageInstaller struct {
 	downloader contracts.Downloader
 	filesystem PackageInstallerFileSystem
+	trusted    TrustedKeys
+	newSpool   func() (ArchiveSpool, error)
 }
 
-func NewPackageInstaller(downloader contracts.Downloader, filesystem PackageInstallerFileSystem) *PackageInstaller {
-	return &PackageInstaller{downloader: downloader, filesystem: filesystem}
+// NewPackageInstaller requires manifests to be signed by one of the trusted keys (if any). Archives
+// which can't be extracted as they're downloaded (i.e. zip archives) are held in a new spool.
+func NewPackageInstaller(
+	downloader contracts.Downloader,
+	filesystem PackageInstallerFileSystem,
+	trusted TrustedKeys,
+	newSpool func() (ArchiveSpool, error),
+) *PackageInstaller {
+	return &PackageInstaller{downloader: downloader, filesystem: filesystem, trusted: trusted, newSpool: newSpool}
 }
 
 func (this *PackageInstaller) DownloadManifest(remoteAddress url.URL) (manifest contracts.Manifest, err error) {
-	body, err := this.downloader.Download(remoteAddress)
+	manifest, _, _, err = this.downloadManifest(remoteAddress)
+	return manifest, err
+}
+
+func (this *PackageInstaller) downloadManifest(remoteAddress url.URL) (manifest contracts.Manifest, raw, signature []byte, err error) {
+	raw, err = this.download(remoteAddress)
 	if err != nil {
-		return contracts.Manifest{}, err
+		return contracts.Manifest{}, nil, nil, err
 	}
+	if len(this.trusted) > 0 {
+		signature, err = this.download(contracts.ComposeSignatureAddress(remoteAddress))
+		if err != nil {
+			return contracts.Manifest{}, nil, nil, fmt.Errorf("failed to download manifest signature (unsigned packages are refused): %w", err)
+		}
+		err = this.trusted.Verify(raw, signature)
+		if err != nil {
+			return contracts.Manifest{}, nil, nil, err
+		}
+	}
+	err = json.Unmarshal(raw, &manifest)
+	if err != nil {
+		return contracts.Manifest{}, nil, nil, err
+	}
+	return manifest, raw, signature, nil
+}
 
-	defer closeResource(body)
-
-	rawManifest, err := ioutil.ReadAll(body)
-	err = json.Unmarshal(rawManifest, &manifest)
+func (this *PackageInstaller) DownloadVersionIndex(remoteAddress url.URL) (index contracts.VersionIndex, err error) {
+	raw, err := this.download(remoteAddress)
+	if err != nil {
+		return contracts.VersionIndex{}, err
+	}
+	err = json.Unmarshal(raw, &index)
+	if err != nil {
+		return contracts.VersionIndex{}, fmt.Errorf("malformed versions index: %w", err)
+	}
+	return index, nil
+}
 
-	return manifest, err
+func (this *PackageInstaller) download(remoteAddress url.URL) ([]byte, error) {
+	body, err := this.downloader.Download(remoteAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer closeResource(body)
+	return ioutil.ReadAll(body)
 }
 
+// InstallManifest stores a signed manifest exactly as it was signed (along with its signature) so
+// that the signature can be verified again once the manifest has been installed.
 func (this *PackageInstaller) InstallManifest(request contracts.InstallationRequest) (manifest contracts.Manifest, err error) {
-	manifest, err = this.DownloadManifest(request.RemoteAddress)
+	manifest, rawManifest, signature, err := this.downloadManifest(request.RemoteAddress)
 	if err != nil {
 		return contracts.Manifest{}, err
 	}
-	rawManifest, err := json.MarshalIndent(manifest, "", "  ")
+	manifestPath := ComposeManifestPath(request.LocalPath, manifest.Name)
+	if signature != nil {
+		this.filesystem.WriteFile(manifestPath, rawManifest)
+		this.filesystem.WriteFile(manifestPath+contracts.SignatureExtension, signature)
+		return manifest, nil
+	}
+	rawManifest, err = json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
 		return contracts.Manifest{}, err
 	}
-	this.filesystem.WriteFile(ComposeManifestPath(request.LocalPath, manifest.Name), rawManifest)
+	this.filesystem.WriteFile(manifestPath, rawManifest)
 	return manifest, nil
 }
 
 func (this *PackageInstaller) InstallPackage(manifest contracts.Manifest, request contracts.InstallationRequest) error {
-	body, err := this.downloader.Download(request.RemoteAddress)
+	options, err := this.codecOptions(manifest, request)
+	if err != nil {
+		return err
+	}
+	body, err := this.downloadArchive(manifest, request)
 	if err != nil {
 		return err
 	}
 
 	defer closeResource(body)
-	checksumReader := NewHashReader(body, md5.New())
+	checksum := this.archiveChecksum(manifest.Archive)
+	checksumReader := NewHashReader(body, checksum.New())
 
-	factory, found := decompressors[manifest.Archive.CompressionAlgorithm]
+	codec, found := LookupCodec(manifest.Archive.CompressionAlgorithm)
 	if !found {
 		return errors.New("invalid compression algorithm")
 	}
-	decompressor, err := factory(checksumReader)
+	decompressor, err := codec.Decompress(checksumReader, options)
 	if err != nil {
 		return err
 	}
 	paths, err := this.extractArchive(decompressor, request, len(manifest.Archive.Contents))
+	if err == nil {
+		_, err = io.Copy(ioutil.Discard, checksumReader) // trailing bytes not consumed by the decompressor are still checksummed
+	}
 	if err != nil {
 		this.revertFileSystem(paths)
 		return err
 	}
-	actualChecksum := checksumReader.Sum(nil)
-	if bytes.Compare(actualChecksum, manifest.Archive.MD5Checksum) != 0 {
+	err = checksum.Verify(checksumReader.Sum(nil))
+	if err != nil {
 		this.revertFileSystem(paths)
-		return fmt.Errorf("checksum mismatch: actual [%x] != expected [%x]", actualChecksum, manifest.Archive.MD5Checksum)
+		return err
 	}
 
 	return nil
 }
 
+func (this *PackageInstaller) archiveChecksum(archive contracts.Archive) Checksum {
+	checksum := StrongestChecksum(archive.HashAlgorithm, archive.MD5Checksum, archive.Checksum)
+	if archive.HashAlgorithm != "" && checksum.Algorithm != archive.HashAlgorithm {
+		log.Printf("[WARN] Unsupported hash algorithm %q, verifying the MD5 checksum instead.", archive.HashAlgorithm)
+	}
+	return checksum
+}
+
+func (this *PackageInstaller) downloadArchive(manifest contracts.Manifest, request contracts.InstallationRequest) (io.ReadCloser, error) {
+	if archives, ok := this.downloader.(ArchiveDownloader); ok {
+		return archives.DownloadArchive(request.RemoteAddress, manifest.Archive)
+	}
+	return this.downloader.Download(request.RemoteAddress)
+}
+
+func (this *PackageInstaller) codecOptions(manifest contracts.Manifest, request contracts.InstallationRequest) (options CodecOptions, err error) {
+	options.Spool = this.newSpool
+	dictionary := manifest.Archive.Dictionary
+	if dictionary == nil {
+		return options, nil
+	}
+	body, err := this.downloader.Download(request.DictionaryAddress)
+	if err != nil {
+		return options, fmt.Errorf("failed to download compression dictionary: %w", err)
+	}
+	defer closeResource(body)
+
+	options.Dictionary, err = ioutil.ReadAll(body)
+	if err != nil {
+		return options, fmt.Errorf("failed to download compression dictionary: %w", err)
+	}
+	checksum := StrongestChecksum(manifest.Archive.HashAlgorithm, dictionary.MD5Checksum, dictionary.Checksum)
+	hasher := checksum.New()
+	_, _ = hasher.Write(options.Dictionary)
+	if err = checksum.Verify(hasher.Sum(nil)); err != nil {
+		return options, fmt.Errorf("compression dictionary %w", err)
+	}
+	return options, nil
+}
+
 func (this *PackageInstaller) extractArchive(decompressor io.ReadCloser, request contracts.InstallationRequest, itemCount int) (paths []string, err error) {
 	defer closeResource(decompressor)
 	var reader ArchiveReader
@@ -104,6 +199,7 @@ func (this *PackageInstaller) extractArchive(decompressor io.ReadCloser, request
 		reader = archiveFormats[""](decompressor)
 	}
 
+	symlinks := make(map[string]bool) // extracted thus far, keyed by their cleaned (relative) paths
 	for i := 0; ; i++ {
 		header, err := reader.Next()
 		if err == io.EOF {
@@ -112,12 +208,16 @@ func (this *PackageInstaller) extractArchive(decompressor io.ReadCloser, request
 		if err != nil {
 			return paths, err
 		}
+		if err = this.checkBounds(header, request.LocalPath, symlinks); err != nil {
+			return paths, err
+		}
 		pathItem := filepath.Join(request.LocalPath, header.Name)
 		paths = append(paths, pathItem)
 		log.Printf("Extracting archive item [%d/%d] \"%s\" [%s] to \"%s\".",
 			i+1, itemCount, header.Name, byteCountToString(header.Size), pathItem)
 
 		if header.Typeflag == tar.TypeSymlink {
+			symlinks[filepath.Clean(header.Name)] = true
 			this.filesystem.CreateSymlink(header.Linkname, pathItem)
 		} else {
 			writer := this.filesystem.Create(pathItem)
@@ -138,6 +238,53 @@ func (this *PackageInstaller) extractArchive(decompressor io.ReadCloser, request
 	return paths, nil
 }
 
+// checkBounds rejects archive items (or the targets of symlinks) which would land outside of
+// the installation directory, as the PackageBuilder does when the archive is created. Because the
+// file system (unlike filepath.Clean) follows symlinks, items may not be written through (or over)
+// a symlink extracted earlier and symlinks may only ascend (with "..") before they descend, so
+// that no ".." is ever resolved from within the target of another symlink.
+func (this *PackageInstaller) checkBounds(header *tar.Header, localPath string, symlinks map[string]bool) error {
+	if outOfBounds(header.Name) {
+		return fmt.Errorf("the archive item \"%s\" is outside of the installation directory: \"%s\"", header.Name, localPath)
+	}
+	for parent := filepath.Clean(header.Name); parent != "."; parent = filepath.Dir(parent) {
+		if symlinks[parent] {
+			return fmt.Errorf(
+				"the archive item \"%s\" passes through the symlink \"%s\" and may be outside of the installation directory: \"%s\"",
+				header.Name, parent, localPath)
+		}
+	}
+	if header.Typeflag != tar.TypeSymlink {
+		return nil
+	}
+	if filepath.IsAbs(header.Linkname) || ascendsAfterDescending(header.Linkname) ||
+		outOfBounds(filepath.Join(filepath.Dir(header.Name), header.Linkname)) {
+		return fmt.Errorf(
+			"the archive item \"%s\" is a symlink that refers to \"%s\" which is outside of the installation directory: \"%s\"",
+			header.Name, header.Linkname, localPath)
+	}
+	return nil
+}
+
+func outOfBounds(relativePath string) bool {
+	if filepath.IsAbs(relativePath) {
+		return true
+	}
+	cleaned := filepath.Clean(relativePath)
+	return cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
+}
+
+func ascendsAfterDescending(linkname string) bool {
+	descended := false
+	for _, element := range strings.Split(filepath.ToSlash(linkname), "/") {
+		if element == ".." && descended {
+			return true
+		}
+		descended = descended || (element != ".." && element != "." && element != "")
+	}
+	return false
+}
+
 func byteCountToString(size int64) string {
 	const unit = 1024
 	if size < unit {
@@ -157,6 +304,20 @@ func (this *PackageInstaller) revertFileSystem(paths []string) {
 	}
 }
 
+// ComposeStagingPath names the directory (next to the local directory) into which a package is
+// extracted and verified before being moved into place. A local directory such as "." or ".." is
+// made absolute first, as otherwise it has neither a name nor a parent.
+func ComposeStagingPath(localPath, packageName string) string {
+	cleanPackageName := strings.ReplaceAll(packageName, "/", "___")
+	localPath = filepath.Clean(localPath)
+	if base := filepath.Base(localPath); base == "." || base == ".." {
+		if absolute, err := filepath.Abs(localPath); err == nil {
+			localPath = absolute
+		}
+	}
+	return filepath.Join(filepath.Dir(localPath), fmt.Sprintf(".%s.staging.%s", filepath.Base(localPath), cleanPackageName))
+}
+
 func ComposeManifestPath(localPath, packageName string) string {
 	cleanPackageName := strings.ReplaceAll(packageName, "/", "___")
 	fileName := fmt.Sprintf("manifest_%s.json", cleanPackageName)
@@ -165,24 +326,6 @@ func ComposeManifestPath(localPath, packageName string) string {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-var decompressors = map[string]func(_ io.Reader) (io.ReadCloser, error){
-	"zstd": newZStdReader,
-	"gzip": newGZipReader,
-}
-
-func newZStdReader(source io.Reader) (io.ReadCloser, error) {
-	if reader, err := zstd.NewReader(source); err != nil {
-		return nil, err
-	} else {
-		return reader.IOReadCloser(), nil
-	}
-}
-func newGZipReader(source io.Reader) (io.ReadCloser, error) {
-	return gzip.NewReader(source)
-}
-
-////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
-
 type ArchiveReader interface {
 	Next() (*tar.Header, error)
 	io.Reader
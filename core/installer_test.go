@@ -2,19 +2,20 @@ package core
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
-	"compress/gzip"
+	"crypto/ed25519"
 	"crypto/md5"
 	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/url"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/klauspost/compress/zstd"
 	"github.com/smartystreets/assertions/should"
 	"github.com/smartystreets/gunit"
 	"github.com/smartystreets/satisfy/contracts"
@@ -29,12 +30,19 @@ type PackageInstallerFixture struct {
 	installer  *PackageInstaller
 	downloader *FakeDownloader
 	filesystem *inMemoryFileSystem
+	spools     []*FakeArchiveSpool
 }
 
 func (this *PackageInstallerFixture) Setup() {
 	this.downloader = &FakeDownloader{}
 	this.filesystem = newInMemoryFileSystem()
-	this.installer = NewPackageInstaller(this.downloader, this.filesystem)
+	this.installer = NewPackageInstaller(this.downloader, this.filesystem, nil, this.newSpool)
+}
+
+func (this *PackageInstallerFixture) newSpool() (ArchiveSpool, error) {
+	spool := &FakeArchiveSpool{}
+	this.spools = append(this.spools, spool)
+	return spool, nil
 }
 
 func (this *PackageInstallerFixture) TestInstallManifest() {
@@ -96,6 +104,204 @@ func (this *PackageInstallerFixture) LongTestInstallPackageToLocalFileSystemUsin
 	this.So(this.filesystem.readFile("local/path/Link"), should.Resemble, []byte("Hello World"))
 }
 
+func (this *PackageInstallerFixture) TestInstallPackageToLocalFileSystemUsingZip() {
+	checksum := this.downloader.prepareZipArchiveDownload()
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, zipAlgorithm), this.installationRequest())
+
+	this.So(err, should.BeNil)
+	this.So(this.filesystem.readFile("local/path/Hello/World"), should.Resemble, []byte("Hello World"))
+	this.So(this.filesystem.readFile("local/path/Goodbye/World"), should.Resemble, []byte("Goodbye World"))
+	this.So(this.filesystem.readFile("local/path/Link"), should.Resemble, []byte("Hello World"))
+	this.So(this.filesystem.fileSystem["local/path/Goodbye/World"].Mode(), should.Equal, 0755)
+	this.So(this.filesystem.fileSystem["local/path/Hello/World"].Mode(), should.NotEqual, 0755)
+	this.So(this.spools, should.HaveLength, 1)
+	this.So(this.spools[0].Len(), should.BeGreaterThan, 0)
+	this.So(this.spools[0].closed, should.BeTrue)
+}
+
+func (this *PackageInstallerFixture) TestZipPackageNotInstalledWithoutSpool() {
+	checksum := this.downloader.prepareZipArchiveDownload()
+	this.installer = NewPackageInstaller(this.downloader, this.filesystem, nil, nil)
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, zipAlgorithm), this.installationRequest())
+
+	this.So(err, should.NotBeNil)
+	this.So(this.filesystem.Listing(), should.BeEmpty)
+}
+
+func (this *PackageInstallerFixture) TestZipPackageNotInstalledWhenSpoolUnavailable() {
+	checksum := this.downloader.prepareZipArchiveDownload()
+	spoolErr := errors.New("no space left on device")
+	this.installer = NewPackageInstaller(this.downloader, this.filesystem, nil, func() (ArchiveSpool, error) { return nil, spoolErr })
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, zipAlgorithm), this.installationRequest())
+
+	this.So(errors.Is(err, spoolErr), should.BeTrue)
+	this.So(this.filesystem.Listing(), should.BeEmpty)
+}
+
+func (this *PackageInstallerFixture) TestInstallZipPackageChecksumMismatch() {
+	this.downloader.prepareZipArchiveDownload()
+
+	err := this.installer.InstallPackage(this.buildManifest([]byte("mismatch"), zipAlgorithm), this.installationRequest())
+
+	this.So(err, should.NotBeNil)
+	this.So(this.filesystem.Listing(), should.BeEmpty)
+}
+
+func (this *PackageInstallerFixture) TestInstallPackageInvalidZipArchive() {
+	this.downloader.prepareMalformedDownload()
+
+	err := this.installer.InstallPackage(this.buildManifest(nil, zipAlgorithm), this.installationRequest())
+
+	this.So(err, should.NotBeNil)
+	this.So(this.filesystem.Listing(), should.BeEmpty)
+}
+
+func (this *PackageInstallerFixture) TestInstallPackageUsingXZCompression() {
+	this.assertArchiveInstalled("xz")
+}
+func (this *PackageInstallerFixture) TestInstallPackageUsingLZ4Compression() {
+	this.assertArchiveInstalled("lz4")
+}
+func (this *PackageInstallerFixture) TestInstallPackageUsingBrotliCompression() {
+	this.assertArchiveInstalled("brotli")
+}
+func (this *PackageInstallerFixture) TestInstallPackageWithoutCompression() {
+	this.assertArchiveInstalled("none")
+}
+
+func (this *PackageInstallerFixture) assertArchiveInstalled(compressionAlgorithm string) {
+	checksum := this.downloader.prepareArchiveDownload(compressionAlgorithm)
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, compressionAlgorithm), this.installationRequest())
+
+	this.So(err, should.BeNil)
+	this.So(this.filesystem.readFile("local/path/Hello/World"), should.Resemble, []byte("Hello World"))
+	this.So(this.filesystem.readFile("local/path/Goodbye/World"), should.Resemble, []byte("Goodbye World"))
+	this.So(this.filesystem.readFile("local/path/Link"), should.Resemble, []byte("Hello World"))
+	this.So(this.filesystem.fileSystem["local/path/Goodbye/World"].Mode(), should.Equal, 0755)
+}
+
+func (this *PackageInstallerFixture) TestInterruptedDownloadResumedAndVerified() {
+	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
+	content, _ := ioutil.ReadAll(this.downloader.Body)
+	ranged := &FakeRangeClient{FakeClient: &FakeClient{}, content: string(content), interruptions: []int64{10, 50}}
+	this.installer = NewPackageInstaller(NewRetryClient(ranged, 2, func(time.Duration) {}), this.filesystem, nil, newMemoryArchiveSpool)
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, gzipAlgorithm), this.installationRequest())
+
+	this.So(err, should.BeNil)
+	this.So(ranged.ranges, should.Resemble, []string{"10-", "50-"})
+	this.So(this.filesystem.readFile("local/path/Hello/World"), should.Resemble, []byte("Hello World"))
+}
+
+func (this *PackageInstallerFixture) TestInstallPackageWithCompressionDictionary() {
+	RegisterCodec(dictionaryAlgorithm, dictionaryCodec)
+	this.downloader.Dictionary = []byte("dictionary")
+	checksum := this.downloader.prepareArchiveDownload("none")
+
+	err := this.installer.InstallPackage(this.buildDictionaryManifest(checksum, "dictionary"), this.dictionaryInstallationRequest())
+
+	this.So(err, should.BeNil)
+	this.So(this.downloader.request, should.Resemble, this.installationRequest().RemoteAddress)
+	this.So(this.filesystem.readFile("local/path/Hello/World"), should.Resemble, []byte("Hello World"))
+}
+
+func (this *PackageInstallerFixture) TestCompressionDictionaryChecksumMismatch() {
+	RegisterCodec(dictionaryAlgorithm, dictionaryCodec)
+	this.downloader.Dictionary = []byte("corrupted")
+	checksum := this.downloader.prepareArchiveDownload("none")
+
+	err := this.installer.InstallPackage(this.buildDictionaryManifest(checksum, "dictionary"), this.dictionaryInstallationRequest())
+
+	this.So(err, should.NotBeNil)
+	this.So(this.downloader.request, should.BeZeroValue)
+	this.So(this.filesystem.Listing(), should.BeEmpty)
+}
+
+func (this *PackageInstallerFixture) buildDictionaryManifest(checksum []byte, dictionary string) contracts.Manifest {
+	manifest := this.buildManifest(checksum, dictionaryAlgorithm)
+	dictionaryChecksum := md5.Sum([]byte(dictionary))
+	manifest.Archive.Dictionary = &contracts.Dictionary{
+		Location:    contracts.ComposeDictionaryLocation(dictionaryChecksum[:]),
+		Size:        uint64(len(dictionary)),
+		MD5Checksum: dictionaryChecksum[:],
+	}
+	return manifest
+}
+
+func (this *PackageInstallerFixture) dictionaryInstallationRequest() contracts.InstallationRequest {
+	request := this.installationRequest()
+	request.DictionaryAddress = dictionaryAddress
+	return request
+}
+
+func (this *PackageInstallerFixture) TestArchiveItemOutsideOfInstallationDirectoryRejected() {
+	this.assertRejected(&tar.Header{Name: "../escape", Size: 1})
+}
+func (this *PackageInstallerFixture) TestNestedArchiveItemOutsideOfInstallationDirectoryRejected() {
+	this.assertRejected(&tar.Header{Name: "Hello/../../escape", Size: 1})
+}
+func (this *PackageInstallerFixture) TestAbsoluteArchiveItemRejected() {
+	this.assertRejected(&tar.Header{Name: "/etc/escape", Size: 1})
+}
+func (this *PackageInstallerFixture) TestSymlinkOutsideOfInstallationDirectoryRejected() {
+	this.assertRejected(&tar.Header{Typeflag: tar.TypeSymlink, Name: "Hello/Link", Linkname: "../../etc/passwd"})
+}
+func (this *PackageInstallerFixture) TestAbsoluteSymlinkRejected() {
+	this.assertRejected(&tar.Header{Typeflag: tar.TypeSymlink, Name: "Link", Linkname: "/etc/passwd"})
+}
+
+func (this *PackageInstallerFixture) TestChainedSymlinksOutsideOfInstallationDirectoryRejected() {
+	checksum := this.downloader.prepareTarDownload(
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "l", Linkname: "."},
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "m", Linkname: "l/.."}, // resolves to the parent of "."
+		&tar.Header{Name: "m/evil", Size: 1},
+	)
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, "none"), this.installationRequest())
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "outside of the installation directory")
+	this.So(this.filesystem.Listing(), should.BeEmpty)
+}
+func (this *PackageInstallerFixture) TestItemWrittenThroughSymlinkRejected() {
+	checksum := this.downloader.prepareTarDownload(
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "Hello/Link", Linkname: "../World"},
+		&tar.Header{Name: "Hello/Link/evil", Size: 1},
+	)
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, "none"), this.installationRequest())
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "passes through the symlink \"Hello/Link\"")
+	this.So(this.filesystem.Listing(), should.BeEmpty)
+}
+
+func (this *PackageInstallerFixture) TestItemsWithinInstallationDirectoryAllowed() {
+	checksum := this.downloader.prepareTarDownload(
+		&tar.Header{Name: "Hello/../World", Size: 1},
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "Hello/Link", Linkname: "../World"},
+	)
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, "none"), this.installationRequest())
+
+	this.So(err, should.BeNil)
+	this.So(this.filesystem.readFile("local/path/Hello/Link"), should.Resemble, []byte("x"))
+}
+
+func (this *PackageInstallerFixture) assertRejected(malicious *tar.Header) {
+	checksum := this.downloader.prepareTarDownload(&tar.Header{Name: "Hello/World", Size: 1}, malicious)
+
+	err := this.installer.InstallPackage(this.buildManifest(checksum, "none"), this.installationRequest())
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "outside of the installation directory")
+	this.So(this.filesystem.Listing(), should.BeEmpty)
+}
+
 func (this *PackageInstallerFixture) TestCompressionMethodInvalid() {
 
 	checksum := this.downloader.prepareArchiveDownload(gzipAlgorithm)
@@ -156,12 +362,16 @@ func (this *PackageInstallerFixture) installationRequest() contracts.Installatio
 ///////////////////////////////////////////////////////////////////////////////////////////////
 
 type FakeDownloader struct {
-	Body    io.ReadCloser
-	Error   error
-	request url.URL
+	Body       io.ReadCloser
+	Error      error
+	Dictionary []byte
+	request    url.URL
 }
 
 func (this *FakeDownloader) Download(request url.URL) (io.ReadCloser, error) {
+	if request == dictionaryAddress {
+		return ioutil.NopCloser(bytes.NewReader(this.Dictionary)), nil
+	}
 	this.request = request
 	return this.Body, this.Error
 }
@@ -170,7 +380,7 @@ func (this *FakeDownloader) prepareArchiveDownload(compressionAlgorithm string)
 	hasher := md5.New()
 	writer := bytes.NewBuffer(nil)
 	multi := io.MultiWriter(hasher, writer)
-	compressor := compression[compressionAlgorithm](multi, 4)
+	compressor, _ := codecs[compressionAlgorithm].Compress(multi, CodecOptions{Level: 4})
 	archiveWriter := tar.NewWriter(compressor)
 
 	_ = archiveWriter.WriteHeader(&tar.Header{
@@ -197,6 +407,67 @@ func (this *FakeDownloader) prepareArchiveDownload(compressionAlgorithm string)
 	return hasher.Sum(nil)
 }
 
+// prepareTarDownload builds an uncompressed tar archive of the headers (with 'x' as the contents of each file).
+func (this *FakeDownloader) prepareTarDownload(headers ...*tar.Header) []byte {
+	writer := bytes.NewBuffer(nil)
+	archiveWriter := tar.NewWriter(writer)
+	for _, header := range headers {
+		_ = archiveWriter.WriteHeader(header)
+		if header.Typeflag != tar.TypeSymlink {
+			_, _ = archiveWriter.Write([]byte("x"))
+		}
+	}
+	_ = archiveWriter.Close()
+
+	this.Body = ioutil.NopCloser(bytes.NewReader(writer.Bytes()))
+
+	checksum := md5.Sum(writer.Bytes())
+	return checksum[:]
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+// FakeArchiveSpool holds a spooled archive in memory.
+type FakeArchiveSpool struct {
+	bytes.Buffer
+	closed bool
+}
+
+func newMemoryArchiveSpool() (ArchiveSpool, error) { return &FakeArchiveSpool{}, nil }
+
+func (this *FakeArchiveSpool) ReadAt(buffer []byte, offset int64) (int, error) {
+	return bytes.NewReader(this.Bytes()).ReadAt(buffer, offset)
+}
+
+func (this *FakeArchiveSpool) Close() error {
+	this.closed = true
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+func (this *FakeDownloader) prepareZipArchiveDownload() []byte {
+	writer := bytes.NewBuffer(nil)
+	archiveWriter := zip.NewWriter(writer)
+
+	hello, _ := archiveWriter.CreateHeader(&zip.FileHeader{Name: "Hello/World", Method: zip.Deflate})
+	_, _ = hello.Write([]byte("Hello World"))
+	goodbyeHeader := &zip.FileHeader{Name: "Goodbye/World", Method: zip.Deflate}
+	goodbyeHeader.SetMode(0755)
+	goodbye, _ := archiveWriter.CreateHeader(goodbyeHeader)
+	_, _ = goodbye.Write([]byte("Goodbye World"))
+	linkHeader := &zip.FileHeader{Name: "Link"}
+	linkHeader.SetMode(os.ModeSymlink | 0777)
+	link, _ := archiveWriter.CreateHeader(linkHeader)
+	_, _ = link.Write([]byte("Hello/World"))
+	_ = archiveWriter.Close()
+
+	this.Body = ioutil.NopCloser(bytes.NewReader(writer.Bytes()))
+
+	checksum := md5.Sum(writer.Bytes())
+	return checksum[:]
+}
+
 func (this *FakeDownloader) prepareManifestDownload(manifest contracts.Manifest) {
 	raw, _ := json.Marshal(manifest)
 	this.Body = ioutil.NopCloser(bytes.NewReader(raw))
@@ -206,24 +477,101 @@ func (this *FakeDownloader) prepareMalformedDownload() {
 	this.Body = ioutil.NopCloser(strings.NewReader("malformed"))
 }
 
-var compression = map[string]func(_ io.Writer, level int) io.WriteCloser{
-	"zstd": func(writer io.Writer, level int) io.WriteCloser {
-		compressor, err := zstd.NewWriter(writer, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
-		if err != nil {
-			log.Fatal(err)
-		}
-		return compressor
-	},
-	gzipAlgorithm: func(writer io.Writer, level int) io.WriteCloser {
-		compressor, err := gzip.NewWriterLevel(writer, level)
-		if err != nil {
-			log.Panicln(err)
-		}
-		return compressor
-	},
-}
-
 const (
 	gzipAlgorithm = "gzip"
 	zstdAlgorithm = "zstd"
+	zipAlgorithm  = "zip"
+
+	dictionaryAlgorithm = "dictionary-test"
 )
+
+var dictionaryAddress = url.URL{Host: "bucket", Path: "dictionaries/checksum"}
+
+// dictionaryCodec stores the tar archive uncompressed, but insists upon being given the dictionary.
+var dictionaryCodec = Codec{
+	Decompress: func(reader io.Reader, options CodecOptions) (io.ReadCloser, error) {
+		if string(options.Dictionary) != "dictionary" {
+			return nil, errors.New("dictionary required")
+		}
+		return ioutil.NopCloser(reader), nil
+	},
+}
+
+func TestSignedPackageInstallerFixture(t *testing.T) {
+	gunit.Run(new(SignedPackageInstallerFixture), t)
+}
+
+type SignedPackageInstallerFixture struct {
+	*gunit.Fixture
+
+	installer  *PackageInstaller
+	storage    *FakeObjectStorage
+	filesystem *inMemoryFileSystem
+	key        ed25519.PrivateKey
+	manifest   []byte
+	request    contracts.InstallationRequest
+}
+
+func (this *SignedPackageInstallerFixture) Setup() {
+	this.storage = NewFakeObjectStorage()
+	this.filesystem = newInMemoryFileSystem()
+	this.key = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	trusted, _ := NewTrustedKeys([]string{EncodePublicKey(this.key.Public().(ed25519.PublicKey))})
+	this.installer = NewPackageInstaller(this.storage, this.filesystem, trusted, newMemoryArchiveSpool)
+	this.manifest = []byte(`{"name": "package", "version": "1.2.3"}`)
+	this.request = contracts.InstallationRequest{
+		RemoteAddress: url.URL{Scheme: "gcs", Host: "bucket", Path: "/package/1.2.3/manifest.json"},
+		LocalPath:     "local",
+	}
+	this.storage.objects[this.request.RemoteAddress.String()] = this.manifest
+}
+
+func (this *SignedPackageInstallerFixture) sign(key ed25519.PrivateKey) {
+	this.storage.objects[this.request.RemoteAddress.String()+contracts.SignatureExtension] = SignManifest(key, this.manifest)
+}
+
+func (this *SignedPackageInstallerFixture) TestSignedManifestInstalledAsSigned() {
+	this.sign(this.key)
+
+	manifest, err := this.installer.InstallManifest(this.request)
+
+	this.So(err, should.BeNil)
+	this.So(manifest, should.Resemble, contracts.Manifest{Name: "package", Version: "1.2.3"})
+	this.So(this.filesystem.readFile("local/manifest_package.json"), should.Resemble, this.manifest)
+	this.So(this.filesystem.readFile("local/manifest_package.json.sig"), should.Resemble, SignManifest(this.key, this.manifest))
+}
+
+func (this *SignedPackageInstallerFixture) TestUnsignedManifestRefused() {
+	manifest, err := this.installer.InstallManifest(this.request)
+
+	this.So(err, should.NotBeNil)
+	this.So(manifest, should.BeZeroValue)
+	this.So(this.filesystem.fileSystem, should.BeEmpty)
+}
+
+func (this *SignedPackageInstallerFixture) TestManifestSignedByUntrustedKeyRefused() {
+	this.sign(ed25519.NewKeyFromSeed([]byte(strings.Repeat("x", ed25519.SeedSize))))
+
+	_, err := this.installer.DownloadManifest(this.request.RemoteAddress)
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *SignedPackageInstallerFixture) TestVersionIndexDownloaded() {
+	address := url.URL{Scheme: "gcs", Host: "bucket", Path: "/package/versions.json"}
+	this.storage.objects[address.String()] = []byte(`{"versions": ["1.2.3", "1.2.4"]}`)
+
+	index, err := this.installer.DownloadVersionIndex(address)
+
+	this.So(err, should.BeNil)
+	this.So(index.Versions, should.Resemble, []string{"1.2.3", "1.2.4"})
+}
+
+func (this *SignedPackageInstallerFixture) TestAlteredManifestRefused() {
+	this.sign(this.key)
+	this.storage.objects[this.request.RemoteAddress.String()] = []byte(`{"name": "package", "version": "6.6.6"}`)
+
+	_, err := this.installer.DownloadManifest(this.request.RemoteAddress)
+
+	this.So(err, should.NotBeNil)
+}
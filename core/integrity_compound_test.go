@@ -48,13 +48,19 @@ func (this *CompoundIntegrityCheckFixture) TestAnyIntegrityTestsFail() {
 //////////////////////////////////////////////////////////////////////
 
 type FakeIntegrityCheck struct {
-	err       error
-	manifest  contracts.Manifest
-	localPath string
+	err        error
+	pathErrors map[string]error
+	manifest   contracts.Manifest
+	localPath  string
+	verified   []string
 }
 
 func (this *FakeIntegrityCheck) Verify(manifest contracts.Manifest, localPath string) error {
 	this.manifest = manifest
 	this.localPath = localPath
+	this.verified = append(this.verified, localPath)
+	if err, found := this.pathErrors[localPath]; found {
+		return err
+	}
 	return this.err
 }
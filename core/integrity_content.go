@@ -16,6 +16,8 @@ type FileOpenChecker interface {
 	contracts.FileChecker
 }
 
+// FileContentIntegrityCheck verifies each item with the strongest checksum recorded in the manifest
+// (calculating MD5 checksums with the hasher).
 type FileContentIntegrityCheck struct {
 	hasher     func() hash.Hash
 	fileSystem FileOpenChecker
@@ -31,11 +33,15 @@ func (this *FileContentIntegrityCheck) Verify(manifest contracts.Manifest, local
 		return nil
 	}
 	for _, item := range manifest.Archive.Contents {
-		checksum, err := this.calculateChecksum(filepath.Join(localPath, item.Path))
+		expected := StrongestChecksum(manifest.Archive.HashAlgorithm, item.MD5Checksum, item.Checksum)
+		if expected.Algorithm == "md5" {
+			expected.New = this.hasher
+		}
+		checksum, err := this.calculateChecksum(expected.New(), filepath.Join(localPath, item.Path))
 		if err != nil {
 			return err
 		}
-		if bytes.Compare(checksum, item.MD5Checksum) != 0 {
+		if bytes.Compare(checksum, expected.Expected) != 0 {
 			return fmt.Errorf("checksum mismatch for \"%s\"", item.Path)
 		}
 	}
@@ -43,8 +49,7 @@ func (this *FileContentIntegrityCheck) Verify(manifest contracts.Manifest, local
 	return nil
 }
 
-func (this *FileContentIntegrityCheck) calculateChecksum(path string) ([]byte, error) {
-	hasher := this.hasher()
+func (this *FileContentIntegrityCheck) calculateChecksum(hasher hash.Hash, path string) ([]byte, error) {
 	info, _ := this.fileSystem.Stat(path)
 	if info.Symlink() != "" {
 		_, err := io.WriteString(hasher, info.Symlink())
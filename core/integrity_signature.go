@@ -0,0 +1,48 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// SignatureIntegrityCheck verifies the signature of an installed manifest (written next to it by
+// the PackageInstaller) so that a manifest altered after installation isn't trusted.
+type SignatureIntegrityCheck struct {
+	fileSystem contracts.FileReader
+	trusted    TrustedKeys
+}
+
+func NewSignatureIntegrityCheck(fileSystem contracts.FileReader, trusted TrustedKeys) *SignatureIntegrityCheck {
+	return &SignatureIntegrityCheck{fileSystem: fileSystem, trusted: trusted}
+}
+
+func (this *SignatureIntegrityCheck) Verify(manifest contracts.Manifest, localPath string) error {
+	path := ComposeManifestPath(localPath, manifest.Name)
+	rawManifest, err := this.fileSystem.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	signature, err := this.fileSystem.ReadFile(path + contracts.SignatureExtension)
+	if err != nil {
+		return fmt.Errorf("unsigned manifest: %w", err)
+	}
+	err = this.trusted.Verify(rawManifest, signature)
+	if err != nil {
+		return err
+	}
+	var signed contracts.Manifest
+	err = json.Unmarshal(rawManifest, &signed)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(signed, manifest) {
+		return errors.New("manifest differs from the signed manifest")
+	}
+	log.Printf("Signature integrity check passed: [%s @ %s]", manifest.Name, manifest.Version)
+	return nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestSignatureIntegrityCheckFixture(t *testing.T) {
+	gunit.Run(new(SignatureIntegrityCheckFixture), t)
+}
+
+type SignatureIntegrityCheckFixture struct {
+	*gunit.Fixture
+
+	checker    *SignatureIntegrityCheck
+	fileSystem *inMemoryFileSystem
+	key        ed25519.PrivateKey
+	manifest   contracts.Manifest
+}
+
+func (this *SignatureIntegrityCheckFixture) Setup() {
+	this.fileSystem = newInMemoryFileSystem()
+	this.key = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	trusted, _ := NewTrustedKeys([]string{EncodePublicKey(this.key.Public().(ed25519.PublicKey))})
+	this.checker = NewSignatureIntegrityCheck(this.fileSystem, trusted)
+	this.manifest = contracts.Manifest{Name: "package/name", Version: "1.2.3"}
+}
+
+func (this *SignatureIntegrityCheckFixture) install(manifest contracts.Manifest) {
+	raw, _ := json.Marshal(manifest)
+	path := ComposeManifestPath("/local", manifest.Name)
+	this.fileSystem.WriteFile(path, raw)
+	this.fileSystem.WriteFile(path+contracts.SignatureExtension, SignManifest(this.key, raw))
+}
+
+func (this *SignatureIntegrityCheckFixture) TestSignedManifestIntact() {
+	this.install(this.manifest)
+
+	this.So(this.checker.Verify(this.manifest, "/local"), should.BeNil)
+}
+
+func (this *SignatureIntegrityCheckFixture) TestUnsignedManifest() {
+	raw, _ := json.Marshal(this.manifest)
+	this.fileSystem.WriteFile(ComposeManifestPath("/local", this.manifest.Name), raw)
+
+	this.So(this.checker.Verify(this.manifest, "/local"), should.NotBeNil)
+}
+
+func (this *SignatureIntegrityCheckFixture) TestAlteredManifestFile() {
+	this.install(this.manifest)
+	this.fileSystem.WriteFile(ComposeManifestPath("/local", this.manifest.Name), []byte(`{"name":"package/name","version":"6.6.6"}`))
+
+	this.So(this.checker.Verify(this.manifest, "/local"), should.NotBeNil)
+}
+
+func (this *SignatureIntegrityCheckFixture) TestManifestDiffersFromSignedManifest() {
+	this.install(this.manifest)
+	this.manifest.Version = "6.6.6"
+
+	err := this.checker.Verify(this.manifest, "/local")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.Equal, "manifest differs from the signed manifest")
+}
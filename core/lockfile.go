@@ -0,0 +1,94 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// LockPackage records the version (and digests) to which the dependency was resolved.
+func LockPackage(dependency contracts.Dependency, manifest contracts.Manifest) contracts.LockedPackage {
+	archive := manifest.Archive
+	checksum := StrongestChecksum(archive.HashAlgorithm, archive.MD5Checksum, archive.Checksum)
+	return contracts.LockedPackage{
+		PackageName:          dependency.PackageName,
+		PackageVersion:       manifest.Version,
+		LocalDirectory:       dependency.LockedLocalDirectory(),
+		ManifestSHA256:       ManifestSHA256(manifest),
+		ArchiveHashAlgorithm: checksum.Algorithm,
+		ArchiveChecksum:      checksum.Expected,
+	}
+}
+
+// ManifestSHA256 digests the manifest as satisfy understands it (re-encoded rather than as it was
+// downloaded) so that the digest of an installed manifest matches that of the remote manifest.
+func ManifestSHA256(manifest contracts.Manifest) []byte {
+	raw, _ := json.Marshal(manifest)
+	digest := sha256.Sum256(raw)
+	return digest[:]
+}
+
+// FreezeDependency pins the dependency to the version in the lockfile, refusing dependencies which
+// aren't locked or whose version (or version constraint) no longer matches the lockfile.
+func FreezeDependency(lockfile contracts.Lockfile, dependency contracts.Dependency) (contracts.Dependency, error) {
+	locked, found := lockfile.Lookup(dependency)
+	if !found {
+		return dependency, fmt.Errorf("%s is not in the lockfile", dependency.Title())
+	}
+	if !admitsLockedVersion(dependency.PackageVersion, locked.PackageVersion) {
+		return dependency, fmt.Errorf("%s is locked at version %q (the lockfile is out of date)",
+			dependency.Title(), locked.PackageVersion)
+	}
+	dependency.PackageVersion = locked.PackageVersion
+	return dependency, nil
+}
+
+func admitsLockedVersion(version, locked string) bool {
+	if version == "latest" || version == locked {
+		return true
+	}
+	if !IsVersionConstraint(version) {
+		return false
+	}
+	constraint, err := ParseVersionConstraint(version)
+	if err != nil {
+		return false
+	}
+	_, found := constraint.Highest([]string{locked})
+	return found
+}
+
+// LockfileIntegrityCheck requires each manifest (whether installed or freshly downloaded) to match
+// the manifest recorded in the lockfile for its version.
+type LockfileIntegrityCheck struct {
+	lockfile contracts.Lockfile
+}
+
+func NewLockfileIntegrityCheck(lockfile contracts.Lockfile) *LockfileIntegrityCheck {
+	return &LockfileIntegrityCheck{lockfile: lockfile}
+}
+
+func (this *LockfileIntegrityCheck) Verify(manifest contracts.Manifest, localPath string) error {
+	actual := LockPackage(contracts.Dependency{}, manifest)
+	found := false
+	for _, locked := range this.lockfile.Packages {
+		if locked.PackageName != manifest.Name || locked.PackageVersion != manifest.Version {
+			continue
+		}
+		found = true
+		if bytes.Equal(locked.ManifestSHA256, actual.ManifestSHA256) {
+			return nil
+		}
+		if locked.ArchiveHashAlgorithm == actual.ArchiveHashAlgorithm && !bytes.Equal(locked.ArchiveChecksum, actual.ArchiveChecksum) {
+			return fmt.Errorf("archive of [%s @ %s] differs from the lockfile: checksum [%x] != locked [%x] (%s)",
+				manifest.Name, manifest.Version, actual.ArchiveChecksum, locked.ArchiveChecksum, locked.ArchiveHashAlgorithm)
+		}
+	}
+	if !found {
+		return fmt.Errorf("[%s @ %s] is not in the lockfile", manifest.Name, manifest.Version)
+	}
+	return fmt.Errorf("manifest of [%s @ %s] differs from the lockfile", manifest.Name, manifest.Version)
+}
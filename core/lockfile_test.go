@@ -0,0 +1,134 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestLockfileIntegrityCheckFixture(t *testing.T) {
+	gunit.Run(new(LockfileIntegrityCheckFixture), t)
+}
+
+type LockfileIntegrityCheckFixture struct {
+	*gunit.Fixture
+
+	dependency contracts.Dependency
+	manifest   contracts.Manifest
+	lockfile   contracts.Lockfile
+	checker    *LockfileIntegrityCheck
+}
+
+func (this *LockfileIntegrityCheckFixture) Setup() {
+	this.dependency = contracts.Dependency{PackageName: "package", PackageVersion: "latest", LocalDirectory: "local"}
+	this.manifest = contracts.Manifest{
+		Name:    "package",
+		Version: "1.2.3",
+		Archive: contracts.Archive{
+			MD5Checksum:   []byte("md5"),
+			HashAlgorithm: "sha256",
+			Checksum:      []byte("sha256"),
+		},
+	}
+	this.lockfile.Lock(LockPackage(this.dependency, this.manifest))
+	this.checker = NewLockfileIntegrityCheck(this.lockfile)
+}
+
+func (this *LockfileIntegrityCheckFixture) TestLockedPackage() {
+	this.So(this.lockfile.Packages, should.HaveLength, 1)
+	locked := this.lockfile.Packages[0]
+	this.So(locked.PackageName, should.Equal, "package")
+	this.So(locked.PackageVersion, should.Equal, "1.2.3")
+	this.So(locked.LocalDirectory, should.Equal, "local")
+	this.So(locked.ManifestSHA256, should.Resemble, ManifestSHA256(this.manifest))
+	this.So(locked.ArchiveHashAlgorithm, should.Equal, "sha256")
+	this.So(locked.ArchiveChecksum, should.Resemble, []byte("sha256"))
+}
+
+func (this *LockfileIntegrityCheckFixture) TestLocalDirectoryLockedAsListed() {
+	this.dependency.LocalDirectory, this.dependency.ListedLocalDirectory = "/home/someone/local", "~/local"
+
+	locked := LockPackage(this.dependency, this.manifest)
+
+	this.So(locked.LocalDirectory, should.Equal, "~/local")
+}
+
+func (this *LockfileIntegrityCheckFixture) TestMD5ChecksumLockedForOlderManifests() {
+	this.manifest.Archive.HashAlgorithm = ""
+	this.manifest.Archive.Checksum = nil
+
+	locked := LockPackage(this.dependency, this.manifest)
+
+	this.So(locked.ArchiveHashAlgorithm, should.Equal, "md5")
+	this.So(locked.ArchiveChecksum, should.Resemble, []byte("md5"))
+}
+
+func (this *LockfileIntegrityCheckFixture) TestLatestDependencyFrozenAtLockedVersion() {
+	frozen, err := FreezeDependency(this.lockfile, this.dependency)
+
+	this.So(err, should.BeNil)
+	this.So(frozen.PackageVersion, should.Equal, "1.2.3")
+}
+
+func (this *LockfileIntegrityCheckFixture) TestSpecificDependencyMustMatchLockedVersion() {
+	this.dependency.PackageVersion = "1.2.3"
+	frozen, err := FreezeDependency(this.lockfile, this.dependency)
+	this.So(err, should.BeNil)
+	this.So(frozen, should.Resemble, this.dependency)
+
+	this.dependency.PackageVersion = "1.2.4"
+	_, err = FreezeDependency(this.lockfile, this.dependency)
+	this.So(err, should.NotBeNil)
+}
+
+func (this *LockfileIntegrityCheckFixture) TestConstrainedDependencyMustAdmitLockedVersion() {
+	this.dependency.PackageVersion = "^1.2"
+	frozen, err := FreezeDependency(this.lockfile, this.dependency)
+	this.So(err, should.BeNil)
+	this.So(frozen.PackageVersion, should.Equal, "1.2.3")
+
+	this.dependency.PackageVersion = "^1.3"
+	_, err = FreezeDependency(this.lockfile, this.dependency)
+	this.So(err, should.NotBeNil)
+}
+
+func (this *LockfileIntegrityCheckFixture) TestUnlockedDependencyNotFrozen() {
+	this.dependency.LocalDirectory = "elsewhere"
+
+	_, err := FreezeDependency(this.lockfile, this.dependency)
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *LockfileIntegrityCheckFixture) TestLockedManifestPasses() {
+	this.So(this.checker.Verify(this.manifest, "anywhere"), should.BeNil)
+}
+
+func (this *LockfileIntegrityCheckFixture) TestUnlockedVersionFails() {
+	this.manifest.Version = "1.2.4"
+
+	err := this.checker.Verify(this.manifest, "anywhere")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.Equal, "[package @ 1.2.4] is not in the lockfile")
+}
+
+func (this *LockfileIntegrityCheckFixture) TestAlteredArchiveFails() {
+	this.manifest.Archive.Checksum = []byte("altered")
+
+	err := this.checker.Verify(this.manifest, "anywhere")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.StartWith, "archive of [package @ 1.2.3] differs from the lockfile")
+}
+
+func (this *LockfileIntegrityCheckFixture) TestAlteredManifestFails() {
+	this.manifest.Archive.Contents = []contracts.ArchiveItem{{Path: "extra"}}
+
+	err := this.checker.Verify(this.manifest, "anywhere")
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.Equal, "manifest of [package @ 1.2.3] differs from the lockfile")
+}
@@ -18,6 +18,7 @@ type inMemoryFileSystem struct {
 	Root         string
 	errReadFile  map[string]error
 	errChmodFile map[string]error
+	errRename    map[string]error
 }
 
 func newInMemoryFileSystem() *inMemoryFileSystem {
@@ -25,6 +26,7 @@ func newInMemoryFileSystem() *inMemoryFileSystem {
 		fileSystem:   make(map[string]*file),
 		errReadFile:  make(map[string]error),
 		errChmodFile: make(map[string]error),
+		errRename:    make(map[string]error),
 	}
 }
 
@@ -115,6 +117,52 @@ func (this *inMemoryFileSystem) Delete(path string) {
 	delete(this.fileSystem, path)
 }
 
+func (this *inMemoryFileSystem) DeleteAll(path string) {
+	for key := range this.fileSystem {
+		if key == path || strings.HasPrefix(key, path+"/") {
+			delete(this.fileSystem, key)
+		}
+	}
+}
+
+func (this *inMemoryFileSystem) Rename(source, target string) error {
+	if err := this.errRename[source]; err != nil {
+		return err
+	}
+	var moved []*file
+	for key, item := range this.fileSystem {
+		if key == source || strings.HasPrefix(key, source+"/") {
+			delete(this.fileSystem, key)
+			moved = append(moved, item)
+		}
+	}
+	if len(moved) == 0 {
+		return os.ErrNotExist
+	}
+	for _, item := range moved {
+		item.path = target + strings.TrimPrefix(item.path, source)
+		this.fileSystem[item.path] = item
+	}
+	return nil
+}
+
+func (this *inMemoryFileSystem) ReadDirectory(path string) (names []string, err error) {
+	unique := make(map[string]struct{})
+	for key := range this.fileSystem {
+		if strings.HasPrefix(key, path+"/") {
+			unique[strings.Split(strings.TrimPrefix(key, path+"/"), "/")[0]] = struct{}{}
+		}
+	}
+	if len(unique) == 0 {
+		return nil, os.ErrNotExist
+	}
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func (this *inMemoryFileSystem) RootPath() string {
 	return this.Root
 }
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// MirrorDownloader fails over to the mirrors of a dependency when its primary remote address
+// is unavailable. Mirrors share the layout of the primary (including its archive pool), so each
+// address is rewritten by swapping one remote address prefix for another. Whatever is served by
+// a mirror is still verified against the checksums of the downloaded manifest.
+type MirrorDownloader struct {
+	inner  contracts.Downloader
+	chains []mirrorChain
+}
+
+type mirrorChain struct {
+	primary url.URL
+	mirrors []url.URL
+}
+
+func NewMirrorDownloader(inner contracts.Downloader, listing contracts.DependencyListing) *MirrorDownloader {
+	this := &MirrorDownloader{inner: inner}
+	for _, dependency := range listing.Listing {
+		this.register(dependency.RemoteAddress, dependency.Mirrors)
+		this.register(dependency.RemoteAddress, listing.Mirrors)
+	}
+	return this
+}
+
+// register merges the mirrors of dependencies which share a remote address prefix.
+func (this *MirrorDownloader) register(primary contracts.URL, mirrors []contracts.URL) {
+	if len(mirrors) == 0 {
+		return
+	}
+	root := mirrorRoot(primary)
+	index := -1
+	for i, chain := range this.chains {
+		if chain.primary == root {
+			index = i
+		}
+	}
+	if index < 0 {
+		index = len(this.chains)
+		this.chains = append(this.chains, mirrorChain{primary: root})
+	}
+	for _, mirror := range mirrors {
+		if !containsMirror(this.chains[index].mirrors, mirrorRoot(mirror)) {
+			this.chains[index].mirrors = append(this.chains[index].mirrors, mirrorRoot(mirror))
+		}
+	}
+}
+
+func (this *MirrorDownloader) Download(request url.URL) (io.ReadCloser, error) {
+	return this.download(request, this.inner.Download)
+}
+
+func (this *MirrorDownloader) DownloadRange(request url.URL, offset, length int64) (io.ReadCloser, error) {
+	ranged, ok := this.inner.(contracts.RangeDownloader)
+	if !ok {
+		return nil, fmt.Errorf("%w (%s)", contracts.RangeUnsupportedErr, request.String())
+	}
+	return this.download(request, func(address url.URL) (io.ReadCloser, error) {
+		return ranged.DownloadRange(address, offset, length)
+	})
+}
+
+func (this *MirrorDownloader) download(request url.URL, download func(url.URL) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	body, err := download(request)
+	if err == nil || !isFailoverError(err) {
+		return body, err
+	}
+
+	chain, relative, found := this.resolve(request)
+	if !found {
+		return nil, err
+	}
+	for _, mirror := range chain.mirrors {
+		address := mirror
+		address.Path = path.Join(mirror.Path, relative)
+		log.Printf("[WARN] %s unavailable (%s), failing over to mirror: %s", request.String(), err, address.String())
+
+		body, err = download(address)
+		if err == nil {
+			log.Printf("Downloaded from mirror: %s", address.String())
+			return body, nil
+		}
+		if !isFailoverError(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// resolve finds the chain with the longest primary prefix containing the requested address.
+func (this *MirrorDownloader) resolve(request url.URL) (resolved mirrorChain, relative string, found bool) {
+	for _, chain := range this.chains {
+		if chain.primary.Scheme != request.Scheme || chain.primary.Host != request.Host {
+			continue
+		}
+		prefix := strings.TrimSuffix(chain.primary.Path, "/")
+		if !strings.HasPrefix(request.Path, prefix+"/") {
+			continue
+		}
+		if !found || len(chain.primary.Path) > len(resolved.primary.Path) {
+			resolved, relative, found = chain, strings.TrimPrefix(request.Path, prefix), true
+		}
+	}
+	return resolved, relative, found
+}
+
+func mirrorRoot(address contracts.URL) url.URL {
+	root := url.URL(address)
+	root.Path = path.Join("/", root.Path)
+	return root
+}
+
+func containsMirror(mirrors []url.URL, mirror url.URL) bool {
+	for _, existing := range mirrors {
+		if existing == mirror {
+			return true
+		}
+	}
+	return false
+}
+
+func isFailoverError(err error) bool {
+	if errors.Is(err, contracts.RetryErr) {
+		return true
+	}
+	var statusErr *contracts.StatusCodeError
+	return errors.As(err, &statusErr) && statusErr.StatusCode() >= http.StatusInternalServerError
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestMirrorDownloaderFixture(t *testing.T) {
+	gunit.Run(new(MirrorDownloaderFixture), t)
+}
+
+type MirrorDownloaderFixture struct {
+	*gunit.Fixture
+
+	inner      *FakeMirroredStorage
+	downloader *MirrorDownloader
+}
+
+func (this *MirrorDownloaderFixture) Setup() {
+	this.inner = &FakeMirroredStorage{
+		content: make(map[string]string),
+		errors:  make(map[string]error),
+	}
+	this.downloader = NewMirrorDownloader(this.inner, contracts.DependencyListing{
+		Listing: []contracts.Dependency{
+			{
+				PackageName:   "package",
+				RemoteAddress: contracts.URL{Scheme: "gcs", Host: "primary", Path: "/prefix"},
+				Mirrors: []contracts.URL{
+					{Scheme: "s3", Host: "mirror-1", Path: "/mirrored"},
+				},
+			},
+			{
+				PackageName:   "unmirrored",
+				RemoteAddress: contracts.URL{Scheme: "gcs", Host: "elsewhere"},
+			},
+		},
+		Mirrors: []contracts.URL{
+			{Scheme: "https", Host: "mirror-2"},
+		},
+	})
+}
+
+func (this *MirrorDownloaderFixture) TestPrimaryServesDownload() {
+	this.inner.content["gcs://primary/prefix/package/1.2.3/archive"] = "primary"
+
+	body, err := this.downloader.Download(this.address("gcs://primary/prefix/package/1.2.3/archive"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "primary")
+	this.So(this.inner.requests, should.HaveLength, 1)
+}
+
+func (this *MirrorDownloaderFixture) TestFailoverToDependencyMirror() {
+	this.inner.errors["gcs://primary/prefix/package/1.2.3/archive"] = aRetryError
+	this.inner.content["s3://mirror-1/mirrored/package/1.2.3/archive"] = "mirror-1"
+
+	body, err := this.downloader.Download(this.address("gcs://primary/prefix/package/1.2.3/archive"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "mirror-1")
+	this.So(this.inner.requests, should.Resemble, []string{
+		"gcs://primary/prefix/package/1.2.3/archive",
+		"s3://mirror-1/mirrored/package/1.2.3/archive",
+	})
+}
+
+func (this *MirrorDownloaderFixture) TestFailoverToGlobalMirrorOnServerError() {
+	this.inner.errors["gcs://primary/prefix/package/manifest.json"] = this.statusCodeError(http.StatusServiceUnavailable)
+	this.inner.errors["s3://mirror-1/mirrored/package/manifest.json"] = this.statusCodeError(http.StatusBadGateway)
+	this.inner.content["https://mirror-2/package/manifest.json"] = "mirror-2"
+
+	body, err := this.downloader.Download(this.address("gcs://primary/prefix/package/manifest.json"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "mirror-2")
+	this.So(this.inner.requests, should.HaveLength, 3)
+}
+
+func (this *MirrorDownloaderFixture) TestAllMirrorsUnavailable() {
+	this.inner.errors["gcs://primary/prefix/package/1.2.3/archive"] = aRetryError
+	this.inner.errors["s3://mirror-1/mirrored/package/1.2.3/archive"] = aRetryError
+	this.inner.errors["https://mirror-2/package/1.2.3/archive"] = aRetryError
+
+	body, err := this.downloader.Download(this.address("gcs://primary/prefix/package/1.2.3/archive"))
+
+	this.So(body, should.BeNil)
+	this.So(err, should.Equal, aRetryError)
+	this.So(this.inner.requests, should.HaveLength, 3)
+}
+
+func (this *MirrorDownloaderFixture) TestNoFailoverOnRegularError() {
+	this.inner.errors["gcs://primary/prefix/package/1.2.3/archive"] = this.statusCodeError(http.StatusNotFound)
+
+	body, err := this.downloader.Download(this.address("gcs://primary/prefix/package/1.2.3/archive"))
+
+	this.So(body, should.BeNil)
+	this.So(err, should.NotBeNil)
+	this.So(this.inner.requests, should.HaveLength, 1)
+}
+
+func (this *MirrorDownloaderFixture) TestMirrorFailureWithRegularErrorStopsFailover() {
+	this.inner.errors["gcs://primary/prefix/package/1.2.3/archive"] = aRetryError
+	this.inner.errors["s3://mirror-1/mirrored/package/1.2.3/archive"] = aRegularError
+
+	body, err := this.downloader.Download(this.address("gcs://primary/prefix/package/1.2.3/archive"))
+
+	this.So(body, should.BeNil)
+	this.So(err, should.Equal, aRegularError)
+	this.So(this.inner.requests, should.HaveLength, 2)
+}
+
+func (this *MirrorDownloaderFixture) TestGlobalMirrorAppliesToOtherDependencies() {
+	this.inner.errors["gcs://elsewhere/unmirrored/1.2.3/archive"] = aRetryError
+	this.inner.content["https://mirror-2/unmirrored/1.2.3/archive"] = "mirror-2"
+
+	body, err := this.downloader.Download(this.address("gcs://elsewhere/unmirrored/1.2.3/archive"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "mirror-2")
+}
+
+func (this *MirrorDownloaderFixture) TestPooledArchiveFailover() {
+	this.inner.errors["gcs://primary/prefix/pool/0123abcd"] = aRetryError
+	this.inner.content["s3://mirror-1/mirrored/pool/0123abcd"] = "mirror-1"
+
+	body, err := this.downloader.Download(this.address("gcs://primary/prefix/pool/0123abcd"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "mirror-1")
+}
+
+func (this *MirrorDownloaderFixture) TestMirrorsMergedForSharedRemoteAddress() {
+	downloader := NewMirrorDownloader(this.inner, contracts.DependencyListing{
+		Listing: []contracts.Dependency{
+			{
+				PackageName:   "package-1",
+				RemoteAddress: contracts.URL{Scheme: "gcs", Host: "primary"},
+				Mirrors:       []contracts.URL{{Scheme: "s3", Host: "mirror-1"}},
+			},
+			{
+				PackageName:   "package-2",
+				RemoteAddress: contracts.URL{Scheme: "gcs", Host: "primary"},
+				Mirrors:       []contracts.URL{{Scheme: "s3", Host: "mirror-2"}, {Scheme: "s3", Host: "mirror-1"}},
+			},
+		},
+	})
+	this.inner.errors["gcs://primary/package-2/archive"] = aRetryError
+	this.inner.errors["s3://mirror-1/package-2/archive"] = aRetryError
+	this.inner.content["s3://mirror-2/package-2/archive"] = "mirror-2"
+
+	body, err := downloader.Download(this.address("gcs://primary/package-2/archive"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "mirror-2")
+	this.So(this.inner.requests, should.HaveLength, 3)
+}
+
+func (this *MirrorDownloaderFixture) TestUnknownAddressNotMirrored() {
+	this.inner.errors["gcs://primary/other/package/1.2.3/archive"] = aRetryError
+
+	body, err := this.downloader.Download(this.address("gcs://primary/other/package/1.2.3/archive"))
+
+	this.So(body, should.BeNil)
+	this.So(err, should.Equal, aRetryError)
+	this.So(this.inner.requests, should.HaveLength, 1)
+}
+
+func (this *MirrorDownloaderFixture) TestRangeFailoverToMirror() {
+	this.inner.errors["gcs://primary/prefix/package/1.2.3/archive"] = aRetryError
+	this.inner.content["s3://mirror-1/mirrored/package/1.2.3/archive"] = "0123456789"
+
+	body, err := this.downloader.DownloadRange(this.address("gcs://primary/prefix/package/1.2.3/archive"), 2, 3)
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "234")
+	this.So(this.inner.requests, should.Resemble, []string{
+		"gcs://primary/prefix/package/1.2.3/archive",
+		"s3://mirror-1/mirrored/package/1.2.3/archive",
+	})
+}
+
+func (this *MirrorDownloaderFixture) TestRangeNotSupportedByInner() {
+	downloader := NewMirrorDownloader(&FakeClient{}, contracts.DependencyListing{})
+
+	body, err := downloader.DownloadRange(this.address("gcs://primary/prefix/package/1.2.3/archive"), 2, 3)
+
+	this.So(body, should.BeNil)
+	this.So(errors.Is(err, contracts.RangeUnsupportedErr), should.BeTrue)
+}
+
+func (this *MirrorDownloaderFixture) address(raw string) url.URL {
+	address, err := url.Parse(raw)
+	this.So(err, should.BeNil)
+	return *address
+}
+
+func (this *MirrorDownloaderFixture) statusCodeError(statusCode int) error {
+	return contracts.NewStatusCodeError(statusCode, http.StatusOK, url.URL{})
+}
+
+func (this *MirrorDownloaderFixture) readAll(body io.Reader) string {
+	raw, _ := ioutil.ReadAll(body)
+	return string(raw)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+type FakeMirroredStorage struct {
+	content  map[string]string
+	errors   map[string]error
+	requests []string
+}
+
+func (this *FakeMirroredStorage) Download(request url.URL) (io.ReadCloser, error) {
+	address := request.String()
+	this.requests = append(this.requests, address)
+	if err := this.errors[address]; err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(strings.NewReader(this.content[address])), nil
+}
+
+func (this *FakeMirroredStorage) DownloadRange(request url.URL, offset, length int64) (io.ReadCloser, error) {
+	body, err := this.Download(request)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := ioutil.ReadAll(body)
+	return ioutil.NopCloser(bytes.NewReader(raw[offset : offset+length])), nil
+}
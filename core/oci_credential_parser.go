@@ -0,0 +1,30 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+type OCICredentialParser struct {
+	environment contracts.Environment
+}
+
+func NewOCICredentialParser(environment contracts.Environment) OCICredentialParser {
+	return OCICredentialParser{environment: environment}
+}
+
+// Parse reads the optional registry credentials. Anonymous access is not an error
+// because many registries (and local stand-ins) permit anonymous pulls.
+func (this OCICredentialParser) Parse() (credentials contracts.OCICredentials) {
+	credentials.Username = this.lookup("SATISFY_OCI_USERNAME")
+	credentials.Password = this.lookup("SATISFY_OCI_PASSWORD")
+	credentials.PlainHTTP, _ = strconv.ParseBool(this.lookup("SATISFY_OCI_PLAIN_HTTP"))
+	return credentials
+}
+
+func (this OCICredentialParser) lookup(key string) string {
+	value, _ := this.environment.LookupEnv(key)
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestOCICredentialParserFixture(t *testing.T) {
+	gunit.Run(new(OCICredentialParserFixture), t)
+}
+
+type OCICredentialParserFixture struct {
+	*gunit.Fixture
+
+	parser      OCICredentialParser
+	environment FakeEnvironment
+}
+
+func (this *OCICredentialParserFixture) Setup() {
+	this.environment = make(FakeEnvironment)
+	this.parser = NewOCICredentialParser(this.environment)
+}
+
+func (this *OCICredentialParserFixture) TestNoCredentials() {
+	this.So(this.parser.Parse(), should.BeZeroValue)
+}
+
+func (this *OCICredentialParserFixture) TestCredentials() {
+	this.environment["SATISFY_OCI_USERNAME"] = "username"
+	this.environment["SATISFY_OCI_PASSWORD"] = "password"
+	this.environment["SATISFY_OCI_PLAIN_HTTP"] = "true"
+
+	this.So(this.parser.Parse(), should.Resemble, contracts.OCICredentials{
+		Username:  "username",
+		Password:  "password",
+		PlainHTTP: true,
+	})
+}
+
+func (this *OCICredentialParserFixture) TestMalformedPlainHTTPIgnored() {
+	this.environment["SATISFY_OCI_PLAIN_HTTP"] = "maybe"
+
+	this.So(this.parser.Parse().PlainHTTP, should.BeFalse)
+}
@@ -0,0 +1,304 @@
+package core
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// MirrorSpool holds a downloaded archive while it is verified and then uploaded again.
+type MirrorSpool interface {
+	io.ReadWriteSeeker
+	io.Closer
+}
+
+// PackageMirror copies published packages (the archive, the versioned manifest and the 'latest'
+// manifest) from one remote address prefix to another. Archives are verified against the
+// checksum in their manifest and objects already present on the target are left alone.
+//
+// The source and target storage may differ in their credentials (e.g. anonymous downloads from a public
+// bucket which is mirrored to a private bucket).
+type PackageMirror struct {
+	sourceStorage contracts.RemoteStorage
+	targetStorage contracts.RemoteStorage
+	source        url.URL
+	target        url.URL
+	newSpool      func() (MirrorSpool, error)
+}
+
+func NewPackageMirror(sourceStorage, targetStorage contracts.RemoteStorage, source, target url.URL, newSpool func() (MirrorSpool, error)) *PackageMirror {
+	return &PackageMirror{
+		sourceStorage: sourceStorage,
+		targetStorage: targetStorage,
+		source:        source,
+		target:        target,
+		newSpool:      newSpool,
+	}
+}
+
+// MirrorVersion copies a single version of the package, where 'latest' refers to the version
+// named by the latest manifest of the source. The version actually copied is returned.
+func (this *PackageMirror) MirrorVersion(packageName, version string) (string, error) {
+	source := this.dependency(this.source, packageName, version)
+	rawManifest, manifest, err := this.downloadManifest(this.sourceStorage, source.ComposeRemoteManifestAddress())
+	if err != nil {
+		return "", fmt.Errorf("failed to download manifest for %s: %w", source.Title(), err)
+	}
+	if version != "latest" && manifest.Version != version {
+		return "", fmt.Errorf("manifest for %s describes version %q", source.Title(), manifest.Version)
+	}
+	source.PackageVersion = manifest.Version
+	target := this.dependency(this.target, packageName, manifest.Version)
+
+	rawExisting, existing, err := this.downloadManifest(this.targetStorage, target.ComposeRemoteManifestAddress())
+	if err != nil && !isNotFound(err) {
+		return "", fmt.Errorf("failed to check mirrored manifest for %s: %w", target.Title(), err)
+	}
+
+	if err == nil && bytes.Equal(existing.Archive.MD5Checksum, manifest.Archive.MD5Checksum) &&
+		bytes.Equal(existing.Archive.Checksum, manifest.Archive.Checksum) {
+		log.Printf("Archive already mirrored: %s", target.Title())
+	} else if err = this.copyArchive(source, target, manifest); err != nil {
+		return "", fmt.Errorf("failed to mirror archive for %s: %w", source.Title(), err)
+	}
+	if err = this.copyDictionary(source, target, manifest.Archive); err != nil {
+		return "", fmt.Errorf("failed to mirror compression dictionary for %s: %w", source.Title(), err)
+	}
+
+	if bytes.Equal(rawExisting, rawManifest) {
+		log.Printf("Manifest already mirrored: %s", target.Title())
+	} else {
+		log.Printf("Uploading manifest for %s", target.Title())
+		err = this.uploadManifest(source.ComposeRemoteManifestAddress(), target.ComposeRemoteManifestAddress(), rawManifest)
+		if err != nil {
+			return "", fmt.Errorf("failed to mirror manifest for %s: %w", source.Title(), err)
+		}
+	}
+	if err = this.indexVersion(target); err != nil {
+		return "", fmt.Errorf("failed to update the versions index for %s: %w", target.Title(), err)
+	}
+	return manifest.Version, nil
+}
+
+// MirrorLatest points the 'latest' manifest of the target at the latest version of the source,
+// provided that version has already been mirrored.
+func (this *PackageMirror) MirrorLatest(packageName string) error {
+	source := this.dependency(this.source, packageName, "latest")
+	rawLatest, latest, err := this.downloadManifest(this.sourceStorage, source.ComposeLatestManifestRemoteAddress())
+	if err != nil {
+		return fmt.Errorf("failed to download latest manifest for %s: %w", source.Title(), err)
+	}
+
+	target := this.dependency(this.target, packageName, latest.Version)
+	_, _, err = this.downloadManifest(this.targetStorage, target.ComposeRemoteManifestAddress())
+	if isNotFound(err) {
+		log.Printf("[WARN] Latest version not mirrored, leaving 'latest' unchanged: %s", target.Title())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check mirrored manifest for %s: %w", target.Title(), err)
+	}
+
+	rawExisting, _, err := this.downloadManifest(this.targetStorage, target.ComposeLatestManifestRemoteAddress())
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to check mirrored latest manifest for %s: %w", target.Title(), err)
+	}
+	if bytes.Equal(rawExisting, rawLatest) {
+		log.Printf("Latest manifest already mirrored: %s", target.Title())
+		return nil
+	}
+	log.Printf("Uploading latest manifest for %s", target.Title())
+	return this.uploadManifest(source.ComposeLatestManifestRemoteAddress(), target.ComposeLatestManifestRemoteAddress(), rawLatest)
+}
+
+func (this *PackageMirror) copyArchive(source, target contracts.Dependency, manifest contracts.Manifest) error {
+	if manifest.Archive.Location != "" {
+		body, err := this.targetStorage.Download(target.ComposeArchiveAddress(manifest.Archive))
+		if err == nil {
+			closeResource(body)
+			log.Printf("Archive already exists in the pool: %s", target.Title())
+			return nil
+		}
+		if !isNotFound(err) {
+			return err
+		}
+	}
+
+	log.Printf("Downloading archive for %s", source.Title())
+	body, err := this.sourceStorage.Download(source.ComposeArchiveAddress(manifest.Archive))
+	if err != nil {
+		return err
+	}
+	defer closeResource(body)
+
+	spool, err := this.newSpool()
+	if err != nil {
+		return err
+	}
+	defer closeResource(spool)
+
+	archive := manifest.Archive
+	checksum := StrongestChecksum(archive.HashAlgorithm, archive.MD5Checksum, archive.Checksum)
+	hasher := checksum.New()
+	size, err := io.Copy(spool, NewHashReader(body, hasher))
+	if err != nil {
+		return err
+	}
+	if uint64(size) != archive.Size {
+		return fmt.Errorf("size mismatch: actual [%d] != expected [%d]", size, archive.Size)
+	}
+	if err = checksum.Verify(hasher.Sum(nil)); err != nil {
+		return err
+	}
+	_, err = spool.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/octet-stream"
+	if codec, found := LookupCodec(manifest.Archive.CompressionAlgorithm); found {
+		contentType = codec.ContentType // as labelled when uploaded
+	}
+
+	log.Printf("Uploading archive for %s", target.Title())
+	return this.targetStorage.Upload(contracts.UploadRequest{
+		RemoteAddress: target.ComposeArchiveAddress(manifest.Archive),
+		Body:          RewindOnClose{ReadSeeker: spool},
+		Size:          size,
+		ContentType:   contentType,
+		Checksum:      manifest.Archive.MD5Checksum,
+	})
+}
+
+func (this *PackageMirror) copyDictionary(source, target contracts.Dependency, archive contracts.Archive) error {
+	dictionary := archive.Dictionary
+	if dictionary == nil {
+		return nil
+	}
+	body, err := this.targetStorage.Download(target.ComposeDictionaryAddress(*dictionary))
+	if err == nil {
+		closeResource(body)
+		return nil
+	}
+	if !isNotFound(err) {
+		return err
+	}
+
+	body, err = this.sourceStorage.Download(source.ComposeDictionaryAddress(*dictionary))
+	if err != nil {
+		return err
+	}
+	defer closeResource(body)
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	checksum := StrongestChecksum(archive.HashAlgorithm, dictionary.MD5Checksum, dictionary.Checksum)
+	hasher := checksum.New()
+	_, _ = hasher.Write(raw)
+	if err = checksum.Verify(hasher.Sum(nil)); err != nil {
+		return err
+	}
+
+	log.Printf("Uploading compression dictionary for %s", target.Title())
+	return this.targetStorage.Upload(contracts.UploadRequest{
+		RemoteAddress: target.ComposeDictionaryAddress(*dictionary),
+		Body:          RewindOnClose{ReadSeeker: bytes.NewReader(raw)},
+		Size:          int64(len(raw)),
+		ContentType:   "application/octet-stream",
+		Checksum:      dictionary.MD5Checksum,
+	})
+}
+
+// indexVersion adds the mirrored version to the versions index of the target so that version
+// constraints may be resolved against the mirror.
+func (this *PackageMirror) indexVersion(target contracts.Dependency) error {
+	address := target.ComposeVersionIndexRemoteAddress()
+	var index contracts.VersionIndex
+	body, err := this.targetStorage.Download(address)
+	if err == nil {
+		err = json.NewDecoder(body).Decode(&index)
+		closeResource(body)
+	}
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	if !index.Add(target.PackageVersion) {
+		return nil
+	}
+	raw, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	log.Printf("Updating the versions index for %s", target.Title())
+	return this.uploadJSON(address, raw)
+}
+
+func (this *PackageMirror) downloadManifest(storage contracts.RemoteStorage, address url.URL) (raw []byte, manifest contracts.Manifest, err error) {
+	body, err := storage.Download(address)
+	if err != nil {
+		return nil, contracts.Manifest{}, err
+	}
+	defer closeResource(body)
+
+	raw, err = ioutil.ReadAll(body)
+	if err != nil {
+		return nil, contracts.Manifest{}, err
+	}
+	err = json.Unmarshal(raw, &manifest)
+	if err != nil {
+		return nil, contracts.Manifest{}, fmt.Errorf("malformed manifest at %s: %w", address.String(), err)
+	}
+	return raw, manifest, nil
+}
+
+// uploadManifest copies the signature of a signed manifest ahead of the manifest itself.
+func (this *PackageMirror) uploadManifest(source, target url.URL, raw []byte) error {
+	body, err := this.sourceStorage.Download(contracts.ComposeSignatureAddress(source))
+	if err == nil {
+		signature, err := ioutil.ReadAll(body)
+		closeResource(body)
+		if err == nil {
+			err = this.uploadJSON(contracts.ComposeSignatureAddress(target), signature)
+		}
+		if err != nil {
+			return err
+		}
+	} else if !isNotFound(err) {
+		return err
+	}
+	return this.uploadJSON(target, raw)
+}
+
+func (this *PackageMirror) uploadJSON(address url.URL, raw []byte) error {
+	checksum := md5.Sum(raw)
+	return this.targetStorage.Upload(contracts.UploadRequest{
+		RemoteAddress: address,
+		Body:          RewindOnClose{ReadSeeker: bytes.NewReader(raw)},
+		Size:          int64(len(raw)),
+		ContentType:   "application/json",
+		Checksum:      checksum[:],
+	})
+}
+
+func (this *PackageMirror) dependency(prefix url.URL, packageName, version string) contracts.Dependency {
+	return contracts.Dependency{
+		PackageName:    packageName,
+		PackageVersion: version,
+		RemoteAddress:  contracts.URL(prefix),
+	}
+}
+
+func isNotFound(err error) bool {
+	var statusErr *contracts.StatusCodeError
+	return errors.As(err, &statusErr) && statusErr.StatusCode() == http.StatusNotFound
+}
@@ -0,0 +1,412 @@
+package core
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestPackageMirrorFixture(t *testing.T) {
+	gunit.Run(new(PackageMirrorFixture), t)
+}
+
+type PackageMirrorFixture struct {
+	*gunit.Fixture
+
+	storage  *FakeObjectStorage
+	spools   []*FakeSpool
+	mirror   *PackageMirror
+	archive  []byte
+	manifest []byte
+}
+
+func (this *PackageMirrorFixture) Setup() {
+	this.storage = NewFakeObjectStorage()
+	this.mirror = this.newMirror(this.storage, this.storage)
+	this.archive = []byte("archive contents")
+	this.manifest = this.publish("gcs://source/packages/package/1.2.3", "1.2.3", this.archive)
+	this.storage.objects["gcs://source/packages/package/manifest.json"] = this.manifest
+}
+
+func (this *PackageMirrorFixture) newMirror(source, target contracts.RemoteStorage) *PackageMirror {
+	return NewPackageMirror(source, target,
+		url.URL{Scheme: "gcs", Host: "source", Path: "/packages"},
+		url.URL{Scheme: "file", Path: "/srv/mirror"},
+		func() (MirrorSpool, error) {
+			spool := new(FakeSpool)
+			this.spools = append(this.spools, spool)
+			return spool, nil
+		},
+	)
+}
+
+func (this *PackageMirrorFixture) publish(prefix, version string, archive []byte) []byte {
+	checksum := md5.Sum(archive)
+	manifest, _ := json.Marshal(contracts.Manifest{
+		Name:    "package",
+		Version: version,
+		Archive: contracts.Archive{
+			Filename:    contracts.RemoteArchiveFilename,
+			Size:        uint64(len(archive)),
+			MD5Checksum: checksum[:],
+		},
+	})
+	this.storage.objects[prefix+"/archive"] = archive
+	this.storage.objects[prefix+"/manifest.json"] = manifest
+	index, _ := json.Marshal(contracts.VersionIndex{Versions: []string{version}})
+	this.storage.objects[strings.TrimSuffix(prefix, version)+"versions.json"] = index
+	return manifest
+}
+
+// pool moves the published archive into the pool beneath the prefix.
+func (this *PackageMirrorFixture) pool(prefix string) {
+	var manifest contracts.Manifest
+	_ = json.Unmarshal(this.manifest, &manifest)
+	manifest.Archive.Location = contracts.ComposeArchivePoolLocation(manifest.Archive.MD5Checksum)
+	this.manifest, _ = json.Marshal(manifest)
+	delete(this.storage.objects, prefix+"/package/1.2.3/archive")
+	this.storage.objects[prefix+"/"+manifest.Archive.Location] = this.archive
+	this.storage.objects[prefix+"/package/1.2.3/manifest.json"] = this.manifest
+}
+
+func (this *PackageMirrorFixture) poolName() string {
+	checksum := md5.Sum(this.archive)
+	return hex.EncodeToString(checksum[:])
+}
+
+func (this *PackageMirrorFixture) TestVersionMirrored() {
+	version, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "1.2.3")
+	this.So(this.storage.objects["file:///srv/mirror/package/1.2.3/archive"], should.Resemble, this.archive)
+	this.So(this.storage.objects["file:///srv/mirror/package/1.2.3/manifest.json"], should.Resemble, this.manifest)
+	this.So(this.storage.uploads, should.Resemble, []string{
+		"file:///srv/mirror/package/1.2.3/archive",
+		"file:///srv/mirror/package/1.2.3/manifest.json",
+		"file:///srv/mirror/package/versions.json",
+	})
+	this.So(this.spools, should.HaveLength, 1)
+	this.So(this.spools[0].closed, should.BeTrue)
+}
+
+func (this *PackageMirrorFixture) TestSourceAndTargetStorageKeptApart() {
+	target := NewFakeObjectStorage()
+	this.mirror = this.newMirror(this.storage, target)
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.uploads, should.BeEmpty)
+	this.So(target.objects["file:///srv/mirror/package/1.2.3/archive"], should.Resemble, this.archive)
+	this.So(target.contentTypes["file:///srv/mirror/package/1.2.3/archive"], should.Equal, "application/octet-stream")
+	this.So(target.downloads, should.NotBeEmpty)
+	for _, address := range target.downloads {
+		this.So(address, should.StartWith, "file:///srv/mirror/")
+	}
+}
+
+func (this *PackageMirrorFixture) TestArchiveLabelledWithContentTypeOfItsCodec() {
+	var manifest contracts.Manifest
+	_ = json.Unmarshal(this.manifest, &manifest)
+	manifest.Archive.CompressionAlgorithm = "gzip"
+	this.storage.objects["gcs://source/packages/package/1.2.3/manifest.json"], _ = json.Marshal(manifest)
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.contentTypes["file:///srv/mirror/package/1.2.3/archive"], should.Equal, "application/gzip")
+}
+
+func (this *PackageMirrorFixture) TestSignatureMirroredAheadOfManifest() {
+	this.storage.objects["gcs://source/packages/package/1.2.3/manifest.json.sig"] = []byte("signature")
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.objects["file:///srv/mirror/package/1.2.3/manifest.json.sig"], should.Resemble, []byte("signature"))
+	this.So(this.storage.uploads, should.Resemble, []string{
+		"file:///srv/mirror/package/1.2.3/archive",
+		"file:///srv/mirror/package/1.2.3/manifest.json.sig",
+		"file:///srv/mirror/package/1.2.3/manifest.json",
+		"file:///srv/mirror/package/versions.json",
+	})
+}
+
+func (this *PackageMirrorFixture) TestLatestVersionResolvedFromSource() {
+	version, err := this.mirror.MirrorVersion("package", "latest")
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "1.2.3")
+	this.So(this.storage.objects["file:///srv/mirror/package/1.2.3/manifest.json"], should.Resemble, this.manifest)
+}
+
+func (this *PackageMirrorFixture) TestCorruptArchiveNotMirrored() {
+	this.storage.objects["gcs://source/packages/package/1.2.3/archive"] = []byte("archive CONTENTS")
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.storage.uploads, should.BeEmpty)
+}
+
+func (this *PackageMirrorFixture) TestTruncatedArchiveNotMirrored() {
+	this.storage.objects["gcs://source/packages/package/1.2.3/archive"] = this.archive[:4]
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.storage.uploads, should.BeEmpty)
+}
+
+func (this *PackageMirrorFixture) TestMissingSourceManifest() {
+	_, err := this.mirror.MirrorVersion("package", "9.9.9")
+
+	this.So(isNotFound(err), should.BeTrue)
+	this.So(this.storage.uploads, should.BeEmpty)
+}
+
+func (this *PackageMirrorFixture) TestManifestVersionMismatch() {
+	this.storage.objects["gcs://source/packages/package/1.2.4/manifest.json"] = this.manifest
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.4")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.storage.uploads, should.BeEmpty)
+}
+
+func (this *PackageMirrorFixture) TestAlreadyMirroredVersionSkipped() {
+	this.publish("file:///srv/mirror/package/1.2.3", "1.2.3", this.archive)
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.uploads, should.BeEmpty)
+	this.So(this.spools, should.BeEmpty)
+}
+
+func (this *PackageMirrorFixture) TestMatchingArchiveSkippedButDifferentManifestReplaced() {
+	this.publish("file:///srv/mirror/package/1.2.3", "1.2.3", this.archive)
+	this.storage.objects["file:///srv/mirror/package/1.2.3/manifest.json"] = append(this.manifest, '\n')
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.uploads, should.Resemble, []string{"file:///srv/mirror/package/1.2.3/manifest.json"})
+}
+
+func (this *PackageMirrorFixture) TestMirroredVersionAddedToVersionIndex() {
+	this.storage.objects["file:///srv/mirror/package/versions.json"] = []byte(`{"versions":["1.2.2"]}`)
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	var index contracts.VersionIndex
+	_ = json.Unmarshal(this.storage.objects["file:///srv/mirror/package/versions.json"], &index)
+	this.So(index.Versions, should.Resemble, []string{"1.2.2", "1.2.3"})
+}
+
+func (this *PackageMirrorFixture) TestAlreadyMirroredVersionIndexed() {
+	this.publish("file:///srv/mirror/package/1.2.3", "1.2.3", this.archive)
+	delete(this.storage.objects, "file:///srv/mirror/package/versions.json")
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.uploads, should.Resemble, []string{"file:///srv/mirror/package/versions.json"})
+}
+
+func (this *PackageMirrorFixture) TestPooledArchiveMirroredToTargetPool() {
+	this.pool("gcs://source/packages")
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.uploads, should.Resemble, []string{
+		"file:///srv/mirror/pool/" + this.poolName(),
+		"file:///srv/mirror/package/1.2.3/manifest.json",
+		"file:///srv/mirror/package/versions.json",
+	})
+}
+
+func (this *PackageMirrorFixture) TestPooledArchiveAlreadyInTargetPoolSkipped() {
+	this.pool("gcs://source/packages")
+	this.storage.objects["file:///srv/mirror/pool/"+this.poolName()] = this.archive
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.uploads, should.Resemble, []string{
+		"file:///srv/mirror/package/1.2.3/manifest.json",
+		"file:///srv/mirror/package/versions.json",
+	})
+	this.So(this.spools, should.BeEmpty)
+}
+
+func (this *PackageMirrorFixture) TestCompressionDictionaryMirrored() {
+	location := this.withDictionary([]byte("dictionary"))
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.objects["file:///srv/mirror/"+location], should.Resemble, []byte("dictionary"))
+	this.So(this.storage.uploads, should.Resemble, []string{
+		"file:///srv/mirror/package/1.2.3/archive",
+		"file:///srv/mirror/" + location,
+		"file:///srv/mirror/package/1.2.3/manifest.json",
+		"file:///srv/mirror/package/versions.json",
+	})
+}
+
+func (this *PackageMirrorFixture) TestCompressionDictionaryAlreadyMirrored() {
+	location := this.withDictionary([]byte("dictionary"))
+	this.storage.objects["file:///srv/mirror/"+location] = []byte("dictionary")
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.uploads, should.NotContain, "file:///srv/mirror/"+location)
+}
+
+func (this *PackageMirrorFixture) TestCorruptCompressionDictionaryNotMirrored() {
+	location := this.withDictionary([]byte("dictionary"))
+	this.storage.objects["gcs://source/packages/"+location] = []byte("DICTIONARY")
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.storage.uploads, should.NotContain, "file:///srv/mirror/package/1.2.3/manifest.json")
+}
+
+// withDictionary records a compression dictionary in the published manifest.
+func (this *PackageMirrorFixture) withDictionary(dictionary []byte) string {
+	var manifest contracts.Manifest
+	_ = json.Unmarshal(this.manifest, &manifest)
+	checksum := md5.Sum(dictionary)
+	manifest.Archive.Dictionary = &contracts.Dictionary{
+		Location:    contracts.ComposeDictionaryLocation(checksum[:]),
+		Size:        uint64(len(dictionary)),
+		MD5Checksum: checksum[:],
+	}
+	this.manifest, _ = json.Marshal(manifest)
+	this.storage.objects["gcs://source/packages/package/1.2.3/manifest.json"] = this.manifest
+	this.storage.objects["gcs://source/packages/"+manifest.Archive.Dictionary.Location] = dictionary
+	return manifest.Archive.Dictionary.Location
+}
+
+func (this *PackageMirrorFixture) TestLatestManifestMirroredOnceVersionIsPresent() {
+	_, _ = this.mirror.MirrorVersion("package", "1.2.3")
+
+	err := this.mirror.MirrorLatest("package")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.objects["file:///srv/mirror/package/manifest.json"], should.Resemble, this.manifest)
+}
+
+func (this *PackageMirrorFixture) TestLatestManifestUnchangedWhenVersionNotMirrored() {
+	err := this.mirror.MirrorLatest("package")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.uploads, should.BeEmpty)
+}
+
+func (this *PackageMirrorFixture) TestLatestManifestAlreadyMirrored() {
+	this.publish("file:///srv/mirror/package/1.2.3", "1.2.3", this.archive)
+	this.storage.objects["file:///srv/mirror/package/manifest.json"] = this.manifest
+
+	err := this.mirror.MirrorLatest("package")
+
+	this.So(err, should.BeNil)
+	this.So(this.storage.uploads, should.BeEmpty)
+}
+
+func (this *PackageMirrorFixture) TestFailureToCheckTarget() {
+	this.storage.downloadErr = errors.New("unavailable")
+
+	_, err := this.mirror.MirrorVersion("package", "1.2.3")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.storage.uploads, should.BeEmpty)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+type FakeObjectStorage struct {
+	objects      map[string][]byte
+	contentTypes map[string]string
+	uploads      []string
+	downloads    []string
+	downloadErr  error
+}
+
+func NewFakeObjectStorage() *FakeObjectStorage {
+	return &FakeObjectStorage{objects: make(map[string][]byte), contentTypes: make(map[string]string)}
+}
+
+func (this *FakeObjectStorage) Download(address url.URL) (io.ReadCloser, error) {
+	this.downloads = append(this.downloads, address.String())
+	if this.downloadErr != nil && address.Scheme == "file" {
+		return nil, this.downloadErr
+	}
+	object, found := this.objects[address.String()]
+	if !found {
+		return nil, contracts.NewStatusCodeError(http.StatusNotFound, http.StatusOK, address)
+	}
+	return ioutil.NopCloser(bytes.NewReader(object)), nil
+}
+
+func (this *FakeObjectStorage) Upload(request contracts.UploadRequest) error {
+	raw, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return err
+	}
+	if checksum := md5.Sum(raw); int64(len(raw)) != request.Size || !bytes.Equal(checksum[:], request.Checksum) {
+		return errors.New("corrupt upload")
+	}
+	this.uploads = append(this.uploads, request.RemoteAddress.String())
+	this.objects[request.RemoteAddress.String()] = raw
+	this.contentTypes[request.RemoteAddress.String()] = request.ContentType
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+type FakeSpool struct {
+	bytes.Buffer
+	offset int
+	closed bool
+}
+
+func (this *FakeSpool) Read(buffer []byte) (int, error) {
+	if this.offset >= this.Len() {
+		return 0, io.EOF
+	}
+	count := copy(buffer, this.Bytes()[this.offset:])
+	this.offset += count
+	return count, nil
+}
+
+func (this *FakeSpool) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.New("unsupported")
+	}
+	this.offset = int(offset)
+	return offset, nil
+}
+
+func (this *FakeSpool) Close() error {
+	this.closed = true
+	return nil
+}
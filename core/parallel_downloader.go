@@ -0,0 +1,164 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"sync"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// DownloadSpool holds the chunks of a parallel download until the archive is complete. Closing
+// the spool discards it.
+type DownloadSpool interface {
+	io.WriterAt
+	io.ReadSeeker
+	io.Closer
+}
+
+// ArchiveDownloader is implemented by downloaders which fetch (and verify) archives on their own
+// terms, given what the manifest says about the archive.
+type ArchiveDownloader interface {
+	DownloadArchive(address url.URL, archive contracts.Archive) (io.ReadCloser, error)
+}
+
+// ParallelDownloader fetches archives larger than a single chunk as concurrent byte ranges
+// into a spool, which is verified against the checksum of the archive before it is read.
+// Smaller archives, and archives on remote storage that doesn't support ranged downloads,
+// are downloaded as a single stream.
+type ParallelDownloader struct {
+	inner       contracts.Downloader
+	chunkSize   int64
+	concurrency int
+	newSpool    func() (DownloadSpool, error)
+}
+
+func NewParallelDownloader(
+	inner contracts.Downloader,
+	chunkSize int64,
+	concurrency int,
+	newSpool func() (DownloadSpool, error),
+) *ParallelDownloader {
+	return &ParallelDownloader{inner: inner, chunkSize: chunkSize, concurrency: concurrency, newSpool: newSpool}
+}
+
+func (this *ParallelDownloader) Download(address url.URL) (io.ReadCloser, error) {
+	return this.inner.Download(address)
+}
+
+func (this *ParallelDownloader) DownloadArchive(address url.URL, archive contracts.Archive) (io.ReadCloser, error) {
+	size := int64(archive.Size)
+	ranged, ok := this.inner.(contracts.RangeDownloader)
+	if !ok || this.concurrency < 2 || this.chunkSize <= 0 || size <= this.chunkSize {
+		return this.inner.Download(address)
+	}
+
+	spool, err := this.newSpool()
+	if err != nil {
+		return nil, err
+	}
+	err = this.downloadChunks(ranged, address, size, spool)
+	if errors.Is(err, contracts.RangeUnsupportedErr) {
+		closeResource(spool)
+		log.Printf("[WARN] %s, downloading %s as a single stream.", err, address.String())
+		return this.inner.Download(address)
+	}
+	if err == nil {
+		err = this.verify(spool, StrongestChecksum(archive.HashAlgorithm, archive.MD5Checksum, archive.Checksum))
+	}
+	if err != nil {
+		closeResource(spool)
+		return nil, err
+	}
+	return spool, nil
+}
+
+func (this *ParallelDownloader) downloadChunks(ranged contracts.RangeDownloader, address url.URL, size int64, spool DownloadSpool) error {
+	offsets := make(chan int64, size/this.chunkSize+1)
+	for offset := int64(0); offset < size; offset += this.chunkSize {
+		offsets <- offset
+	}
+	close(offsets)
+
+	var (
+		waiter   sync.WaitGroup
+		lock     sync.Mutex
+		firstErr error
+	)
+	for x := 0; x < this.concurrency; x++ {
+		waiter.Add(1)
+		go func() {
+			defer waiter.Done()
+			for offset := range offsets {
+				lock.Lock()
+				failed := firstErr != nil
+				lock.Unlock()
+				if failed {
+					return
+				}
+				err := this.downloadChunk(ranged, address, offset, minimum64(this.chunkSize, size-offset), spool)
+				if err != nil {
+					lock.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					lock.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	waiter.Wait()
+	return firstErr
+}
+
+func (this *ParallelDownloader) downloadChunk(ranged contracts.RangeDownloader, address url.URL, offset, length int64, spool DownloadSpool) error {
+	body, err := ranged.DownloadRange(address, offset, length)
+	if err != nil {
+		return err
+	}
+	defer closeResource(body)
+
+	written, err := io.Copy(&offsetWriter{writer: spool, offset: offset}, body)
+	if err != nil {
+		return err
+	}
+	if written != length {
+		return fmt.Errorf("incomplete byte range at offset %d: received [%d] of [%d] bytes", offset, written, length)
+	}
+	return nil
+}
+
+func (this *ParallelDownloader) verify(spool DownloadSpool, checksum Checksum) error {
+	hasher := checksum.New()
+	_, err := io.Copy(hasher, spool)
+	if err != nil {
+		return err
+	}
+	if err = checksum.Verify(hasher.Sum(nil)); err != nil {
+		return err
+	}
+	_, err = spool.Seek(0, io.SeekStart)
+	return err
+}
+
+type offsetWriter struct {
+	writer io.WriterAt
+	offset int64
+}
+
+func (this *offsetWriter) Write(buffer []byte) (int, error) {
+	count, err := this.writer.WriteAt(buffer, this.offset)
+	this.offset += int64(count)
+	return count, err
+}
+
+func minimum64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
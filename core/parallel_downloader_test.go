@@ -0,0 +1,202 @@
+package core
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestParallelDownloaderFixture(t *testing.T) {
+	gunit.Run(new(ParallelDownloaderFixture), t)
+}
+
+type ParallelDownloaderFixture struct {
+	*gunit.Fixture
+	inner      *FakeRangeClient
+	spools     []*FakeDownloadSpool
+	spoolErr   error
+	downloader *ParallelDownloader
+	address    url.URL
+}
+
+func (this *ParallelDownloaderFixture) Setup() {
+	this.inner = &FakeRangeClient{FakeClient: &FakeClient{}, content: "0123456789"}
+	this.downloader = NewParallelDownloader(this.inner, 4, 3, this.newSpool)
+	this.address = url.URL{Scheme: "gcs", Host: "bucket", Path: "/archive"}
+}
+
+func (this *ParallelDownloaderFixture) newSpool() (DownloadSpool, error) {
+	spool := &FakeDownloadSpool{}
+	this.spools = append(this.spools, spool)
+	return spool, this.spoolErr
+}
+
+func (this *ParallelDownloaderFixture) archive(content string) contracts.Archive {
+	checksum := md5.Sum([]byte(content))
+	return contracts.Archive{Size: uint64(len(content)), MD5Checksum: checksum[:]}
+}
+
+func (this *ParallelDownloaderFixture) TestLargeArchiveDownloadedInConcurrentChunks() {
+	body, err := this.downloader.DownloadArchive(this.address, this.archive("0123456789"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "0123456789")
+	sort.Strings(this.inner.ranges)
+	this.So(this.inner.ranges, should.Resemble, []string{"0-3", "4-7", "8-9"})
+	this.So(this.spools, should.HaveLength, 1)
+	_ = body.Close()
+	this.So(this.spools[0].closed, should.BeTrue)
+}
+
+func (this *ParallelDownloaderFixture) TestSmallArchiveDownloadedAsSingleStream() {
+	this.downloader = NewParallelDownloader(this.inner, 10, 3, this.newSpool)
+
+	body, err := this.downloader.DownloadArchive(this.address, this.archive("0123456789"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "0123456789")
+	this.So(this.inner.ranges, should.BeEmpty)
+	this.So(this.spools, should.BeEmpty)
+}
+
+func (this *ParallelDownloaderFixture) TestSingleStreamWithoutConcurrency() {
+	this.downloader = NewParallelDownloader(this.inner, 4, 1, this.newSpool)
+
+	body, err := this.downloader.DownloadArchive(this.address, this.archive("0123456789"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "0123456789")
+	this.So(this.inner.ranges, should.BeEmpty)
+}
+
+func (this *ParallelDownloaderFixture) TestSingleStreamWithoutRangeSupport() {
+	this.downloader = NewParallelDownloader(&FakeClient{downloadContent: "0123456789"}, 4, 3, this.newSpool)
+
+	body, err := this.downloader.DownloadArchive(this.address, this.archive("0123456789"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "0123456789")
+	this.So(this.spools, should.BeEmpty)
+}
+
+func (this *ParallelDownloaderFixture) TestFallbackToSingleStreamWhenRangesRejected() {
+	this.inner.rangeErr = contracts.RangeUnsupportedErr
+
+	body, err := this.downloader.DownloadArchive(this.address, this.archive("0123456789"))
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "0123456789")
+	this.So(this.spools[0].closed, should.BeTrue)
+}
+
+func (this *ParallelDownloaderFixture) TestChunkFailure() {
+	this.inner.rangeErr = aRegularError
+
+	body, err := this.downloader.DownloadArchive(this.address, this.archive("0123456789"))
+
+	this.So(body, should.BeNil)
+	this.So(err, should.Equal, aRegularError)
+	this.So(this.spools[0].closed, should.BeTrue)
+}
+
+func (this *ParallelDownloaderFixture) TestIncompleteChunk() {
+	body, err := this.downloader.DownloadArchive(this.address, this.archive("0123456789AB"))
+
+	this.So(body, should.BeNil)
+	this.So(err, should.NotBeNil)
+	this.So(this.spools[0].closed, should.BeTrue)
+}
+
+func (this *ParallelDownloaderFixture) TestChecksumMismatch() {
+	archive := this.archive("0123456789")
+	archive.MD5Checksum = []byte("mismatch")
+
+	body, err := this.downloader.DownloadArchive(this.address, archive)
+
+	this.So(body, should.BeNil)
+	this.So(err, should.NotBeNil)
+	this.So(this.spools[0].closed, should.BeTrue)
+}
+
+func (this *ParallelDownloaderFixture) TestSpoolFailure() {
+	this.spoolErr = errors.New("spool failure")
+
+	body, err := this.downloader.DownloadArchive(this.address, this.archive("0123456789"))
+
+	this.So(body, should.BeNil)
+	this.So(err, should.Equal, this.spoolErr)
+}
+
+func (this *ParallelDownloaderFixture) TestInstallerDownloadsArchiveInChunks() {
+	downloader := &FakeDownloader{}
+	checksum := downloader.prepareArchiveDownload(gzipAlgorithm)
+	content, _ := ioutil.ReadAll(downloader.Body)
+	this.inner.content = string(content)
+	filesystem := newInMemoryFileSystem()
+	installer := NewPackageInstaller(NewParallelDownloader(this.inner, 16, 3, this.newSpool), filesystem, nil, newMemoryArchiveSpool)
+	manifest := contracts.Manifest{Archive: contracts.Archive{
+		Size:                 uint64(len(content)),
+		MD5Checksum:          checksum,
+		CompressionAlgorithm: gzipAlgorithm,
+		Contents:             []contracts.ArchiveItem{{}, {}, {}},
+	}}
+
+	err := installer.InstallPackage(manifest, contracts.InstallationRequest{LocalPath: "local"})
+
+	this.So(err, should.BeNil)
+	this.So(len(this.inner.ranges), should.BeGreaterThan, 1)
+	this.So(filesystem.readFile("local/Hello/World"), should.Resemble, []byte("Hello World"))
+	this.So(this.spools[0].closed, should.BeTrue)
+}
+
+func (this *ParallelDownloaderFixture) readAll(body io.Reader) string {
+	raw, _ := ioutil.ReadAll(body)
+	return string(raw)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////
+
+type FakeDownloadSpool struct {
+	lock     sync.Mutex
+	contents []byte
+	reader   *bytes.Reader
+	closed   bool
+}
+
+func (this *FakeDownloadSpool) WriteAt(buffer []byte, offset int64) (int, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if end := int(offset) + len(buffer); end > len(this.contents) {
+		this.contents = append(this.contents, make([]byte, end-len(this.contents))...)
+	}
+	return copy(this.contents[offset:], buffer), nil
+}
+
+func (this *FakeDownloadSpool) Read(buffer []byte) (int, error) {
+	if this.reader == nil {
+		this.reader = bytes.NewReader(this.contents)
+	}
+	return this.reader.Read(buffer)
+}
+
+func (this *FakeDownloadSpool) Seek(offset int64, whence int) (int64, error) {
+	if this.reader == nil {
+		this.reader = bytes.NewReader(this.contents)
+	}
+	return this.reader.Seek(offset, whence)
+}
+
+func (this *FakeDownloadSpool) Close() error {
+	this.closed = true
+	return nil
+}
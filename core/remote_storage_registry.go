@@ -0,0 +1,117 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+type RemoteStorageRegistry struct {
+	lock      sync.Mutex
+	factories map[string]contracts.RemoteStorageFactory
+	clients   map[string]contracts.RemoteStorage
+}
+
+func NewRemoteStorageRegistry() *RemoteStorageRegistry {
+	return &RemoteStorageRegistry{
+		factories: make(map[string]contracts.RemoteStorageFactory),
+		clients:   make(map[string]contracts.RemoteStorage),
+	}
+}
+
+func (this *RemoteStorageRegistry) Register(scheme string, factory contracts.RemoteStorageFactory) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.factories[strings.ToLower(scheme)] = factory
+}
+
+func (this *RemoteStorageRegistry) Upload(request contracts.UploadRequest) error {
+	client, err := this.resolve(request.RemoteAddress)
+	if err != nil {
+		return err
+	}
+	return client.Upload(request)
+}
+
+func (this *RemoteStorageRegistry) BeginUpload(request contracts.UploadRequest) (contracts.UploadSession, error) {
+	client, err := this.resolve(request.RemoteAddress)
+	if err != nil {
+		return nil, err
+	}
+	sessions, ok := client.(contracts.SessionUploader)
+	if !ok {
+		return nil, fmt.Errorf("%w for %q remote addresses (%s)",
+			contracts.SessionUnsupportedErr, request.RemoteAddress.Scheme, request.RemoteAddress.String())
+	}
+	return sessions.BeginUpload(request)
+}
+
+func (this *RemoteStorageRegistry) Download(address url.URL) (io.ReadCloser, error) {
+	client, err := this.resolve(address)
+	if err != nil {
+		return nil, err
+	}
+	return client.Download(address)
+}
+
+func (this *RemoteStorageRegistry) DownloadRange(address url.URL, offset, length int64) (io.ReadCloser, error) {
+	client, err := this.resolve(address)
+	if err != nil {
+		return nil, err
+	}
+	ranged, ok := client.(contracts.RangeDownloader)
+	if !ok {
+		return nil, fmt.Errorf("%w for %q remote addresses (%s)", contracts.RangeUnsupportedErr, address.Scheme, address.String())
+	}
+	return ranged.DownloadRange(address, offset, length)
+}
+
+// DownloadRangeIfUnchanged makes the request conditional when the remote storage supports it
+// (otherwise the caller must compare the validator of the body received).
+func (this *RemoteStorageRegistry) DownloadRangeIfUnchanged(address url.URL, offset, length int64, validator contracts.Validator) (io.ReadCloser, error) {
+	client, err := this.resolve(address)
+	if err != nil {
+		return nil, err
+	}
+	if conditional, ok := client.(contracts.ConditionalRangeDownloader); ok {
+		return conditional.DownloadRangeIfUnchanged(address, offset, length, validator)
+	}
+	return this.DownloadRange(address, offset, length)
+}
+
+func (this *RemoteStorageRegistry) List(address url.URL) ([]string, error) {
+	client, err := this.resolve(address)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := client.(contracts.Lister)
+	if !ok {
+		return nil, fmt.Errorf("listing is not supported for %q remote addresses (%s)", address.Scheme, address.String())
+	}
+	return lister.List(address)
+}
+
+func (this *RemoteStorageRegistry) resolve(address url.URL) (contracts.RemoteStorage, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	scheme := strings.ToLower(address.Scheme)
+	key := scheme + "://" + address.Host
+	if client, found := this.clients[key]; found {
+		return client, nil
+	}
+	factory, found := this.factories[scheme]
+	if !found {
+		return nil, fmt.Errorf("unsupported remote address scheme: %q (%s)", address.Scheme, address.String())
+	}
+	client, err := factory(address)
+	if err != nil {
+		return nil, err
+	}
+	this.clients[key] = client
+	return client, nil
+}
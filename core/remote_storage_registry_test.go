@@ -0,0 +1,172 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestRemoteStorageRegistryFixture(t *testing.T) {
+	gunit.Run(new(RemoteStorageRegistryFixture), t)
+}
+
+type RemoteStorageRegistryFixture struct {
+	*gunit.Fixture
+
+	registry   *RemoteStorageRegistry
+	gcsClient  *FakeClient
+	s3Client   *FakeClient
+	factoryErr error
+	addresses  []url.URL
+}
+
+func (this *RemoteStorageRegistryFixture) Setup() {
+	this.gcsClient = &FakeClient{downloadContent: "gcs"}
+	this.s3Client = &FakeClient{downloadContent: "s3"}
+	this.registry = NewRemoteStorageRegistry()
+	this.registry.Register("gcs", this.factory(this.gcsClient))
+	this.registry.Register("S3", this.factory(this.s3Client))
+}
+
+func (this *RemoteStorageRegistryFixture) factory(client contracts.RemoteStorage) contracts.RemoteStorageFactory {
+	return func(address url.URL) (contracts.RemoteStorage, error) {
+		this.addresses = append(this.addresses, address)
+		if this.factoryErr != nil {
+			return nil, this.factoryErr
+		}
+		return client, nil
+	}
+}
+
+func (this *RemoteStorageRegistryFixture) TestDownloadDispatchedByScheme() {
+	gcsAddress := url.URL{Scheme: "gcs", Host: "bucket", Path: "/a"}
+	s3Address := url.URL{Scheme: "s3", Host: "bucket", Path: "/b"}
+
+	gcsBody, gcsErr := this.registry.Download(gcsAddress)
+	s3Body, s3Err := this.registry.Download(s3Address)
+
+	this.So(gcsErr, should.BeNil)
+	this.So(s3Err, should.BeNil)
+	this.So(this.readAll(gcsBody), should.Equal, "gcs")
+	this.So(this.readAll(s3Body), should.Equal, "s3")
+	this.So(this.gcsClient.downloadRequest, should.Resemble, gcsAddress)
+	this.So(this.s3Client.downloadRequest, should.Resemble, s3Address)
+}
+
+func (this *RemoteStorageRegistryFixture) TestUploadDispatchedByScheme() {
+	request := contracts.UploadRequest{RemoteAddress: url.URL{Scheme: "s3", Host: "bucket", Path: "/a"}}
+
+	err := this.registry.Upload(request)
+
+	this.So(err, should.BeNil)
+	this.So(this.s3Client.uploadAttempts, should.Equal, 1)
+	this.So(this.gcsClient.uploadAttempts, should.Equal, 0)
+}
+
+func (this *RemoteStorageRegistryFixture) TestClientsCachedPerSchemeAndHost() {
+	_, _ = this.registry.Download(url.URL{Scheme: "gcs", Host: "bucket-1", Path: "/a"})
+	_, _ = this.registry.Download(url.URL{Scheme: "gcs", Host: "bucket-1", Path: "/b"})
+	_, _ = this.registry.Download(url.URL{Scheme: "gcs", Host: "bucket-2", Path: "/a"})
+
+	this.So(this.addresses, should.HaveLength, 2)
+	this.So(this.gcsClient.downloadAttempts, should.Equal, 3)
+}
+
+func (this *RemoteStorageRegistryFixture) TestUnregisteredScheme() {
+	body, err := this.registry.Download(url.URL{Scheme: "ftp", Host: "host", Path: "/a"})
+	uploadErr := this.registry.Upload(contracts.UploadRequest{RemoteAddress: url.URL{Scheme: "ftp"}})
+
+	this.So(body, should.BeNil)
+	this.So(err, should.NotBeNil)
+	this.So(uploadErr, should.NotBeNil)
+}
+
+func (this *RemoteStorageRegistryFixture) TestFactoryFailure() {
+	this.factoryErr = errors.New("factory failure")
+
+	body, err := this.registry.Download(url.URL{Scheme: "gcs", Host: "bucket", Path: "/a"})
+
+	this.So(body, should.BeNil)
+	this.So(err, should.Equal, this.factoryErr)
+	this.So(this.gcsClient.downloadAttempts, should.Equal, 0)
+}
+
+func (this *RemoteStorageRegistryFixture) TestListDispatchedToListingClient() {
+	lister := &FakeListingClient{FakeClient: &FakeClient{}, listing: []string{"1.2.3"}}
+	this.registry.Register("file", this.factory(lister))
+	address := url.URL{Scheme: "file", Path: "/a"}
+
+	listing, err := this.registry.List(address)
+
+	this.So(err, should.BeNil)
+	this.So(listing, should.Resemble, []string{"1.2.3"})
+	this.So(lister.listRequest, should.Resemble, address)
+}
+
+func (this *RemoteStorageRegistryFixture) TestListingNotSupported() {
+	listing, err := this.registry.List(url.URL{Scheme: "gcs", Host: "bucket", Path: "/a"})
+
+	this.So(listing, should.BeNil)
+	this.So(err, should.NotBeNil)
+}
+
+func (this *RemoteStorageRegistryFixture) TestDownloadRangeDispatchedToRangeClient() {
+	ranged := &FakeRangeClient{FakeClient: &FakeClient{}, content: "0123456789"}
+	this.registry.Register("https", this.factory(ranged))
+
+	body, err := this.registry.DownloadRange(url.URL{Scheme: "https", Host: "host", Path: "/a"}, 3, 2)
+
+	this.So(err, should.BeNil)
+	this.So(this.readAll(body), should.Equal, "34")
+	this.So(ranged.ranges, should.Resemble, []string{"3-4"})
+}
+
+func (this *RemoteStorageRegistryFixture) TestDownloadRangeNotSupported() {
+	body, err := this.registry.DownloadRange(url.URL{Scheme: "gcs", Host: "bucket", Path: "/a"}, 3, 2)
+
+	this.So(body, should.BeNil)
+	this.So(err, should.NotBeNil)
+}
+
+func (this *RemoteStorageRegistryFixture) TestBeginUploadDispatchedToSessionClient() {
+	sessions := &FakeSessionClient{FakeClient: &FakeClient{}, session: &FakeUploadSession{}}
+	this.registry.Register("gcs", this.factory(sessions))
+
+	session, err := this.registry.BeginUpload(contracts.UploadRequest{RemoteAddress: url.URL{Scheme: "gcs", Host: "bucket", Path: "/a"}})
+
+	this.So(err, should.BeNil)
+	this.So(session, should.Equal, sessions.session)
+	this.So(sessions.begun, should.Equal, 1)
+}
+
+func (this *RemoteStorageRegistryFixture) TestBeginUploadNotSupported() {
+	session, err := this.registry.BeginUpload(contracts.UploadRequest{RemoteAddress: url.URL{Scheme: "gcs", Host: "bucket", Path: "/a"}})
+
+	this.So(session, should.BeNil)
+	this.So(errors.Is(err, contracts.SessionUnsupportedErr), should.BeTrue)
+}
+
+func (this *RemoteStorageRegistryFixture) readAll(body io.Reader) string {
+	raw, _ := ioutil.ReadAll(body)
+	return string(raw)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+
+type FakeListingClient struct {
+	*FakeClient
+
+	listRequest url.URL
+	listing     []string
+}
+
+func (this *FakeListingClient) List(address url.URL) ([]string, error) {
+	this.listRequest = address
+	return this.listing, this.error
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/url"
@@ -37,9 +38,50 @@ func (this *RetryClient) Upload(request contracts.UploadRequest) (err error) {
 	return err
 }
 
-func (this *RetryClient) Download(request url.URL) (body io.ReadCloser, err error) {
+func (this *RetryClient) BeginUpload(request contracts.UploadRequest) (session contracts.UploadSession, err error) {
+	sessions, ok := this.inner.(contracts.SessionUploader)
+	if !ok {
+		return nil, fmt.Errorf("%w (%s)", contracts.SessionUnsupportedErr, request.RemoteAddress.String())
+	}
+	for x := 0; x <= this.maxRetry; x++ {
+		session, err = sessions.BeginUpload(request)
+		if err == nil || !errors.Is(err, contracts.RetryErr) {
+			return session, err
+		}
+		if x < this.maxRetry {
+			log.Println("[WARN] upload session failed to start, retry imminent.")
+			this.sleep(time.Second * 3)
+		}
+	}
+	return nil, err
+}
+
+// Download resumes a download which fails part way through from the last byte received (provided
+// the remote storage supports ranged downloads), so callers (and any checksum they calculate over
+// the body) see a single, uninterrupted stream.
+func (this *RetryClient) Download(request url.URL) (io.ReadCloser, error) {
+	body, err := this.download(func() (io.ReadCloser, error) { return this.inner.Download(request) })
+	if err != nil {
+		return nil, err
+	}
+	return this.resumable(request, body, 0, 0), nil
+}
+
+func (this *RetryClient) DownloadRange(request url.URL, offset, length int64) (io.ReadCloser, error) {
+	ranged, ok := this.inner.(contracts.RangeDownloader)
+	if !ok {
+		return nil, fmt.Errorf("%w (%s)", contracts.RangeUnsupportedErr, request.String())
+	}
+	body, err := this.download(func() (io.ReadCloser, error) { return ranged.DownloadRange(request, offset, length) })
+	if err != nil {
+		return nil, err
+	}
+	return this.resumable(request, body, offset, length), nil
+}
+
+func (this *RetryClient) download(attempt func() (io.ReadCloser, error)) (body io.ReadCloser, err error) {
 	for x := 0; x <= this.maxRetry; x++ {
-		body, err = this.inner.Download(request)
+		body, err = attempt()
 		if err == nil {
 			return body, nil
 		}
@@ -53,3 +95,98 @@ func (this *RetryClient) Download(request url.URL) (body io.ReadCloser, err erro
 	}
 	return nil, err
 }
+
+func (this *RetryClient) resumable(request url.URL, body io.ReadCloser, offset, length int64) io.ReadCloser {
+	ranged, ok := this.inner.(contracts.RangeDownloader)
+	if !ok {
+		return body
+	}
+	resumable := &resumableBody{client: this, ranged: ranged, address: request, body: body, offset: offset, length: length}
+	if validated, ok := body.(contracts.ValidatedBody); ok {
+		resumable.validator = validated.Validator()
+	}
+	return resumable
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type resumableBody struct {
+	client    *RetryClient
+	ranged    contracts.RangeDownloader
+	address   url.URL
+	body      io.ReadCloser
+	validator contracts.Validator // of the version of the object first received
+	offset    int64               // of the next byte to be received
+	length    int64               // remaining, if limited (positive)
+	resumed   int
+}
+
+func (this *resumableBody) Read(buffer []byte) (int, error) {
+	count, err := this.body.Read(buffer)
+	this.offset += int64(count)
+	if this.length > 0 {
+		this.length -= int64(count)
+		if this.length <= 0 && err == nil {
+			err = io.EOF
+		}
+	}
+	if err == nil || !errors.Is(err, contracts.RetryErr) {
+		return count, err
+	}
+	return count, this.resume(err)
+}
+
+func (this *resumableBody) resume(cause error) error {
+	_ = this.body.Close()
+	for this.resumed < this.client.maxRetry {
+		this.resumed++
+		log.Printf("[WARN] download interrupted at byte %d (%s), resuming.", this.offset, cause)
+		this.client.sleep(time.Second * 3)
+
+		body, err := this.downloadRemainder()
+		if err == nil {
+			return this.accept(body)
+		}
+		if !errors.Is(err, contracts.RetryErr) {
+			return err
+		}
+		cause = err
+	}
+	return cause
+}
+
+// downloadRemainder requests the rest of the same version of the object (if it can be identified).
+func (this *resumableBody) downloadRemainder() (io.ReadCloser, error) {
+	conditional, ok := this.ranged.(contracts.ConditionalRangeDownloader)
+	if !ok || this.validator == (contracts.Validator{}) {
+		return this.ranged.DownloadRange(this.address, this.offset, this.length)
+	}
+	return conditional.DownloadRangeIfUnchanged(this.address, this.offset, this.length, this.validator)
+}
+
+// accept continues with the body unless it's the remainder of a different version of the object
+// (in case the remote storage ignored the condition).
+func (this *resumableBody) accept(body io.ReadCloser) error {
+	if validated, ok := body.(contracts.ValidatedBody); ok && this.validator.Differs(validated.Validator()) {
+		_ = body.Close()
+		return fmt.Errorf("%w (%s)", contracts.ObjectChangedErr, this.address.String())
+	}
+	this.body = body
+	return nil
+}
+
+func (this *resumableBody) Close() error {
+	return this.body.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// RewindOnClose allows the retry client to resend an upload body once the HTTP transport has closed it.
+type RewindOnClose struct {
+	io.ReadSeeker
+}
+
+func (this RewindOnClose) Close() error {
+	_, err := this.Seek(0, io.SeekStart)
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -104,6 +105,130 @@ func (this *RetryFixture) TestDownloadNoRetryOnRegularErrors() {
 	this.So(this.naps, should.BeEmpty)
 }
 
+func (this *RetryFixture) TestInterruptedDownloadResumedFromLastByteReceived() {
+	ranged := &FakeRangeClient{FakeClient: this.fakeClient, content: "0123456789", interruptions: []int64{4, 7}}
+	this.client = NewRetryClient(ranged, 4, func(duration time.Duration) { this.naps = append(this.naps, duration) })
+
+	body, err := this.client.Download(url.URL{Host: "host.com"})
+
+	this.So(err, should.BeNil)
+	all, err := ioutil.ReadAll(body)
+	this.So(err, should.BeNil)
+	this.So(string(all), should.Equal, "0123456789")
+	this.So(ranged.ranges, should.Resemble, []string{"4-", "7-"})
+	this.So(this.naps, should.HaveLength, 2)
+}
+
+func (this *RetryFixture) TestInterruptedRangeResumedWithinRange() {
+	ranged := &FakeRangeClient{FakeClient: this.fakeClient, content: "0123456789", interruptions: []int64{4}}
+	this.client = NewRetryClient(ranged, 4, func(duration time.Duration) {})
+
+	body, err := this.client.DownloadRange(url.URL{Host: "host.com"}, 2, 5)
+
+	this.So(err, should.BeNil)
+	all, err := ioutil.ReadAll(body)
+	this.So(err, should.BeNil)
+	this.So(string(all), should.Equal, "23456")
+	this.So(ranged.ranges, should.Resemble, []string{"2-6", "4-6"})
+}
+
+func (this *RetryFixture) TestInterruptedDownloadResumedAtMostMaxRetryTimes() {
+	ranged := &FakeRangeClient{FakeClient: this.fakeClient, content: "0123456789", interruptions: []int64{1, 2, 3, 4, 5}}
+	this.client = NewRetryClient(ranged, 4, func(duration time.Duration) {})
+
+	body, _ := this.client.Download(url.URL{Host: "host.com"})
+	_, err := ioutil.ReadAll(body)
+
+	this.So(errors.Is(err, contracts.RetryErr), should.BeTrue)
+	this.So(ranged.ranges, should.HaveLength, 4)
+}
+
+func (this *RetryFixture) TestInterruptedDownloadNotResumedWithoutRangeSupport() {
+	this.fakeClient.downloadContent = "content"
+
+	body, _ := this.client.Download(url.URL{Host: "host.com"})
+
+	_, ok := body.(*resumableBody)
+	this.So(ok, should.BeFalse)
+}
+
+func (this *RetryFixture) TestResumeFailsWithRegularError() {
+	ranged := &FakeRangeClient{FakeClient: this.fakeClient, content: "0123456789", interruptions: []int64{4}, rangeErr: aRegularError}
+	this.client = NewRetryClient(ranged, 4, func(duration time.Duration) {})
+
+	body, _ := this.client.Download(url.URL{Host: "host.com"})
+	_, err := ioutil.ReadAll(body)
+
+	this.So(err, should.Equal, aRegularError)
+}
+
+func (this *RetryFixture) TestInterruptedDownloadResumedOnlyIfUnchanged() {
+	ranged := &FakeRangeClient{FakeClient: this.fakeClient, content: "0123456789", interruptions: []int64{4}, etags: []string{"v1"}}
+	conditional := &FakeConditionalRangeClient{FakeRangeClient: ranged}
+	this.client = NewRetryClient(conditional, 4, func(duration time.Duration) {})
+
+	body, _ := this.client.Download(url.URL{Host: "host.com"})
+	all, err := ioutil.ReadAll(body)
+
+	this.So(err, should.BeNil)
+	this.So(string(all), should.Equal, "0123456789")
+	this.So(conditional.validators, should.Resemble, []contracts.Validator{{ETag: "v1"}})
+}
+
+func (this *RetryFixture) TestChangedObjectNotResumed() {
+	ranged := &FakeRangeClient{FakeClient: this.fakeClient, content: "0123456789", interruptions: []int64{4, 7}, etags: []string{"v1", "v2"}}
+	this.client = NewRetryClient(&FakeConditionalRangeClient{FakeRangeClient: ranged}, 4, func(duration time.Duration) {})
+
+	body, _ := this.client.Download(url.URL{Host: "host.com"})
+	all, err := ioutil.ReadAll(body)
+
+	this.So(errors.Is(err, contracts.ObjectChangedErr), should.BeTrue)
+	this.So(string(all), should.Equal, "0123")
+	this.So(ranged.ranges, should.Resemble, []string{"4-"})
+}
+
+func (this *RetryFixture) TestChangedObjectNotResumedWithoutConditionalRanges() {
+	ranged := &FakeRangeClient{FakeClient: this.fakeClient, content: "0123456789", interruptions: []int64{4}, etags: []string{"v1", "v2"}}
+	this.client = NewRetryClient(ranged, 4, func(duration time.Duration) {})
+
+	body, _ := this.client.Download(url.URL{Host: "host.com"})
+	_, err := ioutil.ReadAll(body)
+
+	this.So(errors.Is(err, contracts.ObjectChangedErr), should.BeTrue)
+	this.So(ranged.ranges, should.HaveLength, 1)
+}
+
+func (this *RetryFixture) TestBeginUploadRetryOnError() {
+	sessions := &FakeSessionClient{FakeClient: this.fakeClient, beginErr: aRetryError}
+	this.client = NewRetryClient(sessions, 4, func(duration time.Duration) {
+		this.naps = append(this.naps, duration)
+	})
+
+	session, err := this.client.BeginUpload(contracts.UploadRequest{})
+
+	this.So(session, should.BeNil)
+	this.So(err, should.Equal, aRetryError)
+	this.So(sessions.begun, should.Equal, 5)
+	this.So(this.naps, should.HaveLength, 4)
+}
+
+func (this *RetryFixture) TestBeginUploadNoRetryOnRegularErrors() {
+	sessions := &FakeSessionClient{FakeClient: this.fakeClient, beginErr: aRegularError}
+	this.client = NewRetryClient(sessions, 4, nil)
+
+	_, err := this.client.BeginUpload(contracts.UploadRequest{})
+
+	this.So(err, should.Equal, aRegularError)
+	this.So(sessions.begun, should.Equal, 1)
+}
+
+func (this *RetryFixture) TestBeginUploadWithoutSessionSupport() {
+	session, err := this.client.BeginUpload(contracts.UploadRequest{})
+
+	this.So(session, should.BeNil)
+	this.So(errors.Is(err, contracts.SessionUnsupportedErr), should.BeTrue)
+}
+
 var (
 	aRetryError   = fmt.Errorf("this is a retry error %w", contracts.RetryErr)
 	aRegularError = errors.New("this is a regular error")
@@ -133,3 +258,83 @@ func (this *FakeClient) Upload(request contracts.UploadRequest) error {
 	this.uploadAttempts++
 	return this.error
 }
+
+// FakeRangeClient serves its content, interrupting the stream (once) at each of the interruptions.
+// Each response carries the next of the etags (if any), the last of which is repeated.
+type FakeRangeClient struct {
+	*FakeClient
+
+	lock          sync.Mutex
+	content       string
+	interruptions []int64
+	etags         []string
+	ranges        []string
+	rangeErr      error
+}
+
+func (this *FakeRangeClient) Download(request url.URL) (io.ReadCloser, error) {
+	return this.serve(0, int64(len(this.content))), nil
+}
+
+func (this *FakeRangeClient) DownloadRange(request url.URL, offset, length int64) (io.ReadCloser, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	end := int64(len(this.content))
+	if length > 0 {
+		this.ranges = append(this.ranges, fmt.Sprintf("%d-%d", offset, offset+length-1))
+		end = offset + length
+	} else {
+		this.ranges = append(this.ranges, fmt.Sprintf("%d-", offset))
+	}
+	if this.rangeErr != nil {
+		return nil, this.rangeErr
+	}
+	return this.serve(offset, end), nil
+}
+
+func (this *FakeRangeClient) serve(offset, end int64) io.ReadCloser {
+	end = minimum64(end, int64(len(this.content)))
+	for len(this.interruptions) > 0 && this.interruptions[0] <= offset {
+		this.interruptions = this.interruptions[1:]
+	}
+	var body io.ReadCloser
+	if len(this.interruptions) > 0 && this.interruptions[0] < end {
+		interruption := this.interruptions[0]
+		this.interruptions = this.interruptions[1:]
+		body = ioutil.NopCloser(io.MultiReader(strings.NewReader(this.content[offset:interruption]), failingReader{err: aRetryError}))
+	} else {
+		body = ioutil.NopCloser(strings.NewReader(this.content[offset:end]))
+	}
+	if len(this.etags) == 0 {
+		return body
+	}
+	etag := this.etags[0]
+	if len(this.etags) > 1 {
+		this.etags = this.etags[1:]
+	}
+	return validatedBody{ReadCloser: body, validator: contracts.Validator{ETag: etag}}
+}
+
+// FakeConditionalRangeClient records the conditions of ranged downloads (which, like a server
+// which ignores them, it doesn't enforce).
+type FakeConditionalRangeClient struct {
+	*FakeRangeClient
+
+	validators []contracts.Validator
+}
+
+func (this *FakeConditionalRangeClient) DownloadRangeIfUnchanged(request url.URL, offset, length int64, validator contracts.Validator) (io.ReadCloser, error) {
+	this.validators = append(this.validators, validator)
+	return this.DownloadRange(request, offset, length)
+}
+
+type validatedBody struct {
+	io.ReadCloser
+	validator contracts.Validator
+}
+
+func (this validatedBody) Validator() contracts.Validator { return this.validator }
+
+type failingReader struct{ err error }
+
+func (this failingReader) Read([]byte) (int, error) { return 0, this.err }
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+const (
+	sideBySideVersions = ".versions"
+	sideBySideCurrent  = "current"
+	sideBySideHistory  = "history.json"
+)
+
+type SideBySideFileSystem interface {
+	contracts.FileChecker
+	contracts.FileReader
+	contracts.FileWriter
+	contracts.SymlinkCreator
+	contracts.Renamer
+	contracts.TreeDeleter
+	contracts.DirectoryReader
+}
+
+// SideBySideInstallation keeps each version of a package in <local_directory>/.versions/<version>/
+// with a 'current' symlink pointing at the active version. Because the symlink is replaced with a
+// single rename, consumers that resolve the link see either the previous or the new version. The
+// order in which versions were activated is recorded so that the previous one can be restored (and
+// kept, rather than being replaced by the next installation, until the listed version changes).
+type SideBySideInstallation struct {
+	fileSystem     SideBySideFileSystem
+	localDirectory string
+	retained       int
+}
+
+func NewSideBySideInstallation(fileSystem SideBySideFileSystem, localDirectory string, retained int) *SideBySideInstallation {
+	return &SideBySideInstallation{fileSystem: fileSystem, localDirectory: localDirectory, retained: retained}
+}
+
+func (this *SideBySideInstallation) CurrentPath() string {
+	return filepath.Join(this.localDirectory, sideBySideCurrent)
+}
+
+// ActiveVersion reads the version targeted by the 'current' symlink (empty if there is none).
+func (this *SideBySideInstallation) ActiveVersion() string {
+	info, err := this.fileSystem.Stat(this.CurrentPath())
+	if err != nil || info.Symlink() == "" {
+		return ""
+	}
+	return filepath.Base(info.Symlink())
+}
+
+func (this *SideBySideInstallation) VersionPath(version string) string {
+	return filepath.Join(this.localDirectory, sideBySideVersions, version)
+}
+
+// Store moves a verified installation into the directory of its version (replacing any previous
+// installation of that version, unless it's the active version, which is never deleted).
+func (this *SideBySideInstallation) Store(source, version string) error {
+	if err := validateSideBySideVersion(version); err != nil {
+		return err
+	}
+	if version == this.ActiveVersion() {
+		return fmt.Errorf("version %s is active in %s and cannot be replaced (roll back or remove it first)", version, this.localDirectory)
+	}
+	target := this.VersionPath(version)
+	this.fileSystem.DeleteAll(target)
+	return this.fileSystem.Rename(source, target)
+}
+
+// Activate points the 'current' symlink at the version and discards all but the most recently
+// activated versions (beyond the number to be retained).
+func (this *SideBySideInstallation) Activate(version string) error {
+	if err := validateSideBySideVersion(version); err != nil {
+		return err
+	}
+	history := this.history()
+	history.Versions = append(this.removeFromHistory(history.Versions, version), version)
+	history.Pinned = nil
+
+	err := this.link(version)
+	if err != nil {
+		return err
+	}
+	if len(history.Versions) > this.retained+1 {
+		history.Versions = history.Versions[len(history.Versions)-this.retained-1:]
+	}
+	this.writeHistory(history)
+	this.prune(history.Versions)
+	return nil
+}
+
+// Rollback re-activates the version which was active before the current one and pins it for as
+// long as the dependency listing names the same version (see PinnedVersion).
+func (this *SideBySideInstallation) Rollback(listedVersion string) (string, error) {
+	history := this.history()
+	versions := history.Versions
+	for len(versions) > 1 {
+		current, previous := versions[len(versions)-1], versions[len(versions)-2]
+		if this.exists(previous) {
+			err := this.link(previous)
+			if err != nil {
+				return "", err
+			}
+			history.Versions = versions[:len(versions)-1]
+			history.Pinned = &rollbackPin{Version: previous, ListedVersion: listedVersion}
+			this.writeHistory(history)
+			log.Printf("Rolled back %s from version %s to version %s", this.localDirectory, current, previous)
+			return previous, nil
+		}
+		versions = append(versions[:len(versions)-2], current)
+	}
+	return "", fmt.Errorf("no previous version retained in %s", this.localDirectory)
+}
+
+// PinnedVersion is the (active) version to which the installation was rolled back, provided that
+// the dependency listing still names the version it named at the time. Otherwise it's empty.
+func (this *SideBySideInstallation) PinnedVersion(listedVersion string) string {
+	pinned := this.history().Pinned
+	if pinned == nil || pinned.ListedVersion != listedVersion || pinned.Version != this.ActiveVersion() {
+		return ""
+	}
+	return pinned.Version
+}
+
+func (this *SideBySideInstallation) link(version string) error {
+	if err := validateSideBySideVersion(version); err != nil {
+		return err
+	}
+	pending := filepath.Join(this.localDirectory, "."+sideBySideCurrent)
+	this.fileSystem.DeleteAll(pending)
+	this.fileSystem.CreateSymlink(filepath.Join(sideBySideVersions, version), pending)
+	return this.fileSystem.Rename(pending, this.CurrentPath())
+}
+
+func (this *SideBySideInstallation) exists(version string) bool {
+	names, err := this.fileSystem.ReadDirectory(this.VersionPath(version))
+	return err == nil && len(names) > 0
+}
+
+func (this *SideBySideInstallation) prune(history []string) {
+	names, err := this.fileSystem.ReadDirectory(filepath.Join(this.localDirectory, sideBySideVersions))
+	if err != nil {
+		return
+	}
+	for _, name := range names {
+		if name == sideBySideHistory || containsVersion(history, name) {
+			continue
+		}
+		log.Printf("Removing version %s from %s", name, this.localDirectory)
+		this.fileSystem.DeleteAll(this.VersionPath(name))
+	}
+}
+
+// history reads the order in which versions were activated (which was once recorded as a bare array).
+func (this *SideBySideInstallation) history() (history versionHistory) {
+	raw, err := this.fileSystem.ReadFile(this.historyPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return versionHistory{}
+	}
+	if err == nil && strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		err = json.Unmarshal(raw, &history.Versions)
+	} else if err == nil {
+		err = json.Unmarshal(raw, &history)
+	}
+	if err != nil {
+		log.Printf("[WARN] Unable to read the version history of %s: %s", this.localDirectory, err)
+	}
+	return history
+}
+
+func (this *SideBySideInstallation) writeHistory(history versionHistory) {
+	raw, _ := json.Marshal(history)
+	this.fileSystem.WriteFile(this.historyPath(), raw)
+}
+
+func (this *SideBySideInstallation) historyPath() string {
+	return filepath.Join(this.localDirectory, sideBySideVersions, sideBySideHistory)
+}
+
+func (this *SideBySideInstallation) removeFromHistory(history []string, version string) (filtered []string) {
+	for _, item := range history {
+		if item != version {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+type versionHistory struct {
+	Versions []string     `json:"versions"` // in the order activated (the active version last)
+	Pinned   *rollbackPin `json:"pinned,omitempty"`
+}
+
+// rollbackPin keeps the version to which a package was rolled back until the listed version changes.
+type rollbackPin struct {
+	Version       string `json:"version"`
+	ListedVersion string `json:"listed_version"`
+}
+
+// validateSideBySideVersion admits only versions which name a single directory within .versions
+// (the version comes from a remote manifest, so "../../etc" must not escape the local directory).
+func validateSideBySideVersion(version string) error {
+	if version == "" || version == "." || strings.Contains(version, "..") || version == sideBySideHistory ||
+		strings.ContainsAny(version, `/\`) || filepath.Base(version) != version || filepath.Clean(version) != version {
+		return fmt.Errorf("invalid version for a side-by-side installation: %q", version)
+	}
+	return nil
+}
+
+func containsVersion(versions []string, version string) bool {
+	for _, item := range versions {
+		if item == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+)
+
+func TestSideBySideInstallationFixture(t *testing.T) {
+	gunit.Run(new(SideBySideInstallationFixture), t)
+}
+
+type SideBySideInstallationFixture struct {
+	*gunit.Fixture
+	fileSystem   *inMemoryFileSystem
+	installation *SideBySideInstallation
+}
+
+func (this *SideBySideInstallationFixture) Setup() {
+	this.fileSystem = newInMemoryFileSystem()
+	this.installation = NewSideBySideInstallation(this.fileSystem, "local", 1)
+}
+
+func (this *SideBySideInstallationFixture) store(version string) {
+	this.fileSystem.WriteFile("staging/contents", []byte(version))
+	this.So(this.installation.Store("staging", version), should.BeNil)
+	this.So(this.installation.Activate(version), should.BeNil)
+}
+
+func (this *SideBySideInstallationFixture) TestActivatedVersionTargetedByCurrentSymlink() {
+	this.store("1")
+
+	this.So(this.fileSystem.fileSystem["local/current"].Symlink(), should.Equal, ".versions/1")
+	this.So(this.fileSystem.readFile("local/.versions/1/contents"), should.Resemble, []byte("1"))
+	this.So(this.installation.ActiveVersion(), should.Equal, "1")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "staging/contents")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/.current")
+}
+
+func (this *SideBySideInstallationFixture) TestNoActiveVersion() {
+	this.So(this.installation.ActiveVersion(), should.BeEmpty)
+	this.So(this.installation.CurrentPath(), should.Equal, "local/current")
+}
+
+func (this *SideBySideInstallationFixture) TestVersionsBeyondRetentionRemoved() {
+	this.store("1")
+	this.store("2")
+	this.store("3")
+
+	this.So(this.installation.ActiveVersion(), should.Equal, "3")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/.versions/2/contents")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/.versions/1/contents")
+}
+
+func (this *SideBySideInstallationFixture) TestRollbackActivatesPreviousVersion() {
+	this.store("1")
+	this.store("2")
+
+	version, err := this.installation.Rollback("1")
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "1")
+	this.So(this.installation.ActiveVersion(), should.Equal, "1")
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "local/.versions/2/contents")
+}
+
+func (this *SideBySideInstallationFixture) TestRollbackPinnedWhileListedVersionUnchanged() {
+	this.store("1")
+	this.store("2")
+
+	_, _ = this.installation.Rollback("latest")
+
+	this.So(this.installation.PinnedVersion("latest"), should.Equal, "1")
+	this.So(this.installation.PinnedVersion("2"), should.BeEmpty)
+}
+
+func (this *SideBySideInstallationFixture) TestActivationReleasesRollbackPin() {
+	this.store("1")
+	this.store("2")
+	_, _ = this.installation.Rollback("latest")
+
+	this.So(this.installation.Activate("2"), should.BeNil)
+
+	this.So(this.installation.PinnedVersion("latest"), should.BeEmpty)
+}
+
+func (this *SideBySideInstallationFixture) TestHistoryRecordedAsArrayStillRead() {
+	this.store("1")
+	this.store("2")
+	this.fileSystem.WriteFile("local/.versions/history.json", []byte(`["1","2"]`))
+
+	version, err := this.installation.Rollback("latest")
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "1")
+}
+
+func (this *SideBySideInstallationFixture) TestRepeatedRollbackExhaustsRetainedVersions() {
+	this.store("1")
+	this.store("2")
+	_, _ = this.installation.Rollback("1")
+
+	_, err := this.installation.Rollback("1")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.installation.ActiveVersion(), should.Equal, "1")
+}
+
+func (this *SideBySideInstallationFixture) TestRollbackWithoutPreviousVersion() {
+	this.store("1")
+
+	_, err := this.installation.Rollback("1")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.installation.ActiveVersion(), should.Equal, "1")
+}
+
+func (this *SideBySideInstallationFixture) TestRollbackSkipsVersionsNoLongerPresent() {
+	this.installation = NewSideBySideInstallation(this.fileSystem, "local", 2)
+	this.store("1")
+	this.store("2")
+	this.store("3")
+	this.fileSystem.DeleteAll("local/.versions/2")
+
+	version, err := this.installation.Rollback("1")
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "1")
+}
+
+func (this *SideBySideInstallationFixture) TestReactivatedVersionMovesToEndOfHistory() {
+	this.store("1")
+	this.store("2")
+	this.So(this.installation.Activate("1"), should.BeNil)
+
+	version, err := this.installation.Rollback("1")
+
+	this.So(err, should.BeNil)
+	this.So(version, should.Equal, "2")
+}
+
+func (this *SideBySideInstallationFixture) TestVersionsEscapingTheVersionsDirectoryRejected() {
+	this.fileSystem.WriteFile("staging/contents", []byte("contents"))
+
+	for _, version := range []string{"", ".", "..", "../../etc", "1.0/../..", `..\..\etc`, "a/b", "history.json"} {
+		this.So(this.installation.Store("staging", version), should.NotBeNil)
+		this.So(this.installation.Activate(version), should.NotBeNil)
+	}
+	this.So(this.fileSystem.fileSystem, should.ContainKey, "staging/contents")
+	this.So(this.fileSystem.fileSystem, should.NotContainKey, "local/current")
+}
+
+func (this *SideBySideInstallationFixture) TestActiveVersionNeverReplaced() {
+	this.store("1")
+	this.fileSystem.WriteFile("staging/contents", []byte("replacement"))
+
+	err := this.installation.Store("staging", "1")
+
+	this.So(err, should.NotBeNil)
+	this.So(this.fileSystem.readFile("local/.versions/1/contents"), should.Resemble, []byte("1"))
+}
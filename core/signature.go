@@ -0,0 +1,132 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+const signatureAlgorithm = "ed25519"
+
+// SignManifest produces the detached signature which is uploaded alongside the manifest.
+func SignManifest(key ed25519.PrivateKey, rawManifest []byte) []byte {
+	raw, _ := json.Marshal(contracts.ManifestSignature{
+		Algorithm: signatureAlgorithm,
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Signature: ed25519.Sign(key, rawManifest),
+	})
+	return raw
+}
+
+// KeyID identifies a public key by (the beginning of) its SHA-256 fingerprint.
+func KeyID(key ed25519.PublicKey) string {
+	fingerprint := sha256.Sum256(key)
+	return hex.EncodeToString(fingerprint[:8])
+}
+
+// EncodePublicKey renders a public key in the form expected by ParsePublicKey (and trusted_keys).
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParsePrivateKey reads a PEM-encoded PKCS #8 ed25519 private key (such as the output of
+// 'openssl genpkey -algorithm ed25519') or a base64-encoded seed (or private key).
+func ParsePrivateKey(encoded []byte) (ed25519.PrivateKey, error) {
+	if block, _ := pem.Decode(encoded); block != nil {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("not an ed25519 private key")
+		}
+		return key, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("malformed private key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("malformed private key: expected %d or %d bytes", ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+}
+
+// ParsePublicKey reads a base64-encoded ed25519 public key or a PEM-encoded PKIX public key (such as
+// the output of 'openssl pkey -pubout').
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode([]byte(encoded)); block != nil {
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("not an ed25519 public key")
+		}
+		return key, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("malformed public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("malformed public key: expected %d bytes", ed25519.PublicKeySize)
+	}
+	return raw, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// TrustedKeys are the public keys whose signatures are accepted (by key ID). When no keys are
+// trusted, manifests need not be signed at all.
+type TrustedKeys map[string]ed25519.PublicKey
+
+func NewTrustedKeys(encoded []string) (TrustedKeys, error) {
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+	keys := make(TrustedKeys, len(encoded))
+	for _, item := range encoded {
+		key, err := ParsePublicKey(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted key %q: %w", item, err)
+		}
+		keys[KeyID(key)] = key
+	}
+	return keys, nil
+}
+
+// Verify ensures the manifest was signed by one of the trusted keys.
+func (this TrustedKeys) Verify(rawManifest, rawSignature []byte) error {
+	var signature contracts.ManifestSignature
+	err := json.Unmarshal(rawSignature, &signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if signature.Algorithm != signatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm: %q", signature.Algorithm)
+	}
+	key, found := this[signature.KeyID]
+	if !found {
+		return fmt.Errorf("manifest signed by untrusted key: %s", signature.KeyID)
+	}
+	if !ed25519.Verify(key, rawManifest, signature.Signature) {
+		return fmt.Errorf("invalid signature by key %s", signature.KeyID)
+	}
+	return nil
+}
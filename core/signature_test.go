@@ -0,0 +1,117 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestSignatureFixture(t *testing.T) {
+	gunit.Run(new(SignatureFixture), t)
+}
+
+type SignatureFixture struct {
+	*gunit.Fixture
+
+	key      ed25519.PrivateKey
+	trusted  TrustedKeys
+	manifest []byte
+}
+
+func (this *SignatureFixture) Setup() {
+	this.key = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	this.trusted, _ = NewTrustedKeys([]string{EncodePublicKey(this.key.Public().(ed25519.PublicKey))})
+	this.manifest = []byte(`{"name":"package","version":"1.2.3"}`)
+}
+
+func (this *SignatureFixture) TestSignedManifestVerified() {
+	signature := SignManifest(this.key, this.manifest)
+
+	this.So(this.trusted.Verify(this.manifest, signature), should.BeNil)
+}
+
+func (this *SignatureFixture) TestAlteredManifestRejected() {
+	signature := SignManifest(this.key, this.manifest)
+
+	err := this.trusted.Verify([]byte(`{"name":"package","version":"6.6.6"}`), signature)
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.StartWith, "invalid signature")
+}
+
+func (this *SignatureFixture) TestUntrustedKeyRejected() {
+	other := ed25519.NewKeyFromSeed([]byte(strings.Repeat("x", ed25519.SeedSize)))
+	signature := SignManifest(other, this.manifest)
+
+	err := this.trusted.Verify(this.manifest, signature)
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.StartWith, "manifest signed by untrusted key")
+}
+
+func (this *SignatureFixture) TestMalformedSignatureRejected() {
+	this.So(this.trusted.Verify(this.manifest, []byte("not json")), should.NotBeNil)
+	this.So(this.trusted.Verify(this.manifest, []byte(`{"algorithm":"rsa"}`)), should.NotBeNil)
+}
+
+func (this *SignatureFixture) TestNoTrustedKeys() {
+	trusted, err := NewTrustedKeys(nil)
+
+	this.So(err, should.BeNil)
+	this.So(trusted, should.BeEmpty)
+}
+
+func (this *SignatureFixture) TestInvalidTrustedKey() {
+	trusted, err := NewTrustedKeys([]string{"bm90IGEga2V5"})
+
+	this.So(trusted, should.BeNil)
+	this.So(err, should.NotBeNil)
+}
+
+func (this *SignatureFixture) TestParsePrivateKeyFromPEM() {
+	raw, _ := x509.MarshalPKCS8PrivateKey(this.key)
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: raw})
+
+	key, err := ParsePrivateKey(encoded)
+
+	this.So(err, should.BeNil)
+	this.So(key, should.Resemble, this.key)
+}
+
+func (this *SignatureFixture) TestParsePrivateKeyFromBase64Seed() {
+	key, err := ParsePrivateKey([]byte(base64.StdEncoding.EncodeToString(this.key.Seed()) + "\n"))
+
+	this.So(err, should.BeNil)
+	this.So(key, should.Resemble, this.key)
+}
+
+func (this *SignatureFixture) TestParseMalformedPrivateKey() {
+	_, err := ParsePrivateKey([]byte("bm90IGEga2V5"))
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *SignatureFixture) TestParsePublicKeyFromPEM() {
+	public := this.key.Public().(ed25519.PublicKey)
+	raw, _ := x509.MarshalPKIXPublicKey(public)
+
+	key, err := ParsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: raw})))
+
+	this.So(err, should.BeNil)
+	this.So(key, should.Resemble, public)
+}
+
+func (this *SignatureFixture) TestSignatureAddress() {
+	address := url.URL{Scheme: "gcs", Host: "bucket", Path: "/package/1.2.3/manifest.json"}
+
+	signature := contracts.ComposeSignatureAddress(address)
+	this.So(signature.String(), should.Equal, "gcs://bucket/package/1.2.3/manifest.json.sig")
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -12,23 +13,31 @@ import (
 )
 
 type UploadConfigLoader struct {
-	parser  CredentialParser
-	storage contracts.FileReader
-	stdin   io.Reader
-	stderr  io.Writer
+	env         contracts.Environment
+	parser      CredentialParser
+	awsParser   AWSCredentialParser
+	azureParser AzureCredentialParser
+	ociParser   OCICredentialParser
+	storage     contracts.FileReader
+	stdin       io.Reader
+	stderr      io.Writer
 }
 
 func NewUploadConfigLoader(storage contracts.FileReader, env contracts.Environment, stdin io.Reader, stderr io.Writer) *UploadConfigLoader {
 	return &UploadConfigLoader{
-		parser:  NewGoogleCredentialParser(storage, env),
-		storage: storage,
-		stdin:   stdin,
-		stderr:  stderr,
+		env:         env,
+		parser:      NewGoogleCredentialParser(storage, env),
+		awsParser:   NewAWSCredentialParser(storage, env),
+		azureParser: NewAzureCredentialParser(env),
+		ociParser:   NewOCICredentialParser(env),
+		storage:     storage,
+		stdin:       stdin,
+		stderr:      stderr,
 	}
 }
 
 func (this *UploadConfigLoader) LoadConfig(name string, args []string) (config contracts.UploadConfig, err error) {
-	config, err = this.parseCLI(name, args)
+	config, signingKeyPath, err := this.parseCLI(name, args)
 	if err != nil {
 		return contracts.UploadConfig{}, err
 	}
@@ -38,12 +47,17 @@ func (this *UploadConfigLoader) LoadConfig(name string, args []string) (config c
 		return contracts.UploadConfig{}, err
 	}
 
-	config.GoogleCredentials, err = this.parser.Parse()
+	err = this.validateConfigJsonValues(config)
 	if err != nil {
 		return contracts.UploadConfig{}, err
 	}
 
-	err = this.validateConfigJsonValues(config)
+	err = this.parseCredentials(&config)
+	if err != nil {
+		return contracts.UploadConfig{}, err
+	}
+
+	config.SigningKey, err = this.parseSigningKey(signingKeyPath)
 	if err != nil {
 		return contracts.UploadConfig{}, err
 	}
@@ -51,7 +65,45 @@ func (this *UploadConfigLoader) LoadConfig(name string, args []string) (config c
 	return config, nil
 }
 
-func (this *UploadConfigLoader) parseCLI(name string, args []string) (config contracts.UploadConfig, err error) {
+func (this *UploadConfigLoader) parseCredentials(config *contracts.UploadConfig) (err error) {
+	switch config.PackageConfig.RemoteAddressPrefix.Scheme {
+	case "gcs":
+		config.GoogleCredentials, err = this.parser.Parse()
+	case "s3":
+		config.AWSCredentials, err = this.awsParser.Parse()
+	case "azblob":
+		config.AzureCredentials, err = this.azureParser.Parse()
+	case "oci":
+		config.OCICredentials = this.ociParser.Parse()
+	}
+	return err
+}
+
+// parseSigningKey reads the key with which to sign the manifest from the file (if specified) or from
+// the SATISFY_SIGNING_KEY environment variable (if set); otherwise the manifest isn't signed.
+func (this *UploadConfigLoader) parseSigningKey(path string) (ed25519.PrivateKey, error) {
+	value, _ := this.env.LookupEnv(signingKeyEnvironmentVariable)
+	encoded := []byte(value)
+	if path != "" {
+		var err error
+		encoded, err = this.storage.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+	key, err := ParsePrivateKey(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key: %w", err)
+	}
+	return key, nil
+}
+
+const signingKeyEnvironmentVariable = "SATISFY_SIGNING_KEY"
+
+func (this *UploadConfigLoader) parseCLI(name string, args []string) (config contracts.UploadConfig, signingKeyPath string, err error) {
 	flags := flag.NewFlagSet("satisfy "+name, flag.ContinueOnError)
 	flags.SetOutput(this.stderr)
 	flags.StringVar(&config.JSONPath,
@@ -64,11 +116,39 @@ func (this *UploadConfigLoader) parseCLI(name string, args []string) (config con
 		5,
 		"HTTP max retry.",
 	)
+	chunkSize := flags.Int64("chunk-size",
+		16,
+		"The size (in MiB) of each chunk of a resumable upload; smaller archives are uploaded in a single request\n"+
+			"(0 always uploads in a single request; s3 requires at least 5).",
+	)
+	flags.StringVar(&config.HashAlgorithm,
+		"hash",
+		"sha256",
+		"The hash algorithm of the checksums recorded in the manifest in addition to MD5 (sha256 or sha512).",
+	)
 	flags.BoolVar(&config.Overwrite,
 		"overwrite",
 		false,
 		"When set, always upload package, even when it already exists at specified remote location.",
 	)
+	flags.BoolVar(&config.ArchivePool,
+		"pool",
+		false,
+		"When set, store the archive in the content-addressed pool shared by all packages under the remote address\n"+
+			"(identical archives are uploaded only once; requires a version of satisfy that honors archive locations).",
+	)
+	flags.BoolVar(&config.Stream,
+		"stream",
+		false,
+		"When set, stream the archive to remote storage (in chunks) as it is built rather than writing it to a\n"+
+			"temporary file first (supported for gcs and s3 remote addresses; not compatible with -pool).",
+	)
+	flags.StringVar(&signingKeyPath,
+		"signing-key",
+		"",
+		"Path to the ed25519 private key (PEM, such as from 'openssl genpkey -algorithm ed25519') with which to sign\n"+
+			"the manifest (defaults to the contents of the "+signingKeyEnvironmentVariable+" environment variable, if set).",
+	)
 	flags.Usage = func() {
 		_, _ = fmt.Fprintf(this.stderr, "Usage of satisfy %s:", name)
 		flags.PrintDefaults()
@@ -78,8 +158,9 @@ exit code 1: general failure (see stderr for details)
 exit code 2: package has already been uploaded`)
 	}
 	err = flags.Parse(args)
+	config.ChunkSize = *chunkSize << 20
 
-	return config, err
+	return config, signingKeyPath, err
 }
 
 func (this *UploadConfigLoader) parseConfigFile(path string) (config contracts.PackageConfig, err error) {
@@ -105,6 +186,12 @@ func (this *UploadConfigLoader) validateConfigJsonValues(config contracts.Upload
 	if config.MaxRetry < 0 {
 		return maxRetryErr
 	}
+	if config.ChunkSize < 0 {
+		return chunkSizeErr
+	}
+	if _, found := LookupHashAlgorithm(config.HashAlgorithm); !found {
+		return unsupportedHashAlgorithmErr
+	}
 	if config.PackageConfig.CompressionAlgorithm == "" {
 		return blankCompressionAlgorithmErr
 	}
@@ -120,15 +207,61 @@ func (this *UploadConfigLoader) validateConfigJsonValues(config contracts.Upload
 	if config.PackageConfig.RemoteAddressPrefix == nil {
 		return nilRemoteAddressPrefixErr
 	}
+	if config.ChunkSize > 0 && config.ChunkSize < minimumS3ChunkSize && config.PackageConfig.RemoteAddressPrefix.Scheme == "s3" {
+		return s3ChunkSizeErr
+	}
+	if config.ArchivePool && config.PackageConfig.RemoteAddressPrefix.Scheme == "oci" {
+		return unsupportedArchivePoolErr
+	}
+	if config.Stream {
+		if err := validateStream(config); err != nil {
+			return err
+		}
+	}
+	if config.PackageConfig.Zstd != (contracts.ZstdOptions{}) && config.PackageConfig.CompressionAlgorithm != "zstd" {
+		return zstdOptionsWithoutZstdErr
+	}
+	if config.PackageConfig.Zstd.Dictionary != "" && config.PackageConfig.RemoteAddressPrefix.Scheme == "oci" {
+		return unsupportedDictionaryErr
+	}
 	return nil
 }
 
+// validateStream ensures the archive can be uploaded before its size and checksum are known,
+// which requires upload sessions and an archive address which doesn't depend on the checksum.
+func validateStream(config contracts.UploadConfig) error {
+	if config.ArchivePool {
+		return streamArchivePoolErr
+	}
+	if config.ChunkSize == 0 {
+		return streamChunkSizeErr
+	}
+	switch config.PackageConfig.RemoteAddressPrefix.Scheme {
+	case "gcs", "s3":
+		return nil
+	default:
+		return unsupportedStreamErr
+	}
+}
+
+// minimumS3ChunkSize is the smallest part (other than the final part) of an S3 multipart upload.
+const minimumS3ChunkSize = 5 << 20
+
 var (
 	maxRetryErr                  = errors.New("max-retry must be positive")
+	chunkSizeErr                 = errors.New("chunk-size must not be negative")
+	unsupportedHashAlgorithmErr  = errors.New("unsupported hash algorithm")
+	s3ChunkSizeErr               = errors.New("chunk-size must be at least 5 (MiB) for s3 remote addresses")
 	blankJSONPathErr             = errors.New("json flag must be populated")
 	blankCompressionAlgorithmErr = errors.New("compression algorithm should not be blank")
 	blankSourceDirectoryErr      = errors.New("source directory should not be blank")
 	blankPackageNameErr          = errors.New("package name should not be blank")
 	blankPackageVersionErr       = errors.New("package version should not be blank")
 	nilRemoteAddressPrefixErr    = errors.New("remote address prefix should not be nil")
+	unsupportedArchivePoolErr    = errors.New("archive pool is not supported for oci remote addresses (registries already deduplicate archives)")
+	zstdOptionsWithoutZstdErr    = errors.New("zstd options require the zstd compression algorithm")
+	unsupportedDictionaryErr     = errors.New("compression dictionaries are not supported for oci remote addresses")
+	streamArchivePoolErr         = errors.New("stream is not compatible with the archive pool (the pool location depends on the checksum of the archive)")
+	streamChunkSizeErr           = errors.New("stream requires a positive chunk-size")
+	unsupportedStreamErr         = errors.New("stream is only supported for gcs and s3 remote addresses")
 )
@@ -2,6 +2,8 @@ package core
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"io/ioutil"
 	"strings"
@@ -61,12 +63,170 @@ func (this *UploadConfigLoaderFixture) TestValidJSONFromSpecifiedFile() {
 	this.So(config, should.Resemble, contracts.UploadConfig{
 		GoogleCredentials: parsedGoogleCredentials,
 		MaxRetry:          10,
+		ChunkSize:         16 << 20,
+		HashAlgorithm:     "sha256",
 		JSONPath:          "config.json",
 		Overwrite:         true,
 		PackageConfig:     packageConfig,
 	})
 }
 
+func (this *UploadConfigLoaderFixture) TestArchivePoolEnabled() {
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-pool"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.BeNil)
+	this.So(config.ArchivePool, should.BeTrue)
+}
+
+func (this *UploadConfigLoaderFixture) TestChunkSizeInMebibytes() {
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-chunk-size", "8"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.BeNil)
+	this.So(config.ChunkSize, should.Equal, 8<<20)
+}
+
+func (this *UploadConfigLoaderFixture) TestNegativeChunkSize() {
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-chunk-size", "-1"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.Equal, chunkSizeErr)
+	this.So(config, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestChunkSizeTooSmallForS3() {
+	this.pkgConfig.RemoteAddressPrefix = &contracts.URL{Scheme: "s3", Host: "bucket", Path: "/packages"}
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-chunk-size", "4"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.Equal, s3ChunkSizeErr)
+	this.So(config, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestHashAlgorithm() {
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-hash", "sha512"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.BeNil)
+	this.So(config.HashAlgorithm, should.Equal, "sha512")
+}
+
+func (this *UploadConfigLoaderFixture) TestUnsupportedHashAlgorithm() {
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-hash", "crc32"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.Equal, unsupportedHashAlgorithmErr)
+	this.So(config, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestSigningKeyFromSpecifiedFile() {
+	_ = this.prepareValidJSONConfigFile()
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	this.storage.WriteFile("signing.key", []byte(base64.StdEncoding.EncodeToString(key.Seed())))
+	args := []string{"-json", "config.json", "-signing-key", "signing.key"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.BeNil)
+	this.So(config.SigningKey, should.Resemble, key)
+}
+
+func (this *UploadConfigLoaderFixture) TestSigningKeyFromEnvironment() {
+	_ = this.prepareValidJSONConfigFile()
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	this.environment["SATISFY_SIGNING_KEY"] = base64.StdEncoding.EncodeToString(key.Seed())
+
+	config, err := this.loader.LoadConfig("upload", []string{"-json", "config.json"})
+
+	this.So(err, should.BeNil)
+	this.So(config.SigningKey, should.Resemble, key)
+}
+
+func (this *UploadConfigLoaderFixture) TestSigningKeyFileIsMissing() {
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-signing-key", "missing.key"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.NotBeNil)
+	this.So(config, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestSigningKeyIsMalformed() {
+	_ = this.prepareValidJSONConfigFile()
+	this.environment["SATISFY_SIGNING_KEY"] = "not a key"
+
+	config, err := this.loader.LoadConfig("upload", []string{"-json", "config.json"})
+
+	this.So(err, should.NotBeNil)
+	this.So(config, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestStreamEnabled() {
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-stream"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.BeNil)
+	this.So(config.Stream, should.BeTrue)
+}
+
+func (this *UploadConfigLoaderFixture) TestStreamNotCompatibleWithArchivePool() {
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-stream", "-pool"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.Equal, streamArchivePoolErr)
+	this.So(config, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestStreamRequiresChunkSize() {
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-stream", "-chunk-size", "0"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.Equal, streamChunkSizeErr)
+	this.So(config, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestStreamNotSupportedForAzureRemoteAddress() {
+	this.pkgConfig.RemoteAddressPrefix = &contracts.URL{Scheme: "azblob", Host: "account", Path: "/container"}
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-stream"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.Equal, unsupportedStreamErr)
+	this.So(config, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestArchivePoolNotSupportedForOCIRemoteAddress() {
+	this.pkgConfig.RemoteAddressPrefix = &contracts.URL{Scheme: "oci", Host: "registry.example.com", Path: "/packages"}
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json", "-pool"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.Equal, unsupportedArchivePoolErr)
+	this.So(config, should.BeZeroValue)
+}
+
 func (this *UploadConfigLoaderFixture) TestInValidJSONFromSpecifiedFile() {
 	this.storage.WriteFile("config.json", []byte("Invalid JSON"))
 	args := []string{"-json", "config.json"}
@@ -151,6 +311,66 @@ func (this *UploadConfigLoaderFixture) TestGoogleCredentialsFileIsMalformed() {
 	this.So(config.GoogleCredentials, should.BeZeroValue)
 }
 
+func (this *UploadConfigLoaderFixture) TestAWSCredentialsParsedForS3RemoteAddress() {
+	delete(this.environment, "GOOGLE_APPLICATION_CREDENTIALS")
+	this.environment["AWS_ACCESS_KEY_ID"] = "access"
+	this.environment["AWS_SECRET_ACCESS_KEY"] = "secret"
+	this.pkgConfig.RemoteAddressPrefix = &contracts.URL{Scheme: "s3", Host: "bucket", Path: "/path"}
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.BeNil)
+	this.So(config.GoogleCredentials, should.BeZeroValue)
+	this.So(config.AWSCredentials, should.Resemble, contracts.AWSCredentials{
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+		Region:          "us-east-1",
+	})
+}
+
+func (this *UploadConfigLoaderFixture) TestAWSCredentialsMissingForS3RemoteAddress() {
+	this.pkgConfig.RemoteAddressPrefix = &contracts.URL{Scheme: "s3", Host: "bucket", Path: "/path"}
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.NotBeNil)
+	this.So(config, should.BeZeroValue)
+}
+
+func (this *UploadConfigLoaderFixture) TestAzureCredentialsParsedForAzureRemoteAddress() {
+	delete(this.environment, "GOOGLE_APPLICATION_CREDENTIALS")
+	this.environment["AZURE_STORAGE_ACCOUNT"] = "account"
+	this.environment["AZURE_STORAGE_KEY"] = "a2V5"
+	this.pkgConfig.RemoteAddressPrefix = &contracts.URL{Scheme: "azblob", Host: "account", Path: "/container/path"}
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.BeNil)
+	this.So(config.GoogleCredentials, should.BeZeroValue)
+	this.So(config.AzureCredentials, should.Resemble, contracts.AzureCredentials{AccountName: "account", AccountKey: "a2V5"})
+}
+
+func (this *UploadConfigLoaderFixture) TestOCICredentialsParsedForOCIRemoteAddress() {
+	delete(this.environment, "GOOGLE_APPLICATION_CREDENTIALS")
+	this.environment["SATISFY_OCI_USERNAME"] = "username"
+	this.environment["SATISFY_OCI_PASSWORD"] = "password"
+	this.pkgConfig.RemoteAddressPrefix = &contracts.URL{Scheme: "oci", Host: "registry.example.com", Path: "/packages"}
+	_ = this.prepareValidJSONConfigFile()
+	args := []string{"-json", "config.json"}
+
+	config, err := this.loader.LoadConfig("upload", args)
+
+	this.So(err, should.BeNil)
+	this.So(config.GoogleCredentials, should.BeZeroValue)
+	this.So(config.OCICredentials, should.Resemble, contracts.OCICredentials{Username: "username", Password: "password"})
+}
+
 func (this *UploadConfigLoaderFixture) TestValidateJSONNegativeMaxRetries() {
 	_ = this.prepareValidJSONConfigFile()
 	args := []string{
@@ -223,6 +443,38 @@ func (this *UploadConfigLoaderFixture) TestValidateRemoteAddressPrefixIsNotNil()
 	this.So(err, should.Resemble, nilRemoteAddressPrefixErr)
 }
 
+func (this *UploadConfigLoaderFixture) TestZstdOptionsLoaded() {
+	this.pkgConfig.CompressionAlgorithm = "zstd"
+	this.pkgConfig.Zstd = contracts.ZstdOptions{WindowSize: 1 << 20, Long: true, Concurrency: 4, Dictionary: "dictionary"}
+	_ = this.prepareValidJSONConfigFile()
+
+	config, err := this.loader.LoadConfig("upload", []string{"-json", "config.json"})
+
+	this.So(err, should.BeNil)
+	this.So(config.PackageConfig.Zstd, should.Resemble, this.pkgConfig.Zstd)
+}
+
+func (this *UploadConfigLoaderFixture) TestValidateZstdOptionsRequireZstdCompression() {
+	this.pkgConfig.CompressionAlgorithm = "gzip"
+	this.pkgConfig.Zstd = contracts.ZstdOptions{Long: true}
+	_ = this.prepareValidJSONConfigFile()
+
+	_, err := this.loader.LoadConfig("upload", []string{"-json", "config.json"})
+
+	this.So(err, should.Equal, zstdOptionsWithoutZstdErr)
+}
+
+func (this *UploadConfigLoaderFixture) TestDictionaryNotSupportedForOCIRemoteAddress() {
+	this.pkgConfig.CompressionAlgorithm = "zstd"
+	this.pkgConfig.Zstd = contracts.ZstdOptions{Dictionary: "dictionary"}
+	this.pkgConfig.RemoteAddressPrefix = &contracts.URL{Scheme: "oci", Host: "registry.example.com", Path: "/packages"}
+	_ = this.prepareValidJSONConfigFile()
+
+	_, err := this.loader.LoadConfig("upload", []string{"-json", "config.json"})
+
+	this.So(err, should.Equal, unsupportedDictionaryErr)
+}
+
 func (this *UploadConfigLoaderFixture) prepareValidJSONConfigFile() contracts.PackageConfig {
 	packageConfig := this.pkgConfig.configure()
 	raw, _ := json.Marshal(packageConfig)
@@ -238,6 +490,7 @@ type FakePackageConfig struct {
 	PackageName          string
 	PackageVersion       string
 	RemoteAddressPrefix  *contracts.URL
+	Zstd                 contracts.ZstdOptions
 }
 
 func NewFakePackageConfig() *FakePackageConfig {
@@ -258,6 +511,7 @@ func (this *FakePackageConfig) configure() contracts.PackageConfig {
 		PackageName:          this.PackageName,
 		PackageVersion:       this.PackageVersion,
 		RemoteAddressPrefix:  this.RemoteAddressPrefix,
+		Zstd:                 this.Zstd,
 	}
 }
 
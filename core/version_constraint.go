@@ -0,0 +1,250 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IsVersionConstraint distinguishes a version constraint (such as "^1.4") from a specific version
+// (or "latest") in a dependency listing.
+func IsVersionConstraint(version string) bool {
+	return strings.ContainsAny(version, "^~<>=| ")
+}
+
+// VersionConstraint selects those published (semantic) versions of a package which satisfy ranges
+// such as "^1.4", "~2.3.1" or ">=1.0 <2.0", where "||" separates alternative ranges. As with npm,
+// pre-release versions are only selected by a range which names a pre-release of the same version.
+type VersionConstraint struct {
+	ranges [][]versionComparator
+}
+
+func ParseVersionConstraint(value string) (constraint VersionConstraint, err error) {
+	for _, alternative := range strings.Split(value, "||") {
+		comparators, err := parseVersionRange(alternative)
+		if err != nil {
+			return VersionConstraint{}, fmt.Errorf("invalid version constraint %q: %w", value, err)
+		}
+		constraint.ranges = append(constraint.ranges, comparators)
+	}
+	return constraint, nil
+}
+
+// Highest selects the highest of the versions which satisfies the constraint. Versions which aren't
+// semantic versions are never selected.
+func (this VersionConstraint) Highest(versions []string) (highest string, found bool) {
+	var best semanticVersion
+	for _, value := range versions {
+		version, _, err := parseSemanticVersion(value)
+		if err != nil || !this.matches(version) {
+			continue
+		}
+		if !found || version.compare(best) > 0 {
+			highest, best, found = value, version, true
+		}
+	}
+	return highest, found
+}
+
+func (this VersionConstraint) matches(version semanticVersion) bool {
+	for _, comparators := range this.ranges {
+		if satisfiesRange(comparators, version) {
+			return true
+		}
+	}
+	return false
+}
+
+func satisfiesRange(comparators []versionComparator, version semanticVersion) bool {
+	allowPrerelease := version.prerelease == ""
+	for _, comparator := range comparators {
+		if !comparator.satisfied(version) {
+			return false
+		}
+		if comparator.version.prerelease != "" && comparator.version.sameRelease(version) {
+			allowPrerelease = true
+		}
+	}
+	return allowPrerelease
+}
+
+func parseVersionRange(value string) (comparators []versionComparator, err error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, errors.New("empty version range")
+	}
+	for x := 0; x < len(fields); x++ {
+		token := fields[x]
+		if strings.Trim(token, "^~<>=") == "" && x+1 < len(fields) {
+			x++
+			token += fields[x] // an operator separated from its version (">= 1.0")
+		}
+		parsed, err := parseVersionComparators(token)
+		if err != nil {
+			return nil, err
+		}
+		comparators = append(comparators, parsed...)
+	}
+	return comparators, nil
+}
+
+func parseVersionComparators(token string) ([]versionComparator, error) {
+	operator := token[:len(token)-len(strings.TrimLeft(token, "^~<>="))]
+	version, parts, err := parseSemanticVersion(token[len(operator):])
+	if err != nil {
+		return nil, err
+	}
+	switch operator {
+	case "^":
+		return []versionComparator{{">=", version}, {"<", version.nextCompatible(parts)}}, nil
+	case "~":
+		return []versionComparator{{">=", version}, {"<", version.nextPatchRange(parts)}}, nil
+	case ">=", ">", "<=", "<":
+		return []versionComparator{{operator, version}}, nil
+	case "", "=":
+		if parts < 3 { // "1.4" is any 1.4.x version (and "1" any 1.x.x version)
+			return []versionComparator{{">=", version}, {"<", version.increment(parts - 1)}}, nil
+		}
+		return []versionComparator{{"=", version}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operator %q", operator)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type versionComparator struct {
+	operator string
+	version  semanticVersion
+}
+
+func (this versionComparator) satisfied(version semanticVersion) bool {
+	comparison := version.compare(this.version)
+	switch this.operator {
+	case ">=":
+		return comparison >= 0
+	case ">":
+		return comparison > 0
+	case "<=":
+		return comparison <= 0
+	case "<":
+		return comparison < 0
+	default:
+		return comparison == 0
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type semanticVersion struct {
+	release    [3]uint64
+	prerelease string
+}
+
+// parseSemanticVersion accepts an optional 'v' prefix and ignores build metadata. The major, minor
+// and patch versions default to zero when omitted (parts counts those present).
+func parseSemanticVersion(value string) (version semanticVersion, parts int, err error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if plus := strings.Index(value, "+"); plus >= 0 {
+		value = value[:plus]
+	}
+	if hyphen := strings.Index(value, "-"); hyphen >= 0 {
+		value, version.prerelease = value[:hyphen], value[hyphen+1:]
+		if version.prerelease == "" {
+			return semanticVersion{}, 0, fmt.Errorf("invalid version %q: empty pre-release", value)
+		}
+	}
+	segments := strings.Split(value, ".")
+	if len(segments) > len(version.release) {
+		return semanticVersion{}, 0, fmt.Errorf("invalid version %q", value)
+	}
+	for x, segment := range segments {
+		version.release[x], err = strconv.ParseUint(segment, 10, 64)
+		if err != nil {
+			return semanticVersion{}, 0, fmt.Errorf("invalid version %q", value)
+		}
+	}
+	return version, len(segments), nil
+}
+
+// nextCompatible is the lowest version excluded by a caret range: the next version which increments
+// the left-most non-zero part of the version (or the last part specified, if all are zero).
+func (this semanticVersion) nextCompatible(parts int) semanticVersion {
+	for x := 0; x < parts-1; x++ {
+		if this.release[x] > 0 {
+			return this.increment(x)
+		}
+	}
+	return this.increment(parts - 1)
+}
+
+// nextPatchRange is the lowest version excluded by a tilde range: the next minor version (or the next
+// major version when only the major version is specified).
+func (this semanticVersion) nextPatchRange(parts int) semanticVersion {
+	if parts < 2 {
+		return this.increment(0)
+	}
+	return this.increment(1)
+}
+
+func (this semanticVersion) increment(part int) (next semanticVersion) {
+	copy(next.release[:part], this.release[:part])
+	next.release[part] = this.release[part] + 1
+	next.prerelease = "0" // excludes the pre-releases of the next version, as does npm
+	return next
+}
+
+func (this semanticVersion) sameRelease(that semanticVersion) bool {
+	return this.release == that.release
+}
+
+func (this semanticVersion) compare(that semanticVersion) int {
+	for x := range this.release {
+		if this.release[x] != that.release[x] {
+			if this.release[x] < that.release[x] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case this.prerelease == that.prerelease:
+		return 0
+	case this.prerelease == "":
+		return 1
+	case that.prerelease == "":
+		return -1
+	}
+	return comparePrereleases(this.prerelease, that.prerelease)
+}
+
+// comparePrereleases orders dot-separated pre-release identifiers, numeric identifiers numerically
+// (and before alphanumeric identifiers), as specified by semver.org.
+func comparePrereleases(a, b string) int {
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for x := 0; x < len(left) && x < len(right); x++ {
+		leftNumber, leftErr := strconv.ParseUint(left[x], 10, 64)
+		rightNumber, rightErr := strconv.ParseUint(right[x], 10, 64)
+		switch {
+		case leftErr == nil && rightErr == nil && leftNumber != rightNumber:
+			if leftNumber < rightNumber {
+				return -1
+			}
+			return 1
+		case leftErr == nil && rightErr != nil:
+			return -1
+		case leftErr != nil && rightErr == nil:
+			return 1
+		case leftErr != nil && rightErr != nil && left[x] != right[x]:
+			return strings.Compare(left[x], right[x])
+		}
+	}
+	switch {
+	case len(left) < len(right):
+		return -1
+	case len(left) > len(right):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+)
+
+func TestVersionConstraintFixture(t *testing.T) {
+	gunit.Run(new(VersionConstraintFixture), t)
+}
+
+type VersionConstraintFixture struct {
+	*gunit.Fixture
+}
+
+var publishedVersions = []string{
+	"0.2.3", "0.2.9", "0.3.0", "1.0.0", "1.4.0", "1.4.7", "1.10.2",
+	"2.0.0-beta.2", "2.0.0-beta.10", "2.3.1", "2.3.4", "2.4.0", "v3.0.0", "nightly-20200101",
+}
+
+func (this *VersionConstraintFixture) highest(value string) string {
+	constraint, err := ParseVersionConstraint(value)
+	this.So(err, should.BeNil)
+	highest, _ := constraint.Highest(publishedVersions)
+	return highest
+}
+
+func (this *VersionConstraintFixture) TestConstraintsDistinguishedFromVersions() {
+	this.So(IsVersionConstraint("^1.4"), should.BeTrue)
+	this.So(IsVersionConstraint("~2.3.1"), should.BeTrue)
+	this.So(IsVersionConstraint(">=1.0 <2.0"), should.BeTrue)
+	this.So(IsVersionConstraint("1.4.7"), should.BeFalse)
+	this.So(IsVersionConstraint("latest"), should.BeFalse)
+}
+
+func (this *VersionConstraintFixture) TestCaretRange() {
+	this.So(this.highest("^1.4"), should.Equal, "1.10.2")
+	this.So(this.highest("^0.2.3"), should.Equal, "0.2.9")
+	this.So(this.highest("^0.2"), should.Equal, "0.2.9")
+	this.So(this.highest("^0"), should.Equal, "0.3.0")
+}
+
+func (this *VersionConstraintFixture) TestTildeRange() {
+	this.So(this.highest("~2.3.1"), should.Equal, "2.3.4")
+	this.So(this.highest("~1.4"), should.Equal, "1.4.7")
+	this.So(this.highest("~1"), should.Equal, "1.10.2")
+}
+
+func (this *VersionConstraintFixture) TestComparisons() {
+	this.So(this.highest(">=1.0 <2.0"), should.Equal, "1.10.2")
+	this.So(this.highest(">= 1.0 < 1.4.7"), should.Equal, "1.4.0")
+	this.So(this.highest(">1.4.7 <=2.4.0"), should.Equal, "2.4.0")
+	this.So(this.highest(">=2.4"), should.Equal, "v3.0.0")
+	this.So(this.highest("=1.4.0"), should.Equal, "1.4.0")
+}
+
+func (this *VersionConstraintFixture) TestPartialVersionMatchesAnyPatch() {
+	this.So(this.highest(">=1.0 1.4"), should.Equal, "1.4.7")
+}
+
+func (this *VersionConstraintFixture) TestAlternativeRanges() {
+	this.So(this.highest("~0.2 || ~1.4"), should.Equal, "1.4.7")
+}
+
+func (this *VersionConstraintFixture) TestPrereleasesOnlySelectedWhenNamed() {
+	this.So(this.highest(">=1.10 <2.3"), should.Equal, "1.10.2")
+	this.So(this.highest(">=2.0.0-beta.1 <2.3"), should.Equal, "2.0.0-beta.10")
+	this.So(this.highest("^1.4 || >=2.0.0-beta <2.0.0-beta.5"), should.Equal, "2.0.0-beta.2")
+}
+
+func (this *VersionConstraintFixture) TestNoVersionSatisfiesConstraint() {
+	constraint, _ := ParseVersionConstraint("^4")
+
+	_, found := constraint.Highest(publishedVersions)
+
+	this.So(found, should.BeFalse)
+}
+
+func (this *VersionConstraintFixture) TestMalformedConstraintsRejected() {
+	for _, value := range []string{"^", "^1.x", ">=1.0 ||", "~>1.2", "1.2.3.4 <2", ">=1.0-"} {
+		_, err := ParseVersionConstraint(value)
+		this.So(err, should.NotBeNil)
+	}
+}
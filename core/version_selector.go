@@ -0,0 +1,106 @@
+package core
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VersionSelector picks package versions by name ("1.2.3,1.2.4" or "latest"), by inclusive
+// range ("1.2.0..1.4.0", either bound may be omitted) or altogether ("all").
+type VersionSelector struct {
+	all      bool
+	ranged   bool
+	from     string
+	to       string
+	versions []string
+}
+
+func ParseVersionSelector(value string) (selector VersionSelector, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return VersionSelector{}, errors.New("version selection is required")
+	}
+	if value == "all" {
+		return VersionSelector{all: true}, nil
+	}
+	if strings.Contains(value, "..") {
+		bounds := strings.SplitN(value, "..", 2)
+		selector = VersionSelector{ranged: true, from: strings.TrimSpace(bounds[0]), to: strings.TrimSpace(bounds[1])}
+		if selector.from == "" && selector.to == "" {
+			return VersionSelector{}, errors.New("version range requires at least one bound")
+		}
+		return selector, nil
+	}
+	for _, version := range strings.Split(value, ",") {
+		if version = strings.TrimSpace(version); version != "" {
+			selector.versions = append(selector.versions, version)
+		}
+	}
+	return selector, nil
+}
+
+// RequiresListing indicates whether the available versions must be enumerated before selection.
+func (this VersionSelector) RequiresListing() bool {
+	return this.all || this.ranged
+}
+
+// Select returns the chosen versions (in ascending order when chosen from those available).
+func (this VersionSelector) Select(available []string) (selected []string) {
+	if !this.RequiresListing() {
+		return this.versions
+	}
+	for _, version := range available {
+		if this.all || this.inRange(version) {
+			selected = append(selected, version)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return CompareVersions(selected[i], selected[j]) < 0 })
+	return selected
+}
+
+func (this VersionSelector) inRange(version string) bool {
+	if this.from != "" && CompareVersions(version, this.from) < 0 {
+		return false
+	}
+	if this.to != "" && CompareVersions(version, this.to) > 0 {
+		return false
+	}
+	return true
+}
+
+// CompareVersions orders dotted version strings (an optional 'v' prefix is ignored), comparing
+// numeric segments numerically and all other segments lexically.
+func CompareVersions(a, b string) int {
+	left := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	right := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for x := 0; x < len(left) || x < len(right); x++ {
+		if x >= len(left) {
+			return -1
+		}
+		if x >= len(right) {
+			return 1
+		}
+		if comparison := compareVersionSegments(left[x], right[x]); comparison != 0 {
+			return comparison
+		}
+	}
+	return 0
+}
+
+func compareVersionSegments(a, b string) int {
+	left, leftErr := strconv.ParseUint(a, 10, 64)
+	right, rightErr := strconv.ParseUint(b, 10, 64)
+	if leftErr == nil && rightErr == nil {
+		switch {
+		case left < right:
+			return -1
+		case left > right:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+)
+
+func TestVersionSelectorFixture(t *testing.T) {
+	gunit.Run(new(VersionSelectorFixture), t)
+}
+
+type VersionSelectorFixture struct {
+	*gunit.Fixture
+}
+
+var availableVersions = []string{"1.10.0", "1.2.0", "1.9.1", "0.9.0", "2.0.0"}
+
+func (this *VersionSelectorFixture) TestBlankSelectionRejected() {
+	_, err := ParseVersionSelector(" ")
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *VersionSelectorFixture) TestExplicitVersionsDoNotRequireListing() {
+	selector, err := ParseVersionSelector("1.2.3, 1.2.4")
+
+	this.So(err, should.BeNil)
+	this.So(selector.RequiresListing(), should.BeFalse)
+	this.So(selector.Select(nil), should.Resemble, []string{"1.2.3", "1.2.4"})
+}
+
+func (this *VersionSelectorFixture) TestAllVersionsSelectedInOrder() {
+	selector, err := ParseVersionSelector("all")
+
+	this.So(err, should.BeNil)
+	this.So(selector.RequiresListing(), should.BeTrue)
+	this.So(selector.Select(availableVersions), should.Resemble, []string{"0.9.0", "1.2.0", "1.9.1", "1.10.0", "2.0.0"})
+}
+
+func (this *VersionSelectorFixture) TestInclusiveRange() {
+	selector, err := ParseVersionSelector("1.2.0..1.10.0")
+
+	this.So(err, should.BeNil)
+	this.So(selector.Select(availableVersions), should.Resemble, []string{"1.2.0", "1.9.1", "1.10.0"})
+}
+
+func (this *VersionSelectorFixture) TestOpenEndedRanges() {
+	from, _ := ParseVersionSelector("1.9.1..")
+	to, _ := ParseVersionSelector("..1.2.0")
+
+	this.So(from.Select(availableVersions), should.Resemble, []string{"1.9.1", "1.10.0", "2.0.0"})
+	this.So(to.Select(availableVersions), should.Resemble, []string{"0.9.0", "1.2.0"})
+}
+
+func (this *VersionSelectorFixture) TestRangeWithoutBoundsRejected() {
+	_, err := ParseVersionSelector("..")
+
+	this.So(err, should.NotBeNil)
+}
+
+func (this *VersionSelectorFixture) TestCompareVersions() {
+	this.So(CompareVersions("1.2.3", "1.2.3"), should.Equal, 0)
+	this.So(CompareVersions("v1.2.3", "1.2.3"), should.Equal, 0)
+	this.So(CompareVersions("1.2.3", "1.10.0"), should.Equal, -1)
+	this.So(CompareVersions("1.2", "1.2.1"), should.Equal, -1)
+	this.So(CompareVersions("2.0.0", "1.99.99"), should.Equal, 1)
+	this.So(CompareVersions("1.0.beta", "1.0.alpha"), should.Equal, 1)
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// ArchiveSpool holds an entire archive which can only be read once it has been received in full.
+type ArchiveSpool interface {
+	io.Writer
+	io.ReaderAt
+	io.Closer
+}
+
+// ZipArchiveReader presents the entries of a zip archive as an ArchiveReader. Because the
+// central directory of a zip archive sits at its end, the entire download is first spooled
+// (e.g. to a temporary file, see CodecOptions.Spool), meaning that the download checksum is
+// complete before extraction.
+type ZipArchiveReader struct {
+	spool   ArchiveSpool
+	files   []*zip.File
+	index   int
+	current io.ReadCloser
+}
+
+func newZipReader(source io.Reader, options CodecOptions) (io.ReadCloser, error) {
+	if options.Spool == nil {
+		return nil, errors.New("zip archives must be spooled in their entirety, but no spool was provided")
+	}
+	spool, err := options.Spool()
+	if err != nil {
+		return nil, err
+	}
+	this := &ZipArchiveReader{spool: spool}
+	size, err := io.Copy(spool, source)
+	if err != nil {
+		_ = this.Close()
+		return nil, err
+	}
+	archive, err := zip.NewReader(spool, size)
+	if err != nil {
+		_ = this.Close()
+		return nil, err
+	}
+	this.files = archive.File
+	return this, nil
+}
+
+func (this *ZipArchiveReader) Next() (*tar.Header, error) {
+	closeResource(this.current)
+	this.current = nil
+
+	for ; this.index < len(this.files); this.index++ {
+		if !this.files[this.index].Mode().IsDir() {
+			break
+		}
+	}
+	if this.index >= len(this.files) {
+		return nil, io.EOF
+	}
+	file := this.files[this.index]
+	this.index++
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	this.current = reader
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     file.Name,
+		Size:     int64(file.UncompressedSize64),
+		Mode:     int64(file.Mode().Perm()),
+		ModTime:  file.Modified,
+	}
+	if file.Mode()&os.ModeSymlink != 0 {
+		target, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		header.Typeflag = tar.TypeSymlink
+		header.Linkname = string(target)
+		header.Size = 0
+	}
+	return header, nil
+}
+
+func (this *ZipArchiveReader) Read(buffer []byte) (int, error) {
+	if this.current == nil {
+		return 0, io.EOF
+	}
+	return this.current.Read(buffer)
+}
+
+func (this *ZipArchiveReader) Close() error {
+	closeResource(this.current)
+	this.current = nil
+	return this.spool.Close()
+}
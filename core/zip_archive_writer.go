@@ -1,9 +1,10 @@
-package shell
+package core
 
 import (
 	"archive/zip"
 	"compress/flate"
 	"io"
+	"os"
 	"sync"
 
 	"github.com/smartystreets/satisfy/contracts"
@@ -24,14 +25,26 @@ func NewZipArchiveWriter(writer io.Writer, level int) contracts.ArchiveWriter {
 }
 
 func (this *ZipArchiveWriter) WriteHeader(header contracts.ArchiveHeader) {
-	var err error
-
-	this.current, err = this.inner.CreateHeader(&zip.FileHeader{
+	zipHeader := &zip.FileHeader{
 		Name:               header.Name,
 		Modified:           header.ModTime,
 		UncompressedSize64: uint64(header.Size),
 		Method:             zip.Deflate,
-	})
+	}
+	zipHeader.SetMode(0644)
+	if header.Executable {
+		zipHeader.SetMode(0755)
+	}
+	if header.LinkName != "" {
+		zipHeader.SetMode(os.ModeSymlink | 0777)
+		zipHeader.UncompressedSize64 = uint64(len(header.LinkName))
+	}
+
+	var err error
+	this.current, err = this.inner.CreateHeader(zipHeader)
+	if err == nil && header.LinkName != "" {
+		_, err = io.WriteString(this.current, header.LinkName) // zip stores the symlink target as the entry contents
+	}
 
 	if err != nil {
 		panic(err)
@@ -3,8 +3,11 @@ module github.com/smartystreets/satisfy
 go 1.13
 
 require (
+	github.com/andybalholm/brotli v1.0.2
 	github.com/klauspost/compress v1.11.13
+	github.com/pierrec/lz4/v4 v4.1.8
 	github.com/smartystreets/assertions v1.2.0
 	github.com/smartystreets/gcs v1.1.2
 	github.com/smartystreets/gunit v1.4.2
+	github.com/ulikunitz/xz v0.5.10
 )
@@ -0,0 +1,25 @@
+package shell
+
+import (
+	"io"
+
+	"github.com/smartystreets/satisfy/contracts"
+	"github.com/smartystreets/satisfy/core"
+)
+
+// The archive writers live in core (alongside the codecs which select them); these forward to them
+// for existing callers of the shell package.
+
+type TarArchiveWriter = core.TarArchiveWriter
+
+func NewSwitchArchiveWriter(writer io.Writer) contracts.ArchiveWriter {
+	return core.NewSwitchArchiveWriter(writer)
+}
+
+func NewTarArchiveWriter(writer io.Writer) *TarArchiveWriter {
+	return core.NewTarArchiveWriter(writer)
+}
+
+func NewZipArchiveWriter(writer io.Writer, level int) contracts.ArchiveWriter {
+	return core.NewZipArchiveWriter(writer, level)
+}
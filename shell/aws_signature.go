@@ -0,0 +1,139 @@
+package shell
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// signAWSRequest applies an AWS Signature Version 4 authorization header to the request.
+// See: https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func signAWSRequest(request *http.Request, credentials contracts.AWSCredentials, service, payloadHash string, now time.Time) {
+	now = now.UTC()
+	timestamp := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	request.Header.Set("Host", request.URL.Host)
+	request.Header.Set("X-Amz-Date", timestamp)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalAWSHeaders(request.Header)
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		canonicalAWSQuery(request.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, credentials.Region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{awsSignatureAlgorithm, timestamp, scope, hashSHA256Hex(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, credentials.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSignatureAlgorithm, credentials.AccessKeyID, scope, signedHeaders, signature))
+	request.Header.Del("Host") // net/http derives the host header from request.Host.
+}
+
+func canonicalAWSHeaders(headers http.Header) (signed string, canonical string) {
+	lines := make(map[string]string, len(headers))
+	var names []string
+	for name, values := range headers {
+		name = strings.ToLower(name)
+		trimmed := make([]string, 0, len(values))
+		for _, value := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		lines[name] = strings.Join(trimmed, ",")
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	builder := new(strings.Builder)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(builder, "%s:%s\n", name, lines[name])
+	}
+	return strings.Join(names, ";"), builder.String()
+}
+
+// canonicalAWSQuery sorts the query by name (then value) and encodes each name and value as required
+// by the canonical request, which (unlike url.Values.Encode) encodes a space as %20 rather than '+'.
+func canonicalAWSQuery(query url.Values) string {
+	encoded := make(map[string][]string, len(query))
+	var names []string
+	for name, values := range query {
+		name = escapeAWSQueryComponent(name)
+		for _, value := range values {
+			encoded[name] = append(encoded[name], escapeAWSQueryComponent(value))
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		values := encoded[name]
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, name+"="+value)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func escapeAWSQueryComponent(value string) string {
+	return strings.ReplaceAll(escapeAWSPath(value), "/", "%2F")
+}
+
+// escapeAWSPath encodes each segment of the path as required by the canonical request.
+func escapeAWSPath(path string) string {
+	builder := new(strings.Builder)
+	for _, character := range []byte(path) {
+		if isUnreservedAWSCharacter(character) || character == '/' {
+			builder.WriteByte(character)
+		} else {
+			_, _ = fmt.Fprintf(builder, "%%%02X", character)
+		}
+	}
+	return builder.String()
+}
+
+func isUnreservedAWSCharacter(character byte) bool {
+	return 'A' <= character && character <= 'Z' ||
+		'a' <= character && character <= 'z' ||
+		'0' <= character && character <= '9' ||
+		character == '-' || character == '_' || character == '.' || character == '~'
+}
+
+func hmacSHA256(key []byte, value string) []byte {
+	hasher := hmac.New(sha256.New, key)
+	_, _ = hasher.Write([]byte(value))
+	return hasher.Sum(nil)
+}
+
+func hashSHA256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+const (
+	awsSignatureAlgorithm = "AWS4-HMAC-SHA256"
+	unsignedAWSPayload    = "UNSIGNED-PAYLOAD"
+)
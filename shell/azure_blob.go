@@ -0,0 +1,261 @@
+package shell
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// AzureBlobStorageClient serves azblob://account/container/prefix remote addresses.
+type AzureBlobStorageClient struct {
+	client         *http.Client
+	credentials    contracts.AzureCredentials
+	expectedStatus int
+}
+
+func NewAzureBlobStorageClient(client *http.Client, credentials contracts.AzureCredentials, expectedStatus int) *AzureBlobStorageClient {
+	return &AzureBlobStorageClient{client: client, credentials: credentials, expectedStatus: expectedStatus}
+}
+
+func (this *AzureBlobStorageClient) Upload(request contracts.UploadRequest) error {
+	azureRequest, err := http.NewRequest("PUT", this.blobAddress(request.RemoteAddress), request.Body)
+	if err != nil {
+		return err
+	}
+	azureRequest.ContentLength = request.Size
+	azureRequest.Header.Set("x-ms-blob-type", "BlockBlob")
+	if request.ContentType != "" {
+		azureRequest.Header.Set("Content-Type", request.ContentType)
+	}
+	if len(request.Checksum) > 0 {
+		azureRequest.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(request.Checksum))
+	}
+	err = this.authorize(azureRequest, request.RemoteAddress.Host)
+	if err != nil {
+		return err
+	}
+
+	response, err := this.client.Do(azureRequest)
+	if err != nil {
+		return fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if !this.uploaded(response.StatusCode) {
+		return classifyStatusCode(response.StatusCode, this.expectedStatus, request.RemoteAddress)
+	}
+	return nil
+}
+
+// uploaded reports whether the status is the expected one. When success (200) is expected, the
+// other success statuses of the Blob service (201 for a blob, 202 for a block list) also qualify.
+func (this *AzureBlobStorageClient) uploaded(status int) bool {
+	if this.expectedStatus != http.StatusOK {
+		return status == this.expectedStatus
+	}
+	return status == http.StatusOK || status == http.StatusCreated || status == http.StatusAccepted
+}
+
+func (this *AzureBlobStorageClient) Download(request url.URL) (io.ReadCloser, error) {
+	return this.download(request, nil)
+}
+
+func (this *AzureBlobStorageClient) DownloadRange(request url.URL, offset, length int64) (io.ReadCloser, error) {
+	return this.download(request, &byteRange{offset: offset, length: length})
+}
+
+func (this *AzureBlobStorageClient) DownloadRangeIfUnchanged(request url.URL, offset, length int64, validator contracts.Validator) (io.ReadCloser, error) {
+	return this.download(request, &byteRange{offset: offset, length: length, validator: validator})
+}
+
+func (this *AzureBlobStorageClient) download(request url.URL, span *byteRange) (io.ReadCloser, error) {
+	azureRequest, err := http.NewRequest("GET", this.blobAddress(request), nil)
+	if err != nil {
+		return nil, err
+	}
+	span.apply(azureRequest)
+	err = this.authorize(azureRequest, request.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := this.client.Do(azureRequest)
+	if err != nil {
+		return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	return span.receive(response, this.expectedStatus, request)
+}
+
+// List returns the names of the virtual directories beneath the address (e.g. the versions of a package).
+func (this *AzureBlobStorageClient) List(address url.URL) ([]string, error) {
+	segments := strings.SplitN(strings.TrimPrefix(path.Clean("/"+address.Path), "/"), "/", 2)
+	container := (&url.URL{Path: "/" + segments[0]}).EscapedPath()
+	prefix := ""
+	if len(segments) > 1 {
+		prefix = listingPrefix(segments[1])
+	}
+
+	var names []string
+	for marker := ""; ; {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "delimiter": {"/"}, "prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		listing, err := this.fetchListing(address, this.endpoint(address.Host)+container, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, blobPrefix := range listing.Blobs.BlobPrefix {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(blobPrefix.Name, prefix), "/"))
+		}
+		if listing.NextMarker == "" {
+			return names, nil
+		}
+		marker = listing.NextMarker
+	}
+}
+
+func (this *AzureBlobStorageClient) fetchListing(address url.URL, container string, query url.Values) (listing azureBlobListing, err error) {
+	if this.credentials.AccountKey == "" && this.credentials.SASToken != "" {
+		sas, err := url.ParseQuery(this.credentials.SASToken)
+		if err != nil {
+			return listing, fmt.Errorf("malformed azure shared access signature: %w", err)
+		}
+		for name, values := range sas {
+			query[name] = values
+		}
+	}
+	azureRequest, err := http.NewRequest("GET", container+"?"+query.Encode(), nil)
+	if err != nil {
+		return listing, err
+	}
+	err = this.authorize(azureRequest, address.Host)
+	if err != nil {
+		return listing, err
+	}
+
+	response, err := this.client.Do(azureRequest)
+	if err != nil {
+		return listing, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != http.StatusOK {
+		return listing, classifyStatusCode(response.StatusCode, http.StatusOK, address)
+	}
+	err = xml.NewDecoder(response.Body).Decode(&listing)
+	return listing, err
+}
+
+func (this *AzureBlobStorageClient) blobAddress(address url.URL) string {
+	blob := (&url.URL{Path: address.Path}).EscapedPath()
+	if this.credentials.AccountKey == "" && this.credentials.SASToken != "" {
+		return this.endpoint(address.Host) + blob + "?" + this.credentials.SASToken
+	}
+	return this.endpoint(address.Host) + blob
+}
+
+// endpoint is the configured endpoint, if any (e.g. an Azurite emulator at
+// http://127.0.0.1:10000/devstoreaccount1), otherwise the account's public endpoint.
+func (this *AzureBlobStorageClient) endpoint(account string) string {
+	if this.credentials.Endpoint != "" {
+		return strings.TrimSuffix(this.credentials.Endpoint, "/")
+	}
+	return fmt.Sprintf("https://%s.blob.core.windows.net", account)
+}
+
+func (this *AzureBlobStorageClient) authorize(request *http.Request, account string) error {
+	request.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	request.Header.Set("x-ms-version", azureStorageVersion)
+	if this.credentials.AccountKey == "" {
+		return nil // SAS token already included in the query string.
+	}
+	if this.credentials.AccountName != "" {
+		account = this.credentials.AccountName
+	}
+	key, err := base64.StdEncoding.DecodeString(this.credentials.AccountKey)
+	if err != nil {
+		return fmt.Errorf("malformed azure storage account key: %w", err)
+	}
+	hasher := hmac.New(sha256.New, key)
+	_, _ = io.WriteString(hasher, azureStringToSign(request, account))
+	signature := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+	request.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", account, signature))
+	return nil
+}
+
+// azureStringToSign implements the Shared Key scheme for the Blob service.
+// See: https://docs.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func azureStringToSign(request *http.Request, account string) string {
+	contentLength := ""
+	if request.ContentLength > 0 {
+		contentLength = strconv.FormatInt(request.ContentLength, 10)
+	}
+	headers := request.Header
+	builder := new(strings.Builder)
+	for _, value := range []string{
+		request.Method,
+		headers.Get("Content-Encoding"),
+		headers.Get("Content-Language"),
+		contentLength,
+		headers.Get("Content-MD5"),
+		headers.Get("Content-Type"),
+		"", // Date (superseded by x-ms-date)
+		headers.Get("If-Modified-Since"),
+		headers.Get("If-Match"),
+		headers.Get("If-None-Match"),
+		headers.Get("If-Unmodified-Since"),
+		headers.Get("Range"),
+	} {
+		builder.WriteString(value)
+		builder.WriteString("\n")
+	}
+
+	var names []string
+	for name := range headers {
+		if name = strings.ToLower(name); strings.HasPrefix(name, "x-ms-") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(builder, "%s:%s\n", name, strings.TrimSpace(headers.Get(name)))
+	}
+
+	_, _ = fmt.Fprintf(builder, "/%s%s", account, request.URL.EscapedPath())
+	query := request.URL.Query()
+	var parameters []string
+	for name := range query {
+		parameters = append(parameters, name)
+	}
+	sort.Strings(parameters)
+	for _, name := range parameters {
+		values := query[name]
+		sort.Strings(values)
+		_, _ = fmt.Fprintf(builder, "\n%s:%s", strings.ToLower(name), strings.Join(values, ","))
+	}
+	return builder.String()
+}
+
+const azureStorageVersion = "2020-04-08"
+
+type azureBlobListing struct {
+	Blobs struct {
+		BlobPrefix []struct {
+			Name string
+		}
+	}
+	NextMarker string
+}
@@ -0,0 +1,228 @@
+package shell
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestAzureBlobStorageClientFixture(t *testing.T) {
+	gunit.Run(new(AzureBlobStorageClientFixture), t)
+}
+
+type AzureBlobStorageClientFixture struct {
+	*gunit.Fixture
+
+	server   *httptest.Server
+	requests []*http.Request
+	bodies   [][]byte
+	blobs    map[string][]byte
+	client   *AzureBlobStorageClient
+}
+
+func (this *AzureBlobStorageClientFixture) Setup() {
+	this.blobs = map[string][]byte{"/account/container/path/to/blob": []byte("0123456789")}
+	this.server = httptest.NewServer(http.HandlerFunc(this.serveHTTP))
+	this.client = NewAzureBlobStorageClient(this.server.Client(), contracts.AzureCredentials{
+		AccountKey: azureTestAccountKey,
+		Endpoint:   this.server.URL + "/account",
+	}, http.StatusOK)
+}
+
+func (this *AzureBlobStorageClientFixture) Teardown() {
+	this.server.Close()
+}
+
+func (this *AzureBlobStorageClientFixture) serveHTTP(response http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+	this.requests = append(this.requests, request)
+	this.bodies = append(this.bodies, body)
+
+	switch request.Method {
+	case "PUT":
+		if request.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			response.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		this.blobs[request.URL.Path] = body
+		response.WriteHeader(http.StatusCreated)
+	case "GET":
+		blob, found := this.blobs[request.URL.Path]
+		if !found {
+			response.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(response, request, "", time.Time{}, bytes.NewReader(blob))
+	}
+}
+
+func (this *AzureBlobStorageClientFixture) TestBlockBlobUpload() {
+	checksum := md5.Sum([]byte("hello"))
+	err := this.client.Upload(contracts.UploadRequest{
+		RemoteAddress: url.URL{Scheme: "azblob", Host: "account", Path: "/container/uploaded blob"},
+		Body:          strings.NewReader("hello"),
+		Size:          5,
+		ContentType:   "application/zstd",
+		Checksum:      checksum[:],
+	})
+
+	this.So(err, should.BeNil)
+	this.So(string(this.blobs["/account/container/uploaded blob"]), should.Equal, "hello")
+	if this.So(this.requests, should.HaveLength, 1) {
+		request := this.requests[0]
+		this.So(request.URL.EscapedPath(), should.Equal, "/account/container/uploaded%20blob")
+		this.So(request.Header.Get("Content-MD5"), should.Equal, "XUFAKrxLKna5cZ2REBfFkg==")
+		this.So(request.Header.Get("x-ms-version"), should.Equal, azureStorageVersion)
+		this.So(request.Header.Get("Authorization"), should.Equal, "SharedKey account:"+signAzureTestString(
+			"PUT\n\n\n5\nXUFAKrxLKna5cZ2REBfFkg==\napplication/zstd\n\n\n\n\n\n\n"+
+				"x-ms-blob-type:BlockBlob\n"+
+				"x-ms-date:"+request.Header.Get("x-ms-date")+"\n"+
+				"x-ms-version:"+azureStorageVersion+"\n"+
+				"/account/account/container/uploaded%20blob"))
+	}
+}
+
+func (this *AzureBlobStorageClientFixture) TestUploadWithSharedAccessSignature() {
+	this.client = NewAzureBlobStorageClient(this.server.Client(), contracts.AzureCredentials{
+		SASToken: "sv=2020-04-08&sig=abc%3D",
+		Endpoint: this.server.URL + "/account",
+	}, http.StatusOK)
+
+	err := this.client.Upload(contracts.UploadRequest{
+		RemoteAddress: url.URL{Scheme: "azblob", Host: "account", Path: "/container/blob"},
+		Body:          strings.NewReader("hello"),
+		Size:          5,
+	})
+
+	this.So(err, should.BeNil)
+	this.So(this.requests[0].URL.RawQuery, should.Equal, "sv=2020-04-08&sig=abc%3D")
+	this.So(this.requests[0].Header.Get("Authorization"), should.BeEmpty)
+}
+
+func (this *AzureBlobStorageClientFixture) TestUploadSucceedsWithAnySuccessStatusOfTheBlobService() {
+	for _, status := range []int{http.StatusOK, http.StatusCreated, http.StatusAccepted} {
+		status := status
+		this.server.Config.Handler = http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+			response.WriteHeader(status)
+		})
+
+		this.So(this.upload(), should.BeNil)
+	}
+}
+
+func (this *AzureBlobStorageClientFixture) TestUploadRejectedWhenOtherStatusExpected() {
+	this.client = NewAzureBlobStorageClient(this.server.Client(), contracts.AzureCredentials{
+		AccountKey: azureTestAccountKey,
+		Endpoint:   this.server.URL + "/account",
+	}, http.StatusNotFound)
+
+	err := this.upload()
+
+	this.So(err, should.HaveSameTypeAs, new(contracts.StatusCodeError))
+}
+
+func (this *AzureBlobStorageClientFixture) TestUploadRejected() {
+	this.server.Config.Handler = http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+		response.WriteHeader(http.StatusForbidden)
+	})
+
+	err := this.upload()
+
+	this.So(err, should.HaveSameTypeAs, new(contracts.StatusCodeError))
+}
+
+func (this *AzureBlobStorageClientFixture) TestUploadFailureRetryable() {
+	this.server.Config.Handler = http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+		response.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	err := this.upload()
+
+	this.So(errors.Is(err, contracts.RetryErr), should.BeTrue)
+}
+
+func (this *AzureBlobStorageClientFixture) upload() error {
+	return this.client.Upload(contracts.UploadRequest{
+		RemoteAddress: url.URL{Scheme: "azblob", Host: "account", Path: "/container/blob"},
+		Body:          strings.NewReader("hello"),
+		Size:          5,
+	})
+}
+
+func (this *AzureBlobStorageClientFixture) TestDownloadRange() {
+	reader, err := this.client.DownloadRange(url.URL{Scheme: "azblob", Host: "account", Path: "/container/path/to/blob"}, 2, 3)
+
+	this.So(err, should.BeNil)
+	this.So(readAndClose(reader), should.Equal, "234")
+	this.So(this.requests[0].Header.Get("Range"), should.Equal, "bytes=2-4")
+}
+
+func (this *AzureBlobStorageClientFixture) TestList() {
+	this.server.Config.Handler = http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		this.requests = append(this.requests, request)
+		if request.URL.Query().Get("marker") == "" {
+			_, _ = response.Write([]byte(`<EnumerationResults><Blobs><BlobPrefix><Name>package/1.0.0/</Name></BlobPrefix></Blobs><NextMarker>next</NextMarker></EnumerationResults>`))
+		} else {
+			_, _ = response.Write([]byte(`<EnumerationResults><Blobs><BlobPrefix><Name>package/1.1.0/</Name></BlobPrefix></Blobs><NextMarker/></EnumerationResults>`))
+		}
+	})
+
+	names, err := this.client.List(url.URL{Scheme: "azblob", Host: "account", Path: "/container/package"})
+
+	this.So(err, should.BeNil)
+	this.So(names, should.Resemble, []string{"1.0.0", "1.1.0"})
+	if this.So(this.requests, should.HaveLength, 2) {
+		this.So(this.requests[0].URL.Path, should.Equal, "/account/container")
+		this.So(this.requests[0].URL.Query().Get("prefix"), should.Equal, "package/")
+		this.So(this.requests[1].URL.Query().Get("marker"), should.Equal, "next")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func TestAzureStringToSignFixture(t *testing.T) {
+	gunit.Run(new(AzureStringToSignFixture), t)
+}
+
+type AzureStringToSignFixture struct {
+	*gunit.Fixture
+}
+
+func (this *AzureStringToSignFixture) TestCanonicalizedHeadersAndResource() {
+	request, _ := http.NewRequest("GET", "https://account.blob.core.windows.net/container?restype=container&comp=list&prefix=a%2F", nil)
+	request.Header.Set("Range", "bytes=0-9")
+	request.Header.Set("X-Ms-Version", azureStorageVersion)
+	request.Header.Set("X-Ms-Date", "Fri, 26 Jun 2015 23:39:12 GMT")
+
+	this.So(azureStringToSign(request, "account"), should.Equal,
+		"GET\n\n\n\n\n\n\n\n\n\n\nbytes=0-9\n"+
+			"x-ms-date:Fri, 26 Jun 2015 23:39:12 GMT\n"+
+			"x-ms-version:"+azureStorageVersion+"\n"+
+			"/account/container\n"+
+			"comp:list\n"+
+			"prefix:a/\n"+
+			"restype:container")
+}
+
+const azureTestAccountKey = "a2V5" // base64("key")
+
+func signAzureTestString(stringToSign string) string {
+	hasher := hmac.New(sha256.New, []byte("key"))
+	_, _ = hasher.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+}
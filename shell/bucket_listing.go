@@ -0,0 +1,71 @@
+package shell
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// listBucket pages through an S3-style XML bucket listing (also spoken by the GCS XML API),
+// returning the names of the "directories" immediately beneath the address.
+func listBucket(client *http.Client, address url.URL, newRequest func(query url.Values) (*http.Request, error)) ([]string, error) {
+	prefix := listingPrefix(address.Path)
+	var names []string
+	for marker := ""; ; {
+		query := url.Values{"prefix": {prefix}, "delimiter": {"/"}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		request, err := newRequest(query)
+		if err != nil {
+			return nil, err
+		}
+		listing, err := fetchBucketListing(client, request, address)
+		if err != nil {
+			return nil, err
+		}
+		for _, common := range listing.CommonPrefixes {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(common.Prefix, prefix), "/"))
+		}
+		if !listing.IsTruncated || listing.NextMarker == "" {
+			return names, nil
+		}
+		marker = listing.NextMarker
+	}
+}
+
+func fetchBucketListing(client *http.Client, request *http.Request, address url.URL) (listing bucketListing, err error) {
+	response, err := client.Do(request)
+	if err != nil {
+		return listing, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != http.StatusOK {
+		return listing, classifyStatusCode(response.StatusCode, http.StatusOK, address)
+	}
+	err = xml.NewDecoder(response.Body).Decode(&listing)
+	return listing, err
+}
+
+// listingPrefix converts the path of a remote address to an object key prefix ending with '/'.
+func listingPrefix(remotePath string) string {
+	prefix := strings.Trim(path.Clean("/"+remotePath), "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+type bucketListing struct {
+	IsTruncated    bool
+	NextMarker     string
+	CommonPrefixes []struct {
+		Prefix string
+	}
+}
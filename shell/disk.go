@@ -65,6 +65,7 @@ func (this *DiskFileSystem) Stat(path string) (contracts.FileInfo, error) {
 		size:    info.Size(),
 		mod:     info.ModTime(),
 		symlink: source,
+		mode:    info.Mode(),
 	}
 	return fileInfo, nil
 }
@@ -119,6 +120,29 @@ func (this *DiskFileSystem) Delete(path string) {
 	}
 }
 
+func (this *DiskFileSystem) DeleteAll(path string) {
+	err := os.RemoveAll(path)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (this *DiskFileSystem) Rename(source, target string) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	return os.Rename(source, target)
+}
+
+func (this *DiskFileSystem) ReadDirectory(path string) (names []string, err error) {
+	infos, err := ioutil.ReadDir(path)
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names, err
+}
+
 ////////////////////////////////////////
 
 type FileInfo struct {
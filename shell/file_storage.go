@@ -0,0 +1,137 @@
+package shell
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// FileSystemStorage serves file:// remote addresses from a local directory or network mount.
+// Outcomes are reported with the same status codes an HTTP backend would produce so that
+// callers (such as the 'check' command) can treat every backend identically.
+type FileSystemStorage struct {
+	expectedStatus int
+}
+
+func NewFileSystemStorage(expectedStatus int) *FileSystemStorage {
+	return &FileSystemStorage{expectedStatus: expectedStatus}
+}
+
+func (this *FileSystemStorage) Upload(request contracts.UploadRequest) error {
+	path := this.localPath(request.RemoteAddress)
+	directory := filepath.Dir(path)
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return err
+	}
+
+	temp, err := ioutil.TempFile(directory, ".satisfy-upload-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(temp.Name()) }()
+
+	err = this.write(temp, request)
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(temp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+func (this *FileSystemStorage) write(temp *os.File, request contracts.UploadRequest) error {
+	defer func() { _ = temp.Close() }()
+
+	hasher := md5.New()
+	written, err := io.Copy(io.MultiWriter(temp, hasher), request.Body)
+	if err != nil {
+		return err
+	}
+	if request.Size > 0 && written != request.Size {
+		return fmt.Errorf("size mismatch: actual [%d] != expected [%d]", written, request.Size)
+	}
+	if actual := hasher.Sum(nil); len(request.Checksum) > 0 && !bytes.Equal(actual, request.Checksum) {
+		return fmt.Errorf("checksum mismatch: actual [%x] != expected [%x]", actual, request.Checksum)
+	}
+	err = temp.Sync()
+	if err != nil {
+		return err
+	}
+	return temp.Close()
+}
+
+func (this *FileSystemStorage) Download(request url.URL) (io.ReadCloser, error) {
+	file, err := os.Open(this.localPath(request))
+	if os.IsNotExist(err) && this.expectedStatus == http.StatusNotFound {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if os.IsNotExist(err) {
+		return nil, contracts.NewStatusCodeError(http.StatusNotFound, this.expectedStatus, request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if this.expectedStatus != http.StatusOK {
+		_ = file.Close()
+		return nil, contracts.NewStatusCodeError(http.StatusOK, this.expectedStatus, request)
+	}
+	return file, nil
+}
+
+func (this *FileSystemStorage) DownloadRange(request url.URL, offset, length int64) (io.ReadCloser, error) {
+	body, err := this.Download(request)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := body.(*os.File)
+	if !ok {
+		return body, nil
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if length > 0 {
+		return limitedBody{Reader: io.LimitReader(file, length), Closer: file}, nil
+	}
+	return file, nil
+}
+
+// List returns the names of the directories beneath the address (e.g. the versions of a package).
+func (this *FileSystemStorage) List(address url.URL) ([]string, error) {
+	entries, err := ioutil.ReadDir(this.localPath(address))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// localPath maps file:///absolute/path and file://localhost/absolute/path onto absolute
+// paths, while file://relative/path refers to a path relative to the working directory.
+func (this *FileSystemStorage) localPath(address url.URL) string {
+	if address.Host == "" || address.Host == "localhost" {
+		return filepath.FromSlash(address.Path)
+	}
+	return filepath.Join(address.Host, filepath.FromSlash(address.Path))
+}
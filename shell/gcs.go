@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path"
 
 	"github.com/smartystreets/gcs"
 	"github.com/smartystreets/satisfy/contracts"
@@ -13,6 +14,7 @@ import (
 type GoogleCloudStorageClient struct {
 	client         *http.Client
 	credentials    gcs.Credentials
+	anonymous      bool
 	expectedStatus int
 }
 
@@ -20,7 +22,16 @@ func NewGoogleCloudStorageClient(client *http.Client, credentials gcs.Credential
 	return &GoogleCloudStorageClient{client: client, credentials: credentials, expectedStatus: expectedStatus}
 }
 
+// NewAnonymousGoogleCloudStorageClient issues unsigned requests, which is sufficient
+// for downloading from publicly readable buckets without any Google credentials.
+func NewAnonymousGoogleCloudStorageClient(client *http.Client, expectedStatus int) *GoogleCloudStorageClient {
+	return &GoogleCloudStorageClient{client: client, anonymous: true, expectedStatus: expectedStatus}
+}
+
 func (this *GoogleCloudStorageClient) Upload(request contracts.UploadRequest) error {
+	if this.anonymous {
+		return fmt.Errorf("anonymous uploads are not supported: %s", request.RemoteAddress.String())
+	}
 	gcsRequest, err := gcs.NewRequest("PUT",
 		gcs.WithCredentials(this.credentials),
 		gcs.WithBucket(request.RemoteAddress.Host),
@@ -46,20 +57,52 @@ func (this *GoogleCloudStorageClient) Upload(request contracts.UploadRequest) er
 }
 
 func (this *GoogleCloudStorageClient) Download(request url.URL) (io.ReadCloser, error) {
-	gcsRequest, err := gcs.NewRequest("GET",
-		gcs.WithCredentials(this.credentials),
-		gcs.WithBucket(request.Host),
-		gcs.WithResource(request.Path),
-	)
+	return this.download(request, nil)
+}
+
+func (this *GoogleCloudStorageClient) DownloadRange(request url.URL, offset, length int64) (io.ReadCloser, error) {
+	return this.download(request, &byteRange{offset: offset, length: length})
+}
+
+func (this *GoogleCloudStorageClient) DownloadRangeIfUnchanged(request url.URL, offset, length int64, validator contracts.Validator) (io.ReadCloser, error) {
+	return this.download(request, &byteRange{offset: offset, length: length, validator: validator})
+}
+
+func (this *GoogleCloudStorageClient) download(request url.URL, span *byteRange) (io.ReadCloser, error) {
+	gcsRequest, err := this.newDownloadRequest(request)
 	if err != nil {
 		return nil, err
 	}
+	span.apply(gcsRequest)
 	response, err := this.client.Do(gcsRequest)
 	if err != nil {
 		return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
 	}
-	if response.StatusCode != this.expectedStatus {
-		return nil, contracts.NewStatusCodeError(response.StatusCode, this.expectedStatus, request)
+	return span.receive(response, this.expectedStatus, request)
+}
+
+func (this *GoogleCloudStorageClient) List(address url.URL) ([]string, error) {
+	return listBucket(this.client, address, func(query url.Values) (*http.Request, error) {
+		gcsRequest, err := this.newDownloadRequest(url.URL{Host: address.Host, Path: "/"})
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range gcsRequest.URL.Query() {
+			query[name] = values // retain the signature
+		}
+		gcsRequest.URL.RawQuery = query.Encode()
+		return gcsRequest, nil
+	})
+}
+
+func (this *GoogleCloudStorageClient) newDownloadRequest(request url.URL) (*http.Request, error) {
+	if this.anonymous {
+		address := url.URL{Scheme: "https", Host: "storage.googleapis.com", Path: path.Join("/", request.Host, request.Path)}
+		return http.NewRequest("GET", address.String(), nil)
 	}
-	return response.Body, nil
+	return gcs.NewRequest("GET",
+		gcs.WithCredentials(this.credentials),
+		gcs.WithBucket(request.Host),
+		gcs.WithResource(request.Path),
+	)
 }
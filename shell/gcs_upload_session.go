@@ -0,0 +1,229 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// BeginUpload starts a resumable upload session for the object.
+// See: https://cloud.google.com/storage/docs/performing-resumable-uploads
+func (this *GoogleCloudStorageClient) BeginUpload(request contracts.UploadRequest) (contracts.UploadSession, error) {
+	if this.anonymous {
+		return nil, fmt.Errorf("anonymous uploads are not supported: %s", request.RemoteAddress.String())
+	}
+	gcsRequest, err := this.newUploadSessionRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := this.client.Do(gcsRequest)
+	if err != nil {
+		return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		return nil, classifyStatusCode(response.StatusCode, http.StatusCreated, request.RemoteAddress)
+	}
+	location := response.Header.Get("Location")
+	if location == "" {
+		return nil, fmt.Errorf("no upload session returned for %s", request.RemoteAddress.String())
+	}
+	return &gcsUploadSession{storage: this, client: this.client, location: location, address: request.RemoteAddress}, nil
+}
+
+// newUploadSessionRequest signs the request which starts an upload session in the same manner as
+// the gcs package signs GET and PUT requests (with the addition of the x-goog-resumable header).
+func (this *GoogleCloudStorageClient) newUploadSessionRequest(request contracts.UploadRequest) (*http.Request, error) {
+	gcsRequest, err := this.newSignedRequest("POST", request.RemoteAddress, request.ContentType, "x-goog-resumable:start\n")
+	if err != nil {
+		return nil, err
+	}
+	gcsRequest.Header.Set("x-goog-resumable", "start")
+	if request.ContentType != "" {
+		gcsRequest.Header.Set("Content-Type", request.ContentType)
+	}
+	return gcsRequest, nil
+}
+
+// newSignedRequest signs requests for methods other than those (GET and PUT) supported by the gcs
+// package. The canonical extension headers, if any, must each end with a newline.
+func (this *GoogleCloudStorageClient) newSignedRequest(method string, remoteAddress url.URL, contentType, extensionHeaders string) (*http.Request, error) {
+	object := path.Join("/", remoteAddress.Host, remoteAddress.Path)
+	address := url.URL{Scheme: "https", Host: "storage.googleapis.com", Path: object}
+	gcsRequest, err := http.NewRequest(method, address.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if this.credentials.BearerToken != "" {
+		gcsRequest.Header.Set("Authorization", this.credentials.BearerToken)
+		return gcsRequest, nil
+	}
+
+	expires := strconv.FormatInt(time.Now().UTC().Add(time.Second*30).Unix(), 10)
+	stringToSign := fmt.Sprintf("%s\n\n%s\n%s\n%s%s", method, contentType, expires, extensionHeaders, object)
+	signature, err := this.credentials.PrivateKey.Sign([]byte(stringToSign))
+	if err != nil {
+		return nil, err
+	}
+	gcsRequest.URL.RawQuery = url.Values{
+		"GoogleAccessId": {this.credentials.AccessID},
+		"Expires":        {expires},
+		"Signature":      {base64.StdEncoding.EncodeToString(signature)},
+	}.Encode()
+	return gcsRequest, nil
+}
+
+// deleteObject removes an object (such as one which failed verification after its upload completed).
+func (this *GoogleCloudStorageClient) deleteObject(address url.URL) error {
+	gcsRequest, err := this.newSignedRequest("DELETE", address, "", "")
+	if err != nil {
+		return err
+	}
+	response, err := this.client.Do(gcsRequest)
+	if err != nil {
+		return fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusNotFound {
+		return classifyStatusCode(response.StatusCode, http.StatusNoContent, address)
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// gcsUploadSession sends each part of the object to the session URI, which requires no further
+// authorization. Google Cloud Storage commits whatever it receives (in multiples of 256 KiB), so
+// parts other than the final part must also be multiples of 256 KiB.
+type gcsUploadSession struct {
+	storage  *GoogleCloudStorageClient
+	client   *http.Client
+	location string
+	address  url.URL
+	complete bool
+	md5      []byte
+}
+
+func (this *gcsUploadSession) Committed() (int64, error) {
+	response, err := this.put(nil, "bytes */*")
+	if err != nil {
+		return 0, err
+	}
+	return this.committed(response)
+}
+
+func (this *gcsUploadSession) UploadPart(part []byte, offset int64, final bool) error {
+	total := "*"
+	if final {
+		total = strconv.FormatInt(offset+int64(len(part)), 10)
+	}
+	contentRange := fmt.Sprintf("bytes */%s", total)
+	if len(part) > 0 {
+		contentRange = fmt.Sprintf("bytes %d-%d/%s", offset, offset+int64(len(part))-1, total)
+	} else if !final {
+		return nil
+	}
+
+	response, err := this.put(part, contentRange)
+	if err != nil {
+		return err
+	}
+	committed, err := this.committed(response)
+	if err != nil {
+		return err
+	}
+	if final && !this.complete || committed < offset+int64(len(part)) {
+		return fmt.Errorf("part at offset %d only committed through byte %d (%w)", offset, committed, contracts.RetryErr)
+	}
+	return nil
+}
+
+func (this *gcsUploadSession) put(part []byte, contentRange string) (*http.Response, error) {
+	request, err := http.NewRequest("PUT", this.location, bytes.NewReader(part))
+	if err != nil {
+		return nil, err
+	}
+	request.ContentLength = int64(len(part))
+	request.Header.Set("Content-Range", contentRange)
+	response, err := this.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	_ = response.Body.Close()
+	return response, nil
+}
+
+// committed interprets the response to a part (or to a status query): 308 (Resume Incomplete) with
+// the range of bytes committed thus far or, once the upload is complete, 200 or 201.
+func (this *gcsUploadSession) committed(response *http.Response) (int64, error) {
+	switch response.StatusCode {
+	case http.StatusPermanentRedirect:
+		committed := response.Header.Get("Range") // e.g. bytes=0-262143
+		if committed == "" {
+			return 0, nil
+		}
+		end, err := strconv.ParseInt(committed[strings.LastIndex(committed, "-")+1:], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed range of committed bytes: %q", committed)
+		}
+		return end + 1, nil
+	case http.StatusOK, http.StatusCreated:
+		this.complete = true
+		this.md5 = parseGoogleHash(response.Header["X-Goog-Hash"], "md5")
+		size, _ := strconv.ParseInt(response.Header.Get("X-Goog-Stored-Content-Length"), 10, 64)
+		return size, nil
+	default:
+		return 0, classifyStatusCode(response.StatusCode, http.StatusPermanentRedirect, this.address)
+	}
+}
+
+func (this *gcsUploadSession) Complete(checksum []byte) error {
+	if !this.complete {
+		return fmt.Errorf("upload of %s is incomplete", this.address.String())
+	}
+	if len(checksum) == 0 || this.md5 == nil || bytes.Equal(checksum, this.md5) {
+		return nil
+	}
+	// The object was finalized when its last part was received, so it's too late to abort the session.
+	mismatch := fmt.Errorf("checksum mismatch: actual [%x] != expected [%x]", this.md5, checksum)
+	if err := this.storage.deleteObject(this.address); err != nil {
+		return fmt.Errorf("%s (and the object could not be deleted: %s)", mismatch, err)
+	}
+	return mismatch
+}
+
+func (this *gcsUploadSession) Abort() error {
+	request, err := http.NewRequest("DELETE", this.location, nil)
+	if err != nil {
+		return err
+	}
+	response, err := this.client.Do(request)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// parseGoogleHash extracts a hash from x-goog-hash headers (e.g. "crc32c=n03x6A==, md5=Ojk9c3dhfxgoKVVHYwFbHQ==").
+func parseGoogleHash(headers []string, name string) []byte {
+	for _, header := range headers {
+		for _, value := range strings.Split(header, ",") {
+			value = strings.TrimSpace(value)
+			if strings.HasPrefix(value, name+"=") {
+				decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, name+"="))
+				if err == nil {
+					return decoded
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package shell
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gcs"
+	"github.com/smartystreets/gunit"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestGoogleCloudStorageUploadSessionFixture(t *testing.T) {
+	gunit.Run(new(GoogleCloudStorageUploadSessionFixture), t)
+}
+
+type GoogleCloudStorageUploadSessionFixture struct {
+	*gunit.Fixture
+
+	server   *httptest.Server
+	requests []string
+	stored   []byte
+	session  contracts.UploadSession
+}
+
+func (this *GoogleCloudStorageUploadSessionFixture) Setup() {
+	this.server = httptest.NewServer(http.HandlerFunc(this.serveHTTP))
+	client := &http.Client{Transport: redirectTransport{target: this.server.URL}}
+	storage := NewGoogleCloudStorageClient(client, gcs.Credentials{BearerToken: "Bearer token"}, http.StatusOK)
+
+	var err error
+	this.session, err = storage.BeginUpload(contracts.UploadRequest{
+		RemoteAddress: url.URL{Scheme: "gcs", Host: "bucket", Path: "/path/to/object"},
+	})
+	this.So(err, should.BeNil)
+}
+
+func (this *GoogleCloudStorageUploadSessionFixture) Teardown() {
+	this.server.Close()
+}
+
+func (this *GoogleCloudStorageUploadSessionFixture) serveHTTP(response http.ResponseWriter, request *http.Request) {
+	this.requests = append(this.requests, request.Method+" "+request.URL.Path)
+	switch request.Method {
+	case "POST":
+		response.Header().Set("Location", "https://storage.googleapis.com/upload/session")
+		response.WriteHeader(http.StatusCreated)
+	case "PUT":
+		this.stored = []byte("stored")
+		checksum := md5.Sum(this.stored)
+		response.Header().Set("X-Goog-Hash", "crc32c=n03x6A==, md5="+base64.StdEncoding.EncodeToString(checksum[:]))
+		response.Header().Set("X-Goog-Stored-Content-Length", strconv.Itoa(len(this.stored)))
+		response.WriteHeader(http.StatusOK)
+	case "DELETE":
+		this.stored = nil
+		response.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (this *GoogleCloudStorageUploadSessionFixture) TestChecksumVerified() {
+	checksum := md5.Sum([]byte("stored"))
+
+	this.So(this.session.UploadPart([]byte("stored"), 0, true), should.BeNil)
+	err := this.session.Complete(checksum[:])
+
+	this.So(err, should.BeNil)
+	this.So(this.stored, should.Resemble, []byte("stored"))
+}
+
+func (this *GoogleCloudStorageUploadSessionFixture) TestChecksumMismatchDeletesFinalizedObject() {
+	checksum := md5.Sum([]byte("expected"))
+
+	this.So(this.session.UploadPart([]byte("stored"), 0, true), should.BeNil)
+	err := this.session.Complete(checksum[:])
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "checksum mismatch")
+	this.So(this.stored, should.BeNil)
+	this.So(this.requests, should.Resemble, []string{
+		"POST /bucket/path/to/object",
+		"PUT /upload/session",
+		"DELETE /bucket/path/to/object",
+	})
+}
+
+// redirectTransport sends every request (whatever its host) to the target server.
+type redirectTransport struct {
+	target string
+}
+
+func (this redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	target, _ := url.Parse(this.target)
+	request = request.Clone(request.Context())
+	request.URL.Scheme, request.URL.Host, request.Host = target.Scheme, target.Host, target.Host
+	return http.DefaultTransport.RoundTrip(request)
+}
@@ -1,9 +1,16 @@
 package shell
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/smartystreets/satisfy/contracts"
 )
 
 func NewHTTPClient() *http.Client {
@@ -23,3 +30,95 @@ func NewHTTPClient() *http.Client {
 		},
 	}
 }
+
+func classifyStatusCode(actual, expected int, address url.URL) error {
+	err := contracts.NewStatusCodeError(actual, expected, address)
+	if actual >= http.StatusInternalServerError || actual == http.StatusTooManyRequests {
+		return fmt.Errorf("%s (%w)", err, contracts.RetryErr)
+	}
+	return err
+}
+
+// byteRange selects part of a remote object for a ranged download (nil selects the entire object),
+// optionally only while the object remains the version identified by the validator.
+type byteRange struct {
+	offset, length int64
+	validator      contracts.Validator
+}
+
+func (this *byteRange) apply(request *http.Request) {
+	if this == nil {
+		return
+	}
+	if this.length > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", this.offset, this.offset+this.length-1))
+	} else {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", this.offset))
+	}
+	if etag := this.validator.ETag; etag != "" && !strings.HasPrefix(etag, "W/") {
+		request.Header.Set("If-Match", etag) // weak entity tags never match (If-Match compares strongly)
+	} else if this.validator.LastModified != "" {
+		request.Header.Set("If-Unmodified-Since", this.validator.LastModified)
+	}
+}
+
+// receive accepts the response to a (possibly ranged) download request. A server which ignores the
+// Range header responds with the entire object. When resuming a download (to the end of the object)
+// the bytes before the offset are skipped; otherwise (for a chunk of a parallel download, which would
+// otherwise download everything before it) ranges are reported as unsupported.
+func (this *byteRange) receive(response *http.Response, expectedStatus int, address url.URL) (io.ReadCloser, error) {
+	validator := contracts.Validator{ETag: response.Header.Get("ETag"), LastModified: response.Header.Get("Last-Modified")}
+	if this == nil || expectedStatus != http.StatusOK {
+		if response.StatusCode != expectedStatus {
+			_ = response.Body.Close()
+			return nil, classifyStatusCode(response.StatusCode, expectedStatus, address)
+		}
+		return retryableBody{ReadCloser: response.Body, validator: validator}, nil
+	}
+
+	if response.StatusCode == http.StatusPreconditionFailed || this.validator.Differs(validator) {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("%w (%s)", contracts.ObjectChangedErr, address.String())
+	} else if response.StatusCode == http.StatusOK && this.length > 0 {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("%w (the server ignored the Range header for %s)", contracts.RangeUnsupportedErr, address.String())
+	} else if response.StatusCode == http.StatusOK {
+		_, err := io.CopyN(ioutil.Discard, response.Body, this.offset)
+		if err != nil {
+			_ = response.Body.Close()
+			return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+		}
+	} else if response.StatusCode != http.StatusPartialContent {
+		_ = response.Body.Close()
+		return nil, classifyStatusCode(response.StatusCode, http.StatusPartialContent, address)
+	}
+	if this.length > 0 {
+		limited := limitedBody{Reader: io.LimitReader(response.Body, this.length), Closer: response.Body}
+		return retryableBody{ReadCloser: limited, validator: validator}, nil
+	}
+	return retryableBody{ReadCloser: response.Body, validator: validator}, nil
+}
+
+// retryableBody marks a failure part way through a response body as retryable, allowing the
+// download to be resumed from the last byte received (of the same version of the object).
+type retryableBody struct {
+	io.ReadCloser
+	validator contracts.Validator
+}
+
+func (this retryableBody) Validator() contracts.Validator {
+	return this.validator
+}
+
+func (this retryableBody) Read(buffer []byte) (int, error) {
+	count, err := this.ReadCloser.Read(buffer)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	return count, err
+}
+
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
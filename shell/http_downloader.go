@@ -0,0 +1,86 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// HTTPDownloader fetches packages from a static web server or CDN using plain GET requests.
+// It is read-only; uploads must target the underlying storage directly. Credentials are only
+// sent over https to their configured host and never follow a redirect.
+type HTTPDownloader struct {
+	client         *http.Client
+	credentials    contracts.HTTPCredentials
+	expectedStatus int
+}
+
+func NewHTTPDownloader(client *http.Client, credentials contracts.HTTPCredentials, expectedStatus int) *HTTPDownloader {
+	scoped := *client
+	scoped.CheckRedirect = withoutCredentials(client.CheckRedirect)
+	return &HTTPDownloader{client: &scoped, credentials: credentials, expectedStatus: expectedStatus}
+}
+
+func (this *HTTPDownloader) Upload(request contracts.UploadRequest) error {
+	return fmt.Errorf("%w: %s", errReadOnlyRemoteStorage, request.RemoteAddress.String())
+}
+
+func (this *HTTPDownloader) Download(request url.URL) (io.ReadCloser, error) {
+	return this.download(request, nil)
+}
+
+func (this *HTTPDownloader) DownloadRange(request url.URL, offset, length int64) (io.ReadCloser, error) {
+	return this.download(request, &byteRange{offset: offset, length: length})
+}
+
+func (this *HTTPDownloader) DownloadRangeIfUnchanged(request url.URL, offset, length int64, validator contracts.Validator) (io.ReadCloser, error) {
+	return this.download(request, &byteRange{offset: offset, length: length, validator: validator})
+}
+
+func (this *HTTPDownloader) download(request url.URL, span *byteRange) (io.ReadCloser, error) {
+	httpRequest, err := http.NewRequest("GET", request.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	this.authorize(httpRequest)
+	span.apply(httpRequest)
+
+	response, err := this.client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	return span.receive(response, this.expectedStatus, request)
+}
+
+func (this *HTTPDownloader) authorize(request *http.Request) {
+	if request.URL.Scheme != "https" || !strings.EqualFold(request.URL.Host, this.credentials.Host) {
+		return
+	}
+	if this.credentials.BearerToken != "" {
+		request.Header.Set("Authorization", "Bearer "+this.credentials.BearerToken)
+	} else if this.credentials.Username != "" {
+		request.SetBasicAuth(this.credentials.Username, this.credentials.Password)
+	}
+}
+
+// withoutCredentials removes the credentials from every redirected request before applying
+// the client's own redirect policy (if any, otherwise the default of at most 10 redirects).
+func withoutCredentials(policy func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(request *http.Request, via []*http.Request) error {
+		request.Header.Del("Authorization")
+		if policy != nil {
+			return policy(request, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
+
+var errReadOnlyRemoteStorage = errors.New("remote storage is read-only")
@@ -0,0 +1,102 @@
+package shell
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestHTTPDownloaderFixture(t *testing.T) {
+	gunit.Run(new(HTTPDownloaderFixture), t)
+}
+
+type HTTPDownloaderFixture struct {
+	*gunit.Fixture
+
+	configured *httptest.Server
+	other      *httptest.Server
+	plain      *httptest.Server
+	received   map[string]string
+	downloader *HTTPDownloader
+}
+
+func (this *HTTPDownloaderFixture) Setup() {
+	this.received = make(map[string]string)
+	this.configured = httptest.NewTLSServer(this.handler("configured"))
+	this.other = httptest.NewTLSServer(this.handler("other"))
+	this.plain = httptest.NewServer(this.handler("plain"))
+	this.downloader = NewHTTPDownloader(this.configured.Client(), contracts.HTTPCredentials{
+		Host:        this.address(this.configured).Host,
+		BearerToken: "token",
+	}, http.StatusOK)
+}
+
+func (this *HTTPDownloaderFixture) Teardown() {
+	this.configured.Close()
+	this.other.Close()
+	this.plain.Close()
+}
+
+func (this *HTTPDownloaderFixture) handler(name string) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		if target := request.URL.Query().Get("redirect"); target != "" {
+			http.Redirect(response, request, target, http.StatusFound)
+			return
+		}
+		this.received[name] = request.Header.Get("Authorization")
+		_, _ = response.Write([]byte(name))
+	}
+}
+
+func (this *HTTPDownloaderFixture) address(server *httptest.Server) url.URL {
+	address, _ := url.Parse(server.URL + "/package")
+	return *address
+}
+
+func (this *HTTPDownloaderFixture) download(address url.URL) string {
+	body, err := this.downloader.Download(address)
+	this.So(err, should.BeNil)
+	return readAndClose(body)
+}
+
+func (this *HTTPDownloaderFixture) TestCredentialsSentToConfiguredHost() {
+	this.So(this.download(this.address(this.configured)), should.Equal, "configured")
+	this.So(this.received["configured"], should.Equal, "Bearer token")
+}
+
+func (this *HTTPDownloaderFixture) TestCredentialsWithheldFromOtherHost() {
+	this.So(this.download(this.address(this.other)), should.Equal, "other")
+	this.So(this.received, should.ContainKey, "other")
+	this.So(this.received["other"], should.BeEmpty)
+}
+
+func (this *HTTPDownloaderFixture) TestCredentialsWithheldFromPlainHTTP() {
+	this.downloader.credentials.Host = this.address(this.plain).Host
+
+	this.So(this.download(this.address(this.plain)), should.Equal, "plain")
+	this.So(this.received, should.ContainKey, "plain")
+	this.So(this.received["plain"], should.BeEmpty)
+}
+
+func (this *HTTPDownloaderFixture) TestCredentialsWithheldAcrossRedirects() {
+	address := this.address(this.configured)
+	address.RawQuery = url.Values{"redirect": {this.other.URL + "/package"}}.Encode()
+
+	this.So(this.download(address), should.Equal, "other")
+	this.So(this.received, should.ContainKey, "other")
+	this.So(this.received["other"], should.BeEmpty)
+}
+
+func (this *HTTPDownloaderFixture) TestCredentialsWithheldWhenRedirectedBackToConfiguredHost() {
+	address := this.address(this.configured)
+	address.RawQuery = url.Values{"redirect": {this.configured.URL + "/elsewhere"}}.Encode()
+
+	this.So(this.download(address), should.Equal, "configured")
+	this.So(this.received["configured"], should.BeEmpty)
+}
@@ -0,0 +1,588 @@
+package shell
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// OCIRegistryClient stores packages in an OCI (container) registry under oci://registry/repository
+// remote addresses. Each package becomes a repository whose versions are tags (plus 'latest'); the
+// archive is pushed as the single layer and manifest.json as the config blob of an OCI image manifest.
+// Manifest signatures (tagged '<tag>.sig') and the versions index (tagged 'versions.json') are each
+// pushed as the config blob of an image manifest without layers.
+type OCIRegistryClient struct {
+	client         *http.Client
+	credentials    contracts.OCICredentials
+	expectedStatus int
+
+	lock          sync.Mutex
+	layers        map[string]ociDescriptor // key: repository:tag
+	signatures    map[string][]byte        // key: remote path of the (not yet uploaded) manifest
+	authorization map[string]string        // key: repository
+}
+
+func NewOCIRegistryClient(client *http.Client, credentials contracts.OCICredentials, expectedStatus int) *OCIRegistryClient {
+	return &OCIRegistryClient{
+		client:         client,
+		credentials:    credentials,
+		expectedStatus: expectedStatus,
+		layers:         make(map[string]ociDescriptor),
+		signatures:     make(map[string][]byte),
+		authorization:  make(map[string]string),
+	}
+}
+
+func (this *OCIRegistryClient) Upload(request contracts.UploadRequest) error {
+	switch path.Base(request.RemoteAddress.Path) {
+	case contracts.RemoteManifestFilename:
+		return this.uploadManifest(request)
+	case contracts.RemoteManifestFilename + contracts.SignatureExtension:
+		return this.uploadSignature(request)
+	case contracts.RemoteVersionIndexFilename:
+		return this.uploadVersionIndex(request)
+	default:
+		return this.uploadArchive(request)
+	}
+}
+
+func (this *OCIRegistryClient) uploadArchive(request contracts.UploadRequest) error {
+	repository, tag := versionedOCIReference(request.RemoteAddress.Path)
+	digest, err := digestUploadBody(request)
+	if err != nil {
+		return err
+	}
+	layer := ociDescriptor{MediaType: request.ContentType, Digest: digest, Size: request.Size}
+	if layer.MediaType == "" {
+		layer.MediaType = "application/octet-stream"
+	}
+	err = this.pushBlob(request.RemoteAddress, repository, layer, request.Body)
+	if err != nil {
+		return err
+	}
+	this.lock.Lock()
+	this.layers[repository+":"+tag] = layer
+	this.lock.Unlock()
+	return nil
+}
+
+func (this *OCIRegistryClient) uploadManifest(request contracts.UploadRequest) error {
+	raw, err := readUploadBody(request)
+	if err != nil {
+		return err
+	}
+	var manifest contracts.Manifest
+	err = json.Unmarshal(raw, &manifest)
+	if err != nil {
+		return fmt.Errorf("malformed package manifest: %w", err)
+	}
+
+	repository, tag := versionedOCIReference(request.RemoteAddress.Path)
+	if tag != manifest.Version {
+		repository, tag = path.Join(repository, tag), ociLatestTag
+	}
+	layer, err := this.archiveLayer(request.RemoteAddress, repository, manifest.Version)
+	if err != nil {
+		return err
+	}
+
+	manifestPath := path.Clean("/" + request.RemoteAddress.Path)
+	this.lock.Lock()
+	signature, signed := this.signatures[manifestPath]
+	this.lock.Unlock()
+	if signed {
+		err = this.pushDocument(request.RemoteAddress, repository, tag+contracts.SignatureExtension, signature)
+		if err != nil {
+			return err
+		}
+	}
+
+	config := ociDescriptor{MediaType: ociConfigMediaType, Digest: sha256Digest(raw), Size: int64(len(raw))}
+	err = this.pushBlob(request.RemoteAddress, repository, config, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	err = this.pushManifest(request.RemoteAddress, repository, tag, ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		Config:        config,
+		Layers:        []ociDescriptor{layer},
+		Annotations: map[string]string{
+			"org.opencontainers.image.title":   manifest.Name,
+			"org.opencontainers.image.version": manifest.Version,
+		},
+	})
+	if err == nil && signed {
+		this.lock.Lock()
+		delete(this.signatures, manifestPath)
+		this.lock.Unlock()
+	}
+	return err
+}
+
+// uploadSignature holds on to the signature of a manifest until the manifest itself is uploaded
+// (which is always afterward) because only the contents of the manifest determine whether it is
+// tagged with its version or as 'latest'. The signature is then pushed ahead of the manifest.
+func (this *OCIRegistryClient) uploadSignature(request contracts.UploadRequest) error {
+	raw, err := readUploadBody(request)
+	if err != nil {
+		return err
+	}
+	manifestPath := strings.TrimSuffix(path.Clean("/"+request.RemoteAddress.Path), contracts.SignatureExtension)
+	this.lock.Lock()
+	this.signatures[manifestPath] = raw
+	this.lock.Unlock()
+	return nil
+}
+
+// uploadVersionIndex pushes /repository/path/versions.json to the repository of the package.
+func (this *OCIRegistryClient) uploadVersionIndex(request contracts.UploadRequest) error {
+	raw, err := readUploadBody(request)
+	if err != nil {
+		return err
+	}
+	repository, name := versionedOCIReference(request.RemoteAddress.Path)
+	return this.pushDocument(request.RemoteAddress, path.Join(repository, name), ociVersionIndexTag, raw)
+}
+
+// pushDocument tags an image manifest whose config blob is the (JSON) document and which has no layers.
+func (this *OCIRegistryClient) pushDocument(address url.URL, repository, tag string, raw []byte) error {
+	config := ociDescriptor{MediaType: ociDocumentMediaType, Digest: sha256Digest(raw), Size: int64(len(raw))}
+	err := this.pushBlob(address, repository, config, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	return this.pushManifest(address, repository, tag, ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		Config:        config,
+		Layers:        []ociDescriptor{},
+	})
+}
+
+// archiveLayer prefers the layer pushed by this client and otherwise falls back to the
+// layer of the version already tagged in the registry (e.g. when only re-tagging 'latest').
+func (this *OCIRegistryClient) archiveLayer(address url.URL, repository, version string) (ociDescriptor, error) {
+	this.lock.Lock()
+	layer, found := this.layers[repository+":"+version]
+	this.lock.Unlock()
+	if found {
+		return layer, nil
+	}
+	manifest, status, err := this.fetchManifest(address, repository, version)
+	if err != nil {
+		return ociDescriptor{}, err
+	}
+	if status != http.StatusOK || len(manifest.Layers) == 0 {
+		return ociDescriptor{}, fmt.Errorf("archive for [%s:%s] must be uploaded before its manifest", repository, version)
+	}
+	return manifest.Layers[0], nil
+}
+
+// Download fetches the config blob of manifests, signatures and the versions index and the single
+// layer of archives. Manifests (and their signatures) not found under a version are tried as 'latest'.
+func (this *OCIRegistryClient) Download(address url.URL) (io.ReadCloser, error) {
+	repository, version := versionedOCIReference(address.Path)
+	tag, latest, isDocument := version, "", true
+	switch path.Base(address.Path) {
+	case contracts.RemoteManifestFilename:
+		latest = ociLatestTag
+	case contracts.RemoteManifestFilename + contracts.SignatureExtension:
+		tag, latest = version+contracts.SignatureExtension, ociLatestTag+contracts.SignatureExtension
+	case contracts.RemoteVersionIndexFilename:
+		repository, tag = path.Join(repository, version), ociVersionIndexTag
+	default:
+		isDocument = false
+	}
+
+	manifest, status, err := this.fetchManifest(address, repository, tag)
+	if err == nil && status == http.StatusNotFound && latest != "" {
+		repository, tag = path.Join(repository, version), latest
+		manifest, status, err = this.fetchManifest(address, repository, tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound && this.expectedStatus == http.StatusNotFound {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if status != this.expectedStatus {
+		return nil, classifyStatusCode(status, this.expectedStatus, address)
+	}
+
+	if isDocument {
+		return this.fetchBlob(address, repository, manifest.Config.Digest)
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, fmt.Errorf("no archive layer found for [%s:%s]", repository, tag)
+	}
+	return this.fetchBlob(address, repository, manifest.Layers[0].Digest)
+}
+
+// List returns the version tags of the repository at the address (i.e. the versions of a package).
+func (this *OCIRegistryClient) List(address url.URL) ([]string, error) {
+	repository := strings.Trim(path.Clean("/"+address.Path), "/")
+	var tags []string
+	for next := fmt.Sprintf("/v2/%s/tags/list", repository); next != ""; {
+		response, err := this.do(address, repository, func(base string) (*http.Request, error) {
+			return http.NewRequest("GET", base+next, nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+		var listing struct {
+			Tags []string `json:"tags"`
+		}
+		if response.StatusCode == http.StatusOK {
+			err = json.NewDecoder(response.Body).Decode(&listing)
+		} else if response.StatusCode != http.StatusNotFound {
+			err = classifyStatusCode(response.StatusCode, http.StatusOK, address)
+		}
+		_ = response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range listing.Tags {
+			if isOCIVersionTag(tag) {
+				tags = append(tags, tag)
+			}
+		}
+		next = nextOCILink(response.Header.Get("Link"))
+	}
+	return tags, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (this *OCIRegistryClient) fetchManifest(address url.URL, repository, tag string) (manifest ociManifest, status int, err error) {
+	response, err := this.do(address, repository, func(base string) (*http.Request, error) {
+		request, err := http.NewRequest("GET", fmt.Sprintf("%s/v2/%s/manifests/%s", base, repository, tag), nil)
+		if err == nil {
+			request.Header.Set("Accept", ociManifestMediaType)
+		}
+		return request, err
+	})
+	if err != nil {
+		return ociManifest{}, 0, err
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return ociManifest{}, response.StatusCode, nil
+	}
+	err = json.NewDecoder(response.Body).Decode(&manifest)
+	if err != nil {
+		return ociManifest{}, 0, fmt.Errorf("malformed oci manifest for [%s:%s]: %w", repository, tag, err)
+	}
+	return manifest, http.StatusOK, nil
+}
+
+func (this *OCIRegistryClient) fetchBlob(address url.URL, repository, digest string) (io.ReadCloser, error) {
+	response, err := this.do(address, repository, func(base string) (*http.Request, error) {
+		return http.NewRequest("GET", fmt.Sprintf("%s/v2/%s/blobs/%s", base, repository, digest), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		return nil, classifyStatusCode(response.StatusCode, http.StatusOK, address)
+	}
+	return response.Body, nil
+}
+
+func (this *OCIRegistryClient) pushBlob(address url.URL, repository string, descriptor ociDescriptor, body io.Reader) error {
+	response, err := this.do(address, repository, func(base string) (*http.Request, error) {
+		return http.NewRequest("HEAD", fmt.Sprintf("%s/v2/%s/blobs/%s", base, repository, descriptor.Digest), nil)
+	})
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode == http.StatusOK {
+		return nil // already present; blobs are content-addressed
+	}
+
+	response, err = this.do(address, repository, func(base string) (*http.Request, error) {
+		return http.NewRequest("POST", fmt.Sprintf("%s/v2/%s/blobs/uploads/", base, repository), nil)
+	})
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusAccepted {
+		return classifyStatusCode(response.StatusCode, http.StatusAccepted, address)
+	}
+	location, err := response.Request.URL.Parse(response.Header.Get("Location"))
+	if err != nil {
+		return err
+	}
+	query := location.Query()
+	query.Set("digest", descriptor.Digest)
+	location.RawQuery = query.Encode()
+
+	response, err = this.do(address, repository, func(string) (*http.Request, error) {
+		if seeker, ok := body.(io.Seeker); ok {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		request, err := http.NewRequest("PUT", location.String(), ioutil.NopCloser(body))
+		if err == nil {
+			request.ContentLength = descriptor.Size
+			request.Header.Set("Content-Type", "application/octet-stream")
+		}
+		return request, err
+	})
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		return classifyStatusCode(response.StatusCode, http.StatusCreated, address)
+	}
+	return nil
+}
+
+func (this *OCIRegistryClient) pushManifest(address url.URL, repository, tag string, manifest ociManifest) error {
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	response, err := this.do(address, repository, func(base string) (*http.Request, error) {
+		request, err := http.NewRequest("PUT", fmt.Sprintf("%s/v2/%s/manifests/%s", base, repository, tag), bytes.NewReader(raw))
+		if err == nil {
+			request.Header.Set("Content-Type", ociManifestMediaType)
+		}
+		return request, err
+	})
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		return classifyStatusCode(response.StatusCode, http.StatusCreated, address)
+	}
+	return nil
+}
+
+// do sends the request built by the factory, answering a single authentication challenge
+// (Basic or Bearer token) from the registry before giving up.
+func (this *OCIRegistryClient) do(address url.URL, repository string, factory func(base string) (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		request, err := factory(this.baseAddress(address))
+		if err != nil {
+			return nil, err
+		}
+		this.lock.Lock()
+		authorization := this.authorization[repository]
+		this.lock.Unlock()
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+
+		response, err := this.client.Do(request)
+		if err != nil {
+			return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+		}
+		if response.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return response, nil
+		}
+		_ = response.Body.Close()
+
+		authorization, err = this.authorize(response.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, err
+		}
+		this.lock.Lock()
+		this.authorization[repository] = authorization
+		this.lock.Unlock()
+	}
+}
+
+func (this *OCIRegistryClient) authorize(challenge string) (string, error) {
+	scheme, parameters := parseAuthenticationChallenge(challenge)
+	if strings.EqualFold(scheme, "basic") {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.SetBasicAuth(this.credentials.Username, this.credentials.Password)
+		return request.Header.Get("Authorization"), nil
+	}
+	if !strings.EqualFold(scheme, "bearer") || parameters["realm"] == "" {
+		return "", fmt.Errorf("unsupported registry authentication challenge: %q", challenge)
+	}
+
+	realm, err := url.Parse(parameters["realm"])
+	if err != nil {
+		return "", err
+	}
+	query := realm.Query()
+	for _, name := range []string{"service", "scope"} {
+		if value := parameters[name]; value != "" {
+			query.Set(name, value)
+		}
+	}
+	realm.RawQuery = query.Encode()
+	request, err := http.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if this.credentials.Username != "" {
+		request.SetBasicAuth(this.credentials.Username, this.credentials.Password)
+	}
+	response, err := this.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return "", classifyStatusCode(response.StatusCode, http.StatusOK, *realm)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// baseAddress uses plain HTTP for loopback registries (as docker does) or when configured to.
+func (this *OCIRegistryClient) baseAddress(address url.URL) string {
+	host := address.Hostname()
+	if this.credentials.PlainHTTP || host == "localhost" || net.ParseIP(host).IsLoopback() {
+		return "http://" + address.Host
+	}
+	return "https://" + address.Host
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// versionedOCIReference interprets /repository/path/version/filename as a repository and tag.
+// Addresses of 'latest' manifests (/repository/path/manifest.json) are resolved by callers.
+func versionedOCIReference(remotePath string) (repository, tag string) {
+	directory := path.Dir(path.Clean("/" + remotePath))
+	return strings.TrimPrefix(path.Dir(directory), "/"), path.Base(directory)
+}
+
+// readUploadBody reads the entire body from the start (the body may have been read by a previous attempt).
+func readUploadBody(request contracts.UploadRequest) ([]byte, error) {
+	if _, err := request.Body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(request.Body)
+}
+
+func digestUploadBody(request contracts.UploadRequest) (string, error) {
+	if _, err := request.Body.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	sha := sha256.New()
+	md := md5.New()
+	_, err := io.Copy(io.MultiWriter(sha, md), request.Body)
+	if err != nil {
+		return "", err
+	}
+	if _, err = request.Body.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	if actual := md.Sum(nil); len(request.Checksum) > 0 && !bytes.Equal(actual, request.Checksum) {
+		return "", fmt.Errorf("checksum mismatch: actual [%x] != expected [%x]", actual, request.Checksum)
+	}
+	return "sha256:" + hex.EncodeToString(sha.Sum(nil)), nil
+}
+
+// nextOCILink extracts the target of a pagination header such as: </v2/repo/tags/list?n=100&last=x>; rel="next"
+func nextOCILink(header string) string {
+	if !strings.Contains(header, `rel="next"`) {
+		return ""
+	}
+	start, end := strings.Index(header, "<"), strings.Index(header, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return header[start+1 : end]
+}
+
+// isOCIVersionTag excludes 'latest', signatures and the versions index from the tags of a repository.
+func isOCIVersionTag(tag string) bool {
+	return tag != ociLatestTag && tag != ociVersionIndexTag && !strings.HasSuffix(tag, contracts.SignatureExtension)
+}
+
+func sha256Digest(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func parseAuthenticationChallenge(challenge string) (scheme string, parameters map[string]string) {
+	parameters = make(map[string]string)
+	challenge = strings.TrimSpace(challenge)
+	index := strings.Index(challenge, " ")
+	if index < 0 {
+		return challenge, parameters
+	}
+	scheme, remaining := challenge[:index], challenge[index+1:]
+	for len(remaining) > 0 {
+		equals := strings.Index(remaining, "=")
+		if equals < 0 {
+			break
+		}
+		name := strings.TrimSpace(strings.TrimLeft(remaining[:equals], ", "))
+		remaining = remaining[equals+1:]
+		var value string
+		if strings.HasPrefix(remaining, `"`) {
+			value, remaining = unquoteChallengeValue(remaining)
+		} else if comma := strings.Index(remaining, ","); comma >= 0 {
+			value, remaining = remaining[:comma], remaining[comma+1:]
+		} else {
+			value, remaining = remaining, ""
+		}
+		parameters[strings.ToLower(name)] = value
+	}
+	return scheme, parameters
+}
+
+func unquoteChallengeValue(quoted string) (value, remaining string) {
+	if end := strings.Index(quoted[1:], `"`); end >= 0 {
+		return quoted[1 : end+1], quoted[end+2:]
+	}
+	return strings.Trim(quoted, `"`), ""
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+const (
+	ociLatestTag         = "latest"
+	ociVersionIndexTag   = contracts.RemoteVersionIndexFilename
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociConfigMediaType   = "application/vnd.smartystreets.satisfy.manifest.v1+json"
+	ociDocumentMediaType = "application/vnd.smartystreets.satisfy.document.v1+json"
+)
@@ -0,0 +1,316 @@
+package shell
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestOCIRegistryClientFixture(t *testing.T) {
+	gunit.Run(new(OCIRegistryClientFixture), t)
+}
+
+type OCIRegistryClientFixture struct {
+	*gunit.Fixture
+
+	registry *stubRegistry
+	server   *httptest.Server
+	client   *OCIRegistryClient
+}
+
+func (this *OCIRegistryClientFixture) Setup() {
+	this.registry = newStubRegistry()
+	this.server = httptest.NewServer(this.registry)
+	this.client = NewOCIRegistryClient(this.server.Client(), contracts.OCICredentials{}, http.StatusOK)
+}
+
+func (this *OCIRegistryClientFixture) Teardown() {
+	this.server.Close()
+}
+
+func (this *OCIRegistryClientFixture) address(remotePath string) url.URL {
+	return url.URL{Scheme: "oci", Host: this.server.Listener.Addr().String(), Path: remotePath}
+}
+
+func (this *OCIRegistryClientFixture) upload(remotePath, body string) error {
+	return this.client.Upload(contracts.UploadRequest{
+		RemoteAddress: this.address(remotePath),
+		Body:          strings.NewReader(body),
+		Size:          int64(len(body)),
+	})
+}
+
+func (this *OCIRegistryClientFixture) publish(version string) {
+	manifest := fmt.Sprintf(`{"name":"pkg","version":"%s"}`, version)
+	this.So(this.upload("/repo/pkg/"+version+"/archive", "archive-"+version), should.BeNil)
+	this.So(this.upload("/repo/pkg/"+version+"/manifest.json", manifest), should.BeNil)
+	this.So(this.upload("/repo/pkg/manifest.json", manifest), should.BeNil)
+}
+
+func (this *OCIRegistryClientFixture) download(remotePath string) (string, error) {
+	reader, err := this.client.Download(this.address(remotePath))
+	if err != nil {
+		return "", err
+	}
+	return readAndClose(reader), nil
+}
+
+func (this *OCIRegistryClientFixture) TestUploadedPackageDownloaded() {
+	this.publish("1.2.3")
+
+	archive, err := this.download("/repo/pkg/1.2.3/archive")
+	this.So(err, should.BeNil)
+	this.So(archive, should.Equal, "archive-1.2.3")
+
+	manifest, err := this.download("/repo/pkg/1.2.3/manifest.json")
+	this.So(err, should.BeNil)
+	this.So(manifest, should.Equal, `{"name":"pkg","version":"1.2.3"}`)
+
+	latest, err := this.download("/repo/pkg/manifest.json")
+	this.So(err, should.BeNil)
+	this.So(latest, should.Equal, `{"name":"pkg","version":"1.2.3"}`)
+}
+
+func (this *OCIRegistryClientFixture) TestLatestTagRepointedToNewestVersion() {
+	this.publish("1.2.3")
+	this.publish("1.3.0")
+
+	latest, err := this.download("/repo/pkg/manifest.json")
+
+	this.So(err, should.BeNil)
+	this.So(latest, should.Equal, `{"name":"pkg","version":"1.3.0"}`)
+	image := this.registry.image("repo/pkg", "latest")
+	this.So(image.Layers, should.HaveLength, 1)
+	this.So(image.Layers[0].Digest, should.Equal, stubDigest([]byte("archive-1.3.0")))
+}
+
+func (this *OCIRegistryClientFixture) TestVersionsListedWithoutLatest() {
+	this.publish("1.2.3")
+	this.publish("1.3.0")
+
+	versions, err := this.client.List(this.address("/repo/pkg"))
+
+	this.So(err, should.BeNil)
+	this.So(versions, should.Resemble, []string{"1.2.3", "1.3.0"})
+}
+
+func (this *OCIRegistryClientFixture) TestSignedPackageUploadedAndDownloaded() {
+	this.So(this.upload("/repo/pkg/1.2.3/archive", "archive-1.2.3"), should.BeNil)
+	for _, remotePath := range []string{"/repo/pkg/1.2.3/manifest.json", "/repo/pkg/manifest.json"} {
+		this.So(this.upload(remotePath+".sig", `{"signature":"`+remotePath+`"}`), should.BeNil)
+		this.So(this.upload(remotePath, `{"name":"pkg","version":"1.2.3"}`), should.BeNil)
+	}
+
+	archive, err := this.download("/repo/pkg/1.2.3/archive")
+	this.So(err, should.BeNil)
+	this.So(archive, should.Equal, "archive-1.2.3")
+	manifest, err := this.download("/repo/pkg/1.2.3/manifest.json")
+	this.So(err, should.BeNil)
+	this.So(manifest, should.Equal, `{"name":"pkg","version":"1.2.3"}`)
+	signature, err := this.download("/repo/pkg/1.2.3/manifest.json.sig")
+	this.So(err, should.BeNil)
+	this.So(signature, should.Equal, `{"signature":"/repo/pkg/1.2.3/manifest.json"}`)
+	latest, err := this.download("/repo/pkg/manifest.json.sig")
+	this.So(err, should.BeNil)
+	this.So(latest, should.Equal, `{"signature":"/repo/pkg/manifest.json"}`)
+
+	this.So(this.registry.image("repo/pkg", "1.2.3.sig").Layers, should.BeEmpty)
+	versions, err := this.client.List(this.address("/repo/pkg"))
+	this.So(err, should.BeNil)
+	this.So(versions, should.Resemble, []string{"1.2.3"})
+}
+
+func (this *OCIRegistryClientFixture) TestMissingSignature() {
+	this.publish("1.2.3")
+
+	_, err := this.download("/repo/pkg/1.2.3/manifest.json.sig")
+
+	this.So(err, should.HaveSameTypeAs, new(contracts.StatusCodeError))
+}
+
+func (this *OCIRegistryClientFixture) TestVersionIndexUploadedAndDownloaded() {
+	this.publish("1.2.3")
+	_, err := this.download("/repo/pkg/versions.json")
+	this.So(err, should.HaveSameTypeAs, new(contracts.StatusCodeError))
+
+	this.So(this.upload("/repo/pkg/versions.json", `{"versions":["1.2.3"]}`), should.BeNil)
+	this.So(this.upload("/repo/pkg/versions.json", `{"versions":["1.2.3","1.3.0"]}`), should.BeNil)
+
+	index, err := this.download("/repo/pkg/versions.json")
+	this.So(err, should.BeNil)
+	this.So(index, should.Equal, `{"versions":["1.2.3","1.3.0"]}`)
+	versions, err := this.client.List(this.address("/repo/pkg"))
+	this.So(err, should.BeNil)
+	this.So(versions, should.Resemble, []string{"1.2.3"})
+}
+
+func (this *OCIRegistryClientFixture) TestMissingManifest() {
+	_, err := this.download("/repo/pkg/9.9.9/manifest.json")
+
+	this.So(err, should.HaveSameTypeAs, new(contracts.StatusCodeError))
+}
+
+func (this *OCIRegistryClientFixture) TestManifestUploadedBeforeArchiveRejected() {
+	err := this.upload("/repo/pkg/1.2.3/manifest.json", `{"name":"pkg","version":"1.2.3"}`)
+
+	this.So(err, should.NotBeNil)
+	this.So(this.registry.manifests, should.BeEmpty)
+}
+
+func (this *OCIRegistryClientFixture) TestRetriedManifestUploadSendsEntireBody() {
+	this.So(this.upload("/repo/pkg/1.2.3/archive", "archive-1.2.3"), should.BeNil)
+	request := contracts.UploadRequest{
+		RemoteAddress: this.address("/repo/pkg/1.2.3/manifest.json"),
+		Body:          strings.NewReader(`{"name":"pkg","version":"1.2.3"}`),
+	}
+	this.registry.failures = 1 // the first manifest push fails after the body has been read
+
+	this.So(this.client.Upload(request), should.NotBeNil)
+	this.So(this.client.Upload(request), should.BeNil)
+
+	manifest, err := this.download("/repo/pkg/1.2.3/manifest.json")
+	this.So(err, should.BeNil)
+	this.So(manifest, should.Equal, `{"name":"pkg","version":"1.2.3"}`)
+}
+
+func (this *OCIRegistryClientFixture) TestBasicAuthenticationChallengeAnswered() {
+	this.registry.credentials = "user:pass"
+	this.client = NewOCIRegistryClient(this.server.Client(), contracts.OCICredentials{Username: "user", Password: "pass"}, http.StatusOK)
+
+	this.publish("1.2.3")
+
+	archive, err := this.download("/repo/pkg/1.2.3/archive")
+	this.So(err, should.BeNil)
+	this.So(archive, should.Equal, "archive-1.2.3")
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// stubRegistry is a minimal, in-memory stand-in for the parts of the OCI distribution API used by the client.
+type stubRegistry struct {
+	blobs       map[string][]byte // key: repository@digest
+	manifests   map[string][]byte // key: repository:tag
+	uploads     int
+	failures    int    // number of manifest pushes to fail (after reading the body)
+	credentials string // username:password required by way of a Basic challenge, if any
+}
+
+func newStubRegistry() *stubRegistry {
+	return &stubRegistry{blobs: make(map[string][]byte), manifests: make(map[string][]byte)}
+}
+
+func (this *stubRegistry) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if this.credentials != "" {
+		username, password, _ := request.BasicAuth()
+		if username+":"+password != this.credentials {
+			response.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			response.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	body, _ := ioutil.ReadAll(request.Body)
+	route := strings.TrimPrefix(request.URL.Path, "/v2/")
+
+	switch {
+	case strings.HasSuffix(route, "/tags/list") && request.Method == "GET":
+		this.listTags(response, strings.TrimSuffix(route, "/tags/list"))
+	case strings.Contains(route, "/blobs/uploads/") && request.Method == "POST":
+		this.uploads++
+		response.Header().Set("Location", fmt.Sprintf("/v2/%s%d?session=x", route, this.uploads))
+		response.WriteHeader(http.StatusAccepted)
+	case strings.Contains(route, "/blobs/uploads/") && request.Method == "PUT":
+		repository := route[:strings.Index(route, "/blobs/uploads/")]
+		digest := request.URL.Query().Get("digest")
+		if stubDigest(body) != digest || request.URL.Query().Get("session") != "x" {
+			response.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		this.blobs[repository+"@"+digest] = body
+		response.WriteHeader(http.StatusCreated)
+	case strings.Contains(route, "/blobs/"):
+		index := strings.LastIndex(route, "/blobs/")
+		blob, found := this.blobs[route[:index]+"@"+route[index+len("/blobs/"):]]
+		if !found {
+			response.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if request.Method == "GET" {
+			_, _ = response.Write(blob)
+		}
+	case strings.Contains(route, "/manifests/") && request.Method == "PUT":
+		if this.failures > 0 {
+			this.failures--
+			response.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := this.verifyReferences(route[:strings.LastIndex(route, "/manifests/")], body); err != nil {
+			http.Error(response, err.Error(), http.StatusBadRequest)
+			return
+		}
+		this.manifests[strings.Replace(route, "/manifests/", ":", 1)] = body
+		response.WriteHeader(http.StatusCreated)
+	case strings.Contains(route, "/manifests/") && request.Method == "GET":
+		manifest, found := this.manifests[strings.Replace(route, "/manifests/", ":", 1)]
+		if !found {
+			response.WriteHeader(http.StatusNotFound)
+			return
+		}
+		response.Header().Set("Content-Type", ociManifestMediaType)
+		_, _ = response.Write(manifest)
+	default:
+		response.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verifyReferences requires the blobs referenced by a manifest to have been pushed to the repository.
+func (this *stubRegistry) verifyReferences(repository string, raw []byte) error {
+	var manifest ociManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return err
+	}
+	for _, descriptor := range append([]ociDescriptor{manifest.Config}, manifest.Layers...) {
+		if _, found := this.blobs[repository+"@"+descriptor.Digest]; !found {
+			return fmt.Errorf("blob unknown: %s", descriptor.Digest)
+		}
+	}
+	return nil
+}
+
+func (this *stubRegistry) listTags(response http.ResponseWriter, repository string) {
+	var tags []string
+	for key := range this.manifests {
+		if strings.HasPrefix(key, repository+":") {
+			tags = append(tags, strings.TrimPrefix(key, repository+":"))
+		}
+	}
+	if len(tags) == 0 {
+		response.WriteHeader(http.StatusNotFound)
+		return
+	}
+	sort.Strings(tags)
+	_ = json.NewEncoder(response).Encode(map[string]interface{}{"name": repository, "tags": tags})
+}
+
+func (this *stubRegistry) image(repository, tag string) (manifest ociManifest) {
+	_ = json.NewDecoder(bytes.NewReader(this.manifests[repository+":"+tag])).Decode(&manifest)
+	return manifest
+}
+
+func stubDigest(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package shell
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+type S3Client struct {
+	client         *http.Client
+	credentials    contracts.AWSCredentials
+	expectedStatus int
+}
+
+func NewS3Client(client *http.Client, credentials contracts.AWSCredentials, expectedStatus int) *S3Client {
+	return &S3Client{client: client, credentials: credentials, expectedStatus: expectedStatus}
+}
+
+func (this *S3Client) Upload(request contracts.UploadRequest) error {
+	s3Request, err := http.NewRequest("PUT", this.objectAddress(request.RemoteAddress), request.Body)
+	if err != nil {
+		return err
+	}
+	s3Request.ContentLength = request.Size
+	if request.ContentType != "" {
+		s3Request.Header.Set("Content-Type", request.ContentType)
+	}
+	if len(request.Checksum) > 0 {
+		s3Request.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(request.Checksum))
+	}
+	signAWSRequest(s3Request, this.credentials, "s3", unsignedAWSPayload, time.Now())
+
+	response, err := this.client.Do(s3Request)
+	if err != nil {
+		return fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != this.expectedStatus {
+		return classifyStatusCode(response.StatusCode, this.expectedStatus, request.RemoteAddress)
+	}
+	return nil
+}
+
+func (this *S3Client) Download(request url.URL) (io.ReadCloser, error) {
+	return this.download(request, nil)
+}
+
+func (this *S3Client) DownloadRange(request url.URL, offset, length int64) (io.ReadCloser, error) {
+	return this.download(request, &byteRange{offset: offset, length: length})
+}
+
+func (this *S3Client) DownloadRangeIfUnchanged(request url.URL, offset, length int64, validator contracts.Validator) (io.ReadCloser, error) {
+	return this.download(request, &byteRange{offset: offset, length: length, validator: validator})
+}
+
+func (this *S3Client) download(request url.URL, span *byteRange) (io.ReadCloser, error) {
+	s3Request, err := http.NewRequest("GET", this.objectAddress(request), nil)
+	if err != nil {
+		return nil, err
+	}
+	span.apply(s3Request)
+	signAWSRequest(s3Request, this.credentials, "s3", unsignedAWSPayload, time.Now())
+
+	response, err := this.client.Do(s3Request)
+	if err != nil {
+		return nil, fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	return span.receive(response, this.expectedStatus, request)
+}
+
+func (this *S3Client) List(address url.URL) ([]string, error) {
+	bucket := this.objectAddress(url.URL{Host: address.Host, Path: "/"})
+	return listBucket(this.client, address, func(query url.Values) (*http.Request, error) {
+		s3Request, err := http.NewRequest("GET", bucket+"?"+canonicalAWSQuery(query), nil)
+		if err == nil {
+			signAWSRequest(s3Request, this.credentials, "s3", unsignedAWSPayload, time.Now())
+		}
+		return s3Request, err
+	})
+}
+
+// objectAddress uses virtual-hosted-style addressing against AWS and path-style addressing
+// against a custom endpoint (such as MinIO), which rarely has wildcard DNS for buckets.
+func (this *S3Client) objectAddress(address url.URL) string {
+	key := escapeAWSPath(address.Path)
+	if this.credentials.Endpoint != "" {
+		return strings.TrimSuffix(this.credentials.Endpoint, "/") + "/" + address.Host + key
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", address.Host, this.credentials.Region, key)
+}
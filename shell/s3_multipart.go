@@ -0,0 +1,187 @@
+package shell
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+// BeginUpload starts a multipart upload of the object.
+// See: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateMultipartUpload.html
+func (this *S3Client) BeginUpload(request contracts.UploadRequest) (contracts.UploadSession, error) {
+	s3Request, err := http.NewRequest("POST", this.objectAddress(request.RemoteAddress)+"?uploads", nil)
+	if err != nil {
+		return nil, err
+	}
+	if request.ContentType != "" {
+		s3Request.Header.Set("Content-Type", request.ContentType)
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = this.send(s3Request, request.RemoteAddress, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.UploadID == "" {
+		return nil, fmt.Errorf("no upload id returned for %s", request.RemoteAddress.String())
+	}
+	return &s3UploadSession{client: this, address: request.RemoteAddress, uploadID: result.UploadID, md5: md5.New()}, nil
+}
+
+// send signs and sends the request, decoding the XML response body (if any) into the result.
+func (this *S3Client) send(s3Request *http.Request, address url.URL, result interface{}) error {
+	signAWSRequest(s3Request, this.credentials, "s3", unsignedAWSPayload, time.Now())
+	response, err := this.client.Do(s3Request)
+	if err != nil {
+		return fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNoContent {
+		return classifyStatusCode(response.StatusCode, http.StatusOK, address)
+	}
+	if result == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	// A request to complete a multipart upload may fail after the 200 status has already been sent.
+	if bytes.Contains(body, []byte("<Error>")) {
+		var failure struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		_ = xml.Unmarshal(body, &failure)
+		return fmt.Errorf("s3 error: %s: %s (%w)", failure.Code, failure.Message, contracts.RetryErr)
+	}
+	return xml.Unmarshal(body, result)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// s3UploadSession uploads each chunk as a numbered part. S3 commits parts whole, so the bytes
+// committed are those of the parts which have been acknowledged. Parts other than the final part
+// must be at least 5 MiB.
+type s3UploadSession struct {
+	client    *S3Client
+	address   url.URL
+	uploadID  string
+	parts     []s3Part
+	committed int64
+	md5       hash.Hash // of the parts acknowledged thus far
+}
+
+type s3Part struct {
+	Number int    `xml:"PartNumber"`
+	ETag   string `xml:"ETag"`
+	md5    []byte
+}
+
+func (this *s3UploadSession) Committed() (int64, error) {
+	return this.committed, nil
+}
+
+func (this *s3UploadSession) UploadPart(part []byte, offset int64, final bool) error {
+	if offset != this.committed {
+		return fmt.Errorf("part at offset %d does not follow the %d bytes committed", offset, this.committed)
+	}
+	if len(part) == 0 && len(this.parts) > 0 {
+		return nil
+	}
+	number := len(this.parts) + 1
+	query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {this.uploadID}}
+	s3Request, err := http.NewRequest("PUT", this.client.objectAddress(this.address)+"?"+canonicalAWSQuery(query), bytes.NewReader(part))
+	if err != nil {
+		return err
+	}
+	checksum := md5.Sum(part)
+	s3Request.ContentLength = int64(len(part))
+	s3Request.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(checksum[:]))
+	signAWSRequest(s3Request, this.client.credentials, "s3", unsignedAWSPayload, time.Now())
+
+	response, err := this.client.client.Do(s3Request)
+	if err != nil {
+		return fmt.Errorf("http error: %s (%w)", err, contracts.RetryErr)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return classifyStatusCode(response.StatusCode, http.StatusOK, this.address)
+	}
+	this.parts = append(this.parts, s3Part{Number: number, ETag: response.Header.Get("ETag"), md5: checksum[:]})
+	this.committed += int64(len(part))
+	_, _ = this.md5.Write(part)
+	return nil
+}
+
+// Complete verifies the checksum of the entire object against the parts uploaded (aborting the upload
+// on a mismatch, so that the object never appears), then assembles the parts and verifies the
+// resulting ETag, which for a multipart upload is the MD5 of the concatenated MD5s of the parts
+// followed by the number of parts.
+// See: https://docs.aws.amazon.com/AmazonS3/latest/API/API_CompleteMultipartUpload.html
+func (this *s3UploadSession) Complete(checksum []byte) error {
+	if actual := this.md5.Sum(nil); len(checksum) > 0 && !bytes.Equal(actual, checksum) {
+		mismatch := fmt.Errorf("checksum mismatch: actual [%x] != expected [%x]", actual, checksum)
+		if err := this.Abort(); err != nil {
+			return fmt.Errorf("%s (and the upload could not be aborted: %s)", mismatch, err)
+		}
+		return mismatch
+	}
+	var body bytes.Buffer
+	err := xml.NewEncoder(&body).Encode(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: this.parts})
+	if err != nil {
+		return err
+	}
+	query := url.Values{"uploadId": {this.uploadID}}
+	s3Request, err := http.NewRequest("POST", this.client.objectAddress(this.address)+"?"+canonicalAWSQuery(query), &body)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		ETag string `xml:"ETag"`
+	}
+	err = this.client.send(s3Request, this.address, &result)
+	if err != nil {
+		return err
+	}
+	expected := this.expectedETag()
+	actual := strings.Trim(result.ETag, `"`)
+	if actual != "" && actual != expected {
+		return fmt.Errorf("checksum mismatch: actual ETag [%s] != expected [%s]", actual, expected)
+	}
+	return nil
+}
+
+func (this *s3UploadSession) expectedETag() string {
+	digest := md5.New()
+	for _, part := range this.parts {
+		_, _ = digest.Write(part.md5)
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(digest.Sum(nil)), len(this.parts))
+}
+
+func (this *s3UploadSession) Abort() error {
+	query := url.Values{"uploadId": {this.uploadID}}
+	s3Request, err := http.NewRequest("DELETE", this.client.objectAddress(this.address)+"?"+canonicalAWSQuery(query), nil)
+	if err != nil {
+		return err
+	}
+	return this.client.send(s3Request, this.address, nil)
+}
@@ -0,0 +1,108 @@
+package shell
+
+import (
+	"crypto/md5"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/smartystreets/assertions/should"
+	"github.com/smartystreets/gunit"
+
+	"github.com/smartystreets/satisfy/contracts"
+)
+
+func TestS3UploadSessionFixture(t *testing.T) {
+	gunit.Run(new(S3UploadSessionFixture), t)
+}
+
+type S3UploadSessionFixture struct {
+	*gunit.Fixture
+
+	server   *httptest.Server
+	requests []string
+	parts    map[string][]byte
+	session  contracts.UploadSession
+}
+
+func (this *S3UploadSessionFixture) Setup() {
+	this.parts = make(map[string][]byte)
+	this.server = httptest.NewServer(http.HandlerFunc(this.serveHTTP))
+	client := NewS3Client(this.server.Client(), contracts.AWSCredentials{
+		AccessKeyID:     "access-key",
+		SecretAccessKey: "secret-key",
+		Region:          "us-east-1",
+		Endpoint:        this.server.URL,
+	}, http.StatusOK)
+
+	var err error
+	this.session, err = client.BeginUpload(contracts.UploadRequest{
+		RemoteAddress: url.URL{Scheme: "s3", Host: "bucket", Path: "/path/to/object"},
+	})
+	this.So(err, should.BeNil)
+}
+
+func (this *S3UploadSessionFixture) Teardown() {
+	this.server.Close()
+}
+
+func (this *S3UploadSessionFixture) serveHTTP(response http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+	query := request.URL.Query()
+	this.requests = append(this.requests, request.Method+" "+request.URL.Path+"?"+request.URL.RawQuery)
+
+	switch {
+	case request.Method == "POST" && query.Get("uploadId") == "":
+		_, _ = response.Write([]byte(`<InitiateMultipartUploadResult><UploadId>upload id</UploadId></InitiateMultipartUploadResult>`))
+	case request.Method == "PUT":
+		this.parts[query.Get("partNumber")] = body
+		response.Header().Set("ETag", `"etag"`)
+	case request.Method == "POST":
+		_, _ = response.Write([]byte(`<CompleteMultipartUploadResult></CompleteMultipartUploadResult>`))
+	case request.Method == "DELETE":
+		response.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (this *S3UploadSessionFixture) TestPartsUploadedAndCompleted() {
+	checksum := md5.Sum([]byte("part1part2"))
+
+	this.So(this.session.UploadPart([]byte("part1"), 0, false), should.BeNil)
+	this.So(this.session.UploadPart([]byte("part2"), 5, true), should.BeNil)
+	err := this.session.Complete(checksum[:])
+
+	this.So(err, should.BeNil)
+	this.So(this.parts, should.Resemble, map[string][]byte{"1": []byte("part1"), "2": []byte("part2")})
+	this.So(this.requests, should.Resemble, []string{
+		"POST /bucket/path/to/object?uploads",
+		"PUT /bucket/path/to/object?partNumber=1&uploadId=upload%20id",
+		"PUT /bucket/path/to/object?partNumber=2&uploadId=upload%20id",
+		"POST /bucket/path/to/object?uploadId=upload%20id",
+	})
+}
+
+func (this *S3UploadSessionFixture) TestChecksumMismatchAbortsUpload() {
+	checksum := md5.Sum([]byte("something else"))
+
+	this.So(this.session.UploadPart([]byte("part1"), 0, true), should.BeNil)
+	err := this.session.Complete(checksum[:])
+
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.ContainSubstring, "checksum mismatch")
+	this.So(this.requests, should.Resemble, []string{
+		"POST /bucket/path/to/object?uploads",
+		"PUT /bucket/path/to/object?partNumber=1&uploadId=upload%20id",
+		"DELETE /bucket/path/to/object?uploadId=upload%20id",
+	})
+}
+
+func (this *S3UploadSessionFixture) TestPartOutOfSequenceRejected() {
+	this.So(this.session.UploadPart([]byte("part1"), 0, false), should.BeNil)
+
+	err := this.session.UploadPart([]byte("part2"), 4, true)
+
+	this.So(err, should.NotBeNil)
+	this.So(this.parts, should.HaveLength, 1)
+}